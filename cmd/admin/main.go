@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/ochko-b/goapp/internal/config"
+	"github.com/ochko-b/goapp/internal/database"
+	"github.com/ochko-b/goapp/internal/repository"
+	"github.com/ochko-b/goapp/internal/services"
+)
+
+const usage = `Usage: admin <command> [arguments]
+
+Commands:
+  grant-role <email> <role>    grant a role to a user
+  revoke-role <email> <role>   revoke a role from a user
+  roles                        list the available roles
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to connect to database: ", err)
+	}
+	defer db.Close()
+
+	repo := repository.New(db)
+	rbacService := services.NewRBACService(repo)
+	ctx := context.Background()
+
+	switch cmd, args := os.Args[1], os.Args[2:]; {
+	case cmd == "grant-role" && len(args) == 2:
+		if err := rbacService.GrantRoleByEmail(ctx, args[0], args[1]); err != nil {
+			log.Fatal("Failed to grant role: ", err)
+		}
+		fmt.Printf("Granted %s to %s\n", args[1], args[0])
+
+	case cmd == "revoke-role" && len(args) == 2:
+		user, err := repo.GetUserByEmail(ctx, args[0])
+		if err != nil {
+			log.Fatal("Failed to find user: ", err)
+		}
+		if err := rbacService.RevokeRole(ctx, user.ID.String(), args[1]); err != nil {
+			log.Fatal("Failed to revoke role: ", err)
+		}
+		fmt.Printf("Revoked %s from %s\n", args[1], args[0])
+
+	case cmd == "roles" && len(args) == 0:
+		roles, err := rbacService.ListRoles(ctx)
+		if err != nil {
+			log.Fatal("Failed to list roles: ", err)
+		}
+		for _, role := range roles {
+			fmt.Printf("%-20s %s\n", role.Name, role.Description)
+		}
+
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
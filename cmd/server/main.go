@@ -1,19 +1,27 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	fiber_recover "github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/joho/godotenv"
 	"github.com/ochko-b/goapp/cmd/server/routes"
 	"github.com/ochko-b/goapp/internal/config"
 	"github.com/ochko-b/goapp/internal/database"
 	"github.com/ochko-b/goapp/internal/handlers"
+	"github.com/ochko-b/goapp/internal/i18n"
+	"github.com/ochko-b/goapp/internal/mailer"
+	"github.com/ochko-b/goapp/internal/middleware"
 	"github.com/ochko-b/goapp/internal/repository"
 	"github.com/ochko-b/goapp/internal/services"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/internal/webauthn"
 )
 
 func main() {
@@ -22,7 +30,10 @@ func main() {
 		log.Println("No .env file found")
 	}
 	// Load Configuration
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
 
 	// Initialize DB
 	db, err := database.Connect(cfg.Database)
@@ -30,46 +41,124 @@ func main() {
 		log.Fatal("Failed to connect to database: ", err)
 	}
 	defer db.Close()
+	if err := database.CheckRowSecurity(context.Background(), db); err != nil {
+		log.Fatal(err)
+	}
+
+	// Load JWT signing keys
+	keys, err := utils.LoadKeyRing(cfg.JWT.KeyID, cfg.JWT.PrivateKeyPath, cfg.JWT.Secret, cfg.JWT.VerificationKeys)
+	if err != nil {
+		log.Fatal("Failed to load JWT keys: ", err)
+	}
+
+	// Initialize Mailer
+	mailBackend, err := mailer.New(cfg.Mail)
+	if err != nil {
+		log.Fatal("Failed to initialize mailer: ", err)
+	}
+	mailTemplates, err := mailer.LoadTemplates()
+	if err != nil {
+		log.Fatal("Failed to load email templates: ", err)
+	}
+	if err := i18n.Load(); err != nil {
+		log.Fatal("Failed to load message catalogs: ", err)
+	}
 
 	// Initize Repository
 	repo := repository.New(db)
 
 	// Initialize Services
-	authService := services.NewAuthService(repo, cfg.JWT)
+	revocationService := services.NewRevocationService(repo, cfg.JWT.AccessExpiresIn)
+	notifier := services.NewMailNotifier(mailBackend, mailTemplates)
+	auditService := services.NewAuditService(repo)
+	loginThrottle := services.NewLoginThrottle(repo, cfg.Auth, auditService, notifier)
+	passwordPolicy, err := services.NewPasswordPolicy(cfg.Auth)
+	if err != nil {
+		log.Fatal("Failed to set up password policy: ", err)
+	}
+	authService := services.NewAuthService(repo, cfg.JWT, cfg.Auth, keys, revocationService, notifier, loginThrottle, passwordPolicy, auditService, webauthn.New(cfg.WebAuthn))
+	oauthService := services.NewOAuthService(repo, authService, auditService, cfg.OAuth)
 	userService := services.NewUserService(repo)
+	rbacService := services.NewRBACService(repo)
+	orgService := services.NewOrganizationService(repo)
+	apiKeyService := services.NewAPIKeyService(repo, orgService)
+	oauthServerService := services.NewOAuthServerService(repo, cfg.JWT, cfg.AuthServer, keys, revocationService, auditService)
+	sessionService := services.NewSessionService(repo, revocationService, auditService)
+	invitationService := services.NewInvitationService(repo, authService, oauthService, auditService, notifier, cfg.Auth)
+
+	// Promote the configured user to admin while no admin exists yet
+	if cfg.Auth.BootstrapAdminEmail != "" {
+		granted, err := rbacService.BootstrapAdmin(context.Background(), cfg.Auth.BootstrapAdminEmail)
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			log.Printf("Bootstrap admin %s has not registered yet", cfg.Auth.BootstrapAdminEmail)
+		case err != nil:
+			log.Fatal("Failed to bootstrap admin: ", err)
+		case granted:
+			log.Printf("Granted admin role to %s", cfg.Auth.BootstrapAdminEmail)
+		}
+	}
+
+	// Prune expired denylist entries, sessions, login counters, OAuth states,
+	// authorization codes and passkey challenges in the background
+	go revocationService.RunSweeper(context.Background(), 10*time.Minute)
+	go loginThrottle.RunSweeper(context.Background(), time.Hour)
+	go oauthService.RunSweeper(context.Background(), time.Hour)
+	go oauthServerService.RunSweeper(context.Background(), time.Hour)
+	go authService.RunPasskeySweeper(context.Background(), time.Hour)
 
 	// Initialize  handlers
 	authHandler := handlers.NewAuthHandler(authService)
 	userHandler := handlers.NewUserHandler(userService)
+	roleHandler := handlers.NewRoleHandler(rbacService)
+	orgHandler := handlers.NewOrganizationHandler(orgService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	oauthHandler := handlers.NewOAuthHandler(oauthService)
+	oauthServerHandler := handlers.NewOAuthServerHandler(oauthServerService)
+	sessionHandler := handlers.NewSessionHandler(sessionService)
+	invitationHandler := handlers.NewInvitationHandler(invitationService)
 	healthHandler := handlers.NewHealthHandler()
+	jwksHandler := handlers.NewJWKSHandler(keys)
 
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			code := fiber.StatusInternalServerError
-			if e, ok := err.(*fiber.Error); ok {
-				code = e.Code
-			}
-			return c.Status(code).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		},
+		ProxyHeader:             cfg.Server.ProxyHeader,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          cfg.Server.TrustedProxies,
+		EnableIPValidation:      true,
+		ErrorHandler:            handlers.ErrorHandler,
 	})
 
 	// Global Middleware
 	app.Use(fiber_recover.New())
-	app.Use(logger.New())
+	app.Use(requestid.New(requestid.Config{ContextKey: utils.RequestIDKey}))
+	app.Use(middleware.Locale())
+	app.Use(middleware.Logger())
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: cfg.CORS.Origins,
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
+		AllowOrigins:  cfg.CORS.Origins,
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, " + middleware.TenantHeader + ", " + middleware.APIKeyHeader,
+		AllowMethods:  "GET, POST, PUT, DELETE, OPTIONS",
+		ExposeHeaders: fiber.HeaderXRequestID,
 	}))
 
 	routes.Setup(app, &routes.Handlers{
-		Auth:   authHandler,
-		User:   userHandler,
-		Health: healthHandler,
-	}, cfg.JWT.Secret)
+		Auth:        authHandler,
+		User:        userHandler,
+		Role:        roleHandler,
+		Org:         orgHandler,
+		APIKey:      apiKeyHandler,
+		OAuth:       oauthHandler,
+		OAuthServer: oauthServerHandler,
+		Session:     sessionHandler,
+		Invitation:  invitationHandler,
+		Health:      healthHandler,
+		JWKS:        jwksHandler,
+	}, &routes.Middleware{
+		Auth:          middleware.JWTAuth(keys, revocationService, apiKeyService),
+		VerifiedEmail: middleware.RequireVerifiedEmail(cfg.Auth.EmailVerificationMode == config.EmailVerificationRoutes),
+		Tenant:        middleware.Tenant(orgService),
+		Impersonation: middleware.AuditImpersonation(auditService),
+	})
 
 	log.Printf("Server starting on %s:%s", cfg.Server.Host, cfg.Server.Port)
 	log.Fatal(app.Listen(cfg.Server.Host + ":" + cfg.Server.Port))
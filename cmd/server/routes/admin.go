@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/handlers"
+	"github.com/ochko-b/goapp/internal/middleware"
+)
+
+func setupAdminRoutes(protected fiber.Router, authHandler *handlers.AuthHandler, m *Middleware) {
+	// Support staff sign in as a customer with their own user token; an
+	// impersonation token cannot start another impersonation
+	protected.Post("/admin/users/:id/impersonate",
+		middleware.RequireUserToken(),
+		middleware.RejectImpersonation(),
+		m.VerifiedEmail,
+		middleware.RequirePermission("users:impersonate"),
+		authHandler.Impersonate,
+	)
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/handlers"
+	"github.com/ochko-b/goapp/internal/middleware"
+)
+
+func setupAPIKeyRoutes(protected fiber.Router, apiKeyHandler *handlers.APIKeyHandler, m *Middleware) {
+	// Keys can only be managed with a user access token, never with a key
+	userToken := middleware.RequireUserToken()
+	// A key minted while impersonating would outlive the impersonation
+	notImpersonated := middleware.RejectImpersonation()
+
+	protected.Post("/api-keys", userToken, notImpersonated, m.VerifiedEmail, apiKeyHandler.Create)
+	protected.Get("/api-keys", userToken, apiKeyHandler.List)
+	protected.Get("/api-keys/:id", userToken, apiKeyHandler.Get)
+	protected.Delete("/api-keys/:id", userToken, notImpersonated, apiKeyHandler.Revoke)
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/ochko-b/goapp/internal/handlers"
+	"github.com/ochko-b/goapp/internal/middleware"
 )
 
 func setupAuthRoutes(api fiber.Router, authHandler *handlers.AuthHandler) {
@@ -10,8 +11,49 @@ func setupAuthRoutes(api fiber.Router, authHandler *handlers.AuthHandler) {
 
 	auth.Post("/register", authHandler.Register)
 	auth.Post("/login", authHandler.Login)
+	auth.Post("/refresh", authHandler.Refresh)
+	auth.Post("/password/forgot", authHandler.ForgotPassword)
+	auth.Post("/password/reset", authHandler.ResetPassword)
+	auth.Post("/verify-email", authHandler.VerifyEmail)
+	auth.Post("/verify-email/resend", authHandler.ResendVerification)
+	auth.Post("/confirm-email-change", authHandler.ConfirmEmailChange)
+	auth.Post("/mfa/verify", authHandler.VerifyMFA)
+	auth.Post("/mfa/passkey/begin", authHandler.BeginPasskeyMFA)
+	auth.Post("/mfa/passkey/finish", authHandler.FinishPasskeyMFA)
+	auth.Post("/passkey/login/begin", authHandler.BeginPasskeyLogin)
+	auth.Post("/passkey/login/finish", authHandler.FinishPasskeyLogin)
+	auth.Post("/magic-link", authHandler.RequestMagicLink)
+	auth.Post("/magic-link/consume", authHandler.ConsumeMagicLink)
+	auth.Post("/unlock", authHandler.UnlockAccount)
 }
 
 func setupProtectedAuthRoutes(protected fiber.Router, authHandler *handlers.AuthHandler) {
-	protected.Post("/auth/refresh", authHandler.Register)
+	// Session and credential management is not available to API keys
+	userToken := middleware.RequireUserToken()
+	// Only the user themselves, not a support agent impersonating them
+	notImpersonated := middleware.RejectImpersonation()
+
+	// Logging out ends an impersonation; signing the user out everywhere
+	// is up to them
+	protected.Post("/auth/logout", userToken, authHandler.Logout)
+	protected.Post("/auth/logout-all", userToken, notImpersonated, authHandler.LogoutAll)
+
+	// MFA management
+	protected.Post("/auth/mfa/totp/setup", userToken, notImpersonated, authHandler.SetupTOTP)
+	protected.Post("/auth/mfa/totp/confirm", userToken, notImpersonated, authHandler.ConfirmTOTP)
+	protected.Post("/auth/mfa/recovery-codes", userToken, notImpersonated, authHandler.RegenerateRecoveryCodes)
+	protected.Delete("/auth/mfa", userToken, notImpersonated, authHandler.DisableMFA)
+
+	// Passkeys
+	protected.Get("/users/me/passkeys", userToken, authHandler.ListPasskeys)
+	protected.Post("/users/me/passkeys/register/begin", userToken, notImpersonated, authHandler.BeginPasskeyRegistration)
+	protected.Post("/users/me/passkeys/register/finish", userToken, notImpersonated, authHandler.FinishPasskeyRegistration)
+	protected.Delete("/users/me/passkeys/:id", userToken, notImpersonated, authHandler.DeletePasskey)
+
+	// Credential changes on the caller's own account
+	protected.Put("/users/me/password", userToken, notImpersonated, authHandler.ChangePassword)
+	protected.Put("/users/me/email", userToken, notImpersonated, authHandler.ChangeEmail)
+
+	// Lift a login lockout on behalf of the user
+	protected.Post("/users/:id/unlock", notImpersonated, middleware.RequirePermission("users:write"), authHandler.UnlockUser)
 }
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/handlers"
+	"github.com/ochko-b/goapp/internal/middleware"
+)
+
+func setupInvitationRoutes(api fiber.Router, invitationHandler *handlers.InvitationHandler) {
+	invitations := api.Group("/auth/invitations")
+
+	invitations.Post("/lookup", invitationHandler.Lookup)
+	invitations.Post("/accept", invitationHandler.Accept)
+	invitations.Post("/accept/oauth/:provider", invitationHandler.AcceptWithOAuth)
+}
+
+func setupProtectedInvitationRoutes(protected fiber.Router, invitationHandler *handlers.InvitationHandler, m *Middleware) {
+	canRead := middleware.RequirePermission("users:read")
+	canWrite := middleware.RequirePermission("users:write")
+	// Invitations grant roles, so an impersonation cannot send them
+	notImpersonated := middleware.RejectImpersonation()
+
+	protected.Post("/invitations", notImpersonated, m.VerifiedEmail, canWrite, invitationHandler.Create)
+	protected.Get("/invitations", m.VerifiedEmail, canRead, invitationHandler.List)
+	protected.Post("/invitations/:id/resend", notImpersonated, m.VerifiedEmail, canWrite, invitationHandler.Resend)
+	protected.Delete("/invitations/:id", notImpersonated, m.VerifiedEmail, canWrite, invitationHandler.Revoke)
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/handlers"
+	"github.com/ochko-b/goapp/internal/middleware"
+)
+
+func setupOAuthRoutes(api fiber.Router, oauthHandler *handlers.OAuthHandler) {
+	oauth := api.Group("/auth/oauth")
+
+	oauth.Get("/providers", oauthHandler.Providers)
+	oauth.Post("/:provider/authorize", oauthHandler.Authorize)
+	oauth.Post("/:provider/callback", oauthHandler.Callback)
+}
+
+func setupProtectedOAuthRoutes(protected fiber.Router, oauthHandler *handlers.OAuthHandler) {
+	// Linked sign-in methods are credentials, so API keys cannot touch them
+	userToken := middleware.RequireUserToken()
+	notImpersonated := middleware.RejectImpersonation()
+
+	protected.Get("/users/me/identities", userToken, oauthHandler.ListIdentities)
+	protected.Post("/users/me/identities/:provider/authorize", userToken, notImpersonated, oauthHandler.AuthorizeLink)
+	protected.Post("/users/me/identities/:provider/callback", userToken, notImpersonated, oauthHandler.Link)
+	protected.Delete("/users/me/identities/:id", userToken, notImpersonated, oauthHandler.Unlink)
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/handlers"
+	"github.com/ochko-b/goapp/internal/middleware"
+)
+
+// setupOAuthServerRoutes mounts the protocol endpoints at the root, next to
+// the JWKS, where OAuth client libraries look for them.
+func setupOAuthServerRoutes(app *fiber.App, oauthServerHandler *handlers.OAuthServerHandler) {
+	app.Get("/.well-known/oauth-authorization-server", oauthServerHandler.Metadata)
+	app.Get("/oauth/authorize", oauthServerHandler.Authorize)
+	app.Post("/oauth/token", oauthServerHandler.Token)
+	app.Post("/oauth/introspect", oauthServerHandler.Introspect)
+	app.Post("/oauth/revoke", oauthServerHandler.Revoke)
+}
+
+func setupProtectedOAuthServerRoutes(protected fiber.Router, oauthServerHandler *handlers.OAuthServerHandler, m *Middleware) {
+	// Granting access and registering clients need the user themselves
+	userToken := middleware.RequireUserToken()
+	notImpersonated := middleware.RejectImpersonation()
+
+	// Consent screen
+	protected.Get("/oauth/authorize", userToken, m.VerifiedEmail, oauthServerHandler.Consent)
+	protected.Post("/oauth/authorize", userToken, notImpersonated, m.VerifiedEmail, oauthServerHandler.Decide)
+
+	// Client registration
+	protected.Post("/oauth-clients", userToken, notImpersonated, m.VerifiedEmail, oauthServerHandler.CreateClient)
+	protected.Get("/oauth-clients", userToken, oauthServerHandler.ListClients)
+	protected.Get("/oauth-clients/:id", userToken, oauthServerHandler.GetClient)
+	protected.Delete("/oauth-clients/:id", userToken, notImpersonated, oauthServerHandler.DeleteClient)
+}
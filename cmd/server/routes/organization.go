@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/handlers"
+	"github.com/ochko-b/goapp/internal/middleware"
+	"github.com/ochko-b/goapp/internal/models"
+)
+
+func setupOrganizationRoutes(protected fiber.Router, orgHandler *handlers.OrganizationHandler, m *Middleware) {
+	protected.Post("/organizations", m.VerifiedEmail, orgHandler.Create)
+	protected.Get("/organizations", orgHandler.List)
+
+	// Routes acting on the organization selected by the X-Organization-ID header
+	org := protected.Group("/organizations/current", m.VerifiedEmail, m.Tenant)
+	isAdmin := middleware.RequireOrgRole(models.OrgRoleAdmin)
+
+	org.Get("", orgHandler.Get)
+	org.Put("", isAdmin, orgHandler.Update)
+	org.Get("/members", orgHandler.ListMembers)
+	org.Post("/members", isAdmin, orgHandler.AddMember)
+	org.Put("/members/:userId", isAdmin, orgHandler.UpdateMemberRole)
+	// Members may remove themselves; the service checks removing others
+	org.Delete("/members/:userId", orgHandler.RemoveMember)
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/handlers"
+	"github.com/ochko-b/goapp/internal/middleware"
+)
+
+func setupRoleRoutes(protected fiber.Router, roleHandler *handlers.RoleHandler, m *Middleware) {
+	canManageRoles := middleware.RequirePermission("roles:write")
+	// An impersonated admin's roles are for looking, not for granting
+	notImpersonated := middleware.RejectImpersonation()
+
+	protected.Get("/roles", m.VerifiedEmail, canManageRoles, roleHandler.ListRoles)
+	protected.Post("/users/:id/roles", notImpersonated, m.VerifiedEmail, canManageRoles, roleHandler.AssignRole)
+	protected.Delete("/users/:id/roles/:role", notImpersonated, m.VerifiedEmail, canManageRoles, roleHandler.RemoveRole)
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/handlers"
+	"github.com/ochko-b/goapp/internal/middleware"
+)
+
+func setupSessionRoutes(protected fiber.Router, sessionHandler *handlers.SessionHandler, m *Middleware) {
+	// Only the user themselves may see where they are signed in; API keys and
+	// OAuth clients cannot sign the user out of other devices
+	userToken := middleware.RequireUserToken()
+
+	// Registered before /users/:id/sessions, which would match "me" otherwise
+	protected.Get("/users/me/sessions", userToken, sessionHandler.ListMine)
+	protected.Delete("/users/me/sessions/:id", userToken, middleware.RejectImpersonation(), sessionHandler.RevokeMine)
+
+	// Management routes
+	canRead := middleware.RequirePermission("users:read")
+	canWrite := middleware.RequirePermission("users:write")
+
+	protected.Get("/users/:id/sessions", m.VerifiedEmail, canRead, sessionHandler.List)
+	protected.Delete("/users/:id/sessions/:sessionId", middleware.RejectImpersonation(), m.VerifiedEmail, canWrite, sessionHandler.Revoke)
+}
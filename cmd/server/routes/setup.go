@@ -3,23 +3,50 @@ package routes
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/ochko-b/goapp/internal/handlers"
-	"github.com/ochko-b/goapp/internal/middleware"
 )
 
 type Handlers struct {
-	Auth   *handlers.AuthHandler
-	User   *handlers.UserHandler
-	Health *handlers.HealthHandler
+	Auth        *handlers.AuthHandler
+	User        *handlers.UserHandler
+	Role        *handlers.RoleHandler
+	Org         *handlers.OrganizationHandler
+	APIKey      *handlers.APIKeyHandler
+	OAuth       *handlers.OAuthHandler
+	OAuthServer *handlers.OAuthServerHandler
+	Session     *handlers.SessionHandler
+	Invitation  *handlers.InvitationHandler
+	Health      *handlers.HealthHandler
+	JWKS        *handlers.JWKSHandler
 }
 
-func Setup(app *fiber.App, h *Handlers, jwtSecret string) {
+type Middleware struct {
+	Auth          fiber.Handler
+	VerifiedEmail fiber.Handler
+	Tenant        fiber.Handler
+	// Impersonation audits requests made with an impersonation token
+	Impersonation fiber.Handler
+}
+
+func Setup(app *fiber.App, h *Handlers, m *Middleware) {
 	app.Get("/health", h.Health.Check)
+	app.Get("/.well-known/jwks.json", h.JWKS.Keys)
+	setupOAuthServerRoutes(app, h.OAuthServer)
 
 	api := app.Group("/api/v1")
 
 	setupAuthRoutes(api, h.Auth)
-	setupUserRoutes(api, h.User, jwtSecret)
+	setupOAuthRoutes(api, h.OAuth)
+	setupInvitationRoutes(api, h.Invitation)
 
-	protected := api.Group("/", middleware.JWTAuth(jwtSecret))
+	protected := api.Group("/", m.Auth, m.Impersonation)
 	setupProtectedAuthRoutes(protected, h.Auth)
+	setupProtectedOAuthRoutes(protected, h.OAuth)
+	setupSessionRoutes(protected, h.Session, m)
+	setupUserRoutes(protected, h.User, m)
+	setupRoleRoutes(protected, h.Role, m)
+	setupOrganizationRoutes(protected, h.Org, m)
+	setupAPIKeyRoutes(protected, h.APIKey, m)
+	setupProtectedOAuthServerRoutes(protected, h.OAuthServer, m)
+	setupAdminRoutes(protected, h.Auth, m)
+	setupProtectedInvitationRoutes(protected, h.Invitation, m)
 }
@@ -0,0 +1,76 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/handlers"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+// Admin writes on other users are made by the support agent as themselves,
+// never with the token of a user they impersonate, even one holding the
+// permissions.
+func TestAdminWritesRejectImpersonation(t *testing.T) {
+	impersonated := &utils.Claims{
+		UserID:      "00000000-0000-0000-0000-000000000001",
+		TokenUse:    utils.TokenUseAccess,
+		Permissions: []string{"users:read", "users:write", "roles:write", "users:impersonate"},
+		Actor:       &utils.Actor{UserID: "00000000-0000-0000-0000-000000000002"},
+	}
+	next := func(c *fiber.Ctx) error { return c.Next() }
+
+	app := fiber.New(fiber.Config{ErrorHandler: handlers.ErrorHandler})
+	// The handlers have no services: a request that gets past the
+	// middleware would panic.
+	Setup(app, &Handlers{
+		Auth:        new(handlers.AuthHandler),
+		User:        new(handlers.UserHandler),
+		Role:        new(handlers.RoleHandler),
+		Org:         new(handlers.OrganizationHandler),
+		APIKey:      new(handlers.APIKeyHandler),
+		OAuth:       new(handlers.OAuthHandler),
+		OAuthServer: new(handlers.OAuthServerHandler),
+		Session:     new(handlers.SessionHandler),
+		Invitation:  new(handlers.InvitationHandler),
+		Health:      new(handlers.HealthHandler),
+		JWKS:        new(handlers.JWKSHandler),
+	}, &Middleware{
+		Auth: func(c *fiber.Ctx) error {
+			c.Locals("claims", impersonated)
+			return c.Next()
+		},
+		VerifiedEmail: next,
+		Tenant:        next,
+		Impersonation: next,
+	})
+
+	const user = "/api/v1/users/00000000-0000-0000-0000-000000000003"
+	routes := []struct{ method, path string }{
+		{fiber.MethodPut, user},
+		{fiber.MethodPost, user + "/roles"},
+		{fiber.MethodDelete, user + "/roles/admin"},
+		{fiber.MethodPost, user + "/unlock"},
+		{fiber.MethodDelete, user + "/sessions/00000000-0000-0000-0000-000000000004"},
+		{fiber.MethodPost, "/api/v1/invitations"},
+		{fiber.MethodPost, "/api/v1/invitations/00000000-0000-0000-0000-000000000005/resend"},
+		{fiber.MethodDelete, "/api/v1/invitations/00000000-0000-0000-0000-000000000005"},
+		{fiber.MethodPost, "/api/v1/admin/users/00000000-0000-0000-0000-000000000003/impersonate"},
+	}
+
+	for _, route := range routes {
+		resp, err := app.Test(httptest.NewRequest(route.method, route.path, nil))
+		if err != nil {
+			t.Fatalf("%s %s: %v", route.method, route.path, err)
+		}
+		var problem utils.Problem
+		if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+			t.Fatalf("%s %s: %v", route.method, route.path, err)
+		}
+		if resp.StatusCode != fiber.StatusForbidden || problem.Code != "impersonation_not_allowed" {
+			t.Errorf("%s %s: got %d %q, want 403 impersonation_not_allowed", route.method, route.path, resp.StatusCode, problem.Code)
+		}
+	}
+}
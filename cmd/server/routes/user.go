@@ -6,15 +6,18 @@ import (
 	"github.com/ochko-b/goapp/internal/middleware"
 )
 
-func setupUserRoutes(api fiber.Router, userHandler *handlers.UserHandler, jwtSecret string) {
-	protected := api.Group("/", middleware.JWTAuth(jwtSecret))
-
+func setupUserRoutes(protected fiber.Router, userHandler *handlers.UserHandler, m *Middleware) {
 	// Profile routes
 	protected.Get("/users/me", userHandler.GetProfile)
-	protected.Put("/users/me", userHandler.UpdateProfile)
+	protected.Put("/users/me", m.VerifiedEmail, userHandler.UpdateProfile)
 
 	// Management routes
-	protected.Get("/users/:id", userHandler.GetUser)
-	protected.Put("/users/:id", userHandler.UpdateUserTransaction)
-	protected.Get("/users", userHandler.ListUser)
+	canRead := middleware.RequirePermission("users:read")
+	canWrite := middleware.RequirePermission("users:write")
+	// Changes to other users are made by the agent, not by someone they impersonate
+	notImpersonated := middleware.RejectImpersonation()
+
+	protected.Get("/users/:id", m.VerifiedEmail, canRead, userHandler.GetUser)
+	protected.Put("/users/:id", notImpersonated, m.VerifiedEmail, canWrite, userHandler.UpdateUserTransaction)
+	protected.Get("/users", m.VerifiedEmail, canRead, userHandler.ListUser)
 }
@@ -0,0 +1,9 @@
+#!/bin/sh
+# Creates the role the application connects as, when the database volume is
+# first initialized. Migration 0021 grants it the rights on the tables.
+set -e
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" \
+	-v password="$APP_DB_PASSWORD" <<-'EOSQL'
+	CREATE ROLE goapp_app LOGIN NOSUPERUSER NOBYPASSRLS PASSWORD :'password';
+EOSQL
@@ -0,0 +1,97 @@
+// Package apperror defines the kinds of errors the services return. The HTTP
+// layer maps each kind to a status in one place, so handlers pass errors on
+// as they are instead of guessing.
+package apperror
+
+import "errors"
+
+// Kind classifies an error by what the client can do about it.
+type Kind uint8
+
+const (
+	KindInternal Kind = iota
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindUnprocessable
+	KindRateLimited
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindValidation:
+		return "validation"
+	case KindUnauthorized:
+		return "unauthorized"
+	case KindForbidden:
+		return "forbidden"
+	case KindNotFound:
+		return "not_found"
+	case KindConflict:
+		return "conflict"
+	case KindUnprocessable:
+		return "unprocessable"
+	case KindRateLimited:
+		return "rate_limited"
+	}
+	return "internal"
+}
+
+// Error is an error of a known kind. Code is a stable identifier for clients
+// and the key of the error's message in the message catalogs. Message is the
+// English text, safe to show to clients; the wrapped Err, if any, is only for
+// the logs.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Err     error
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Wrap attaches a kind, code and client-facing message to err.
+func Wrap(kind Kind, code, message string, err error) *Error {
+	return &Error{Kind: kind, Code: code, Message: message, Err: err}
+}
+
+func Validation(code, message string) *Error    { return New(KindValidation, code, message) }
+func Unauthorized(code, message string) *Error  { return New(KindUnauthorized, code, message) }
+func Forbidden(code, message string) *Error     { return New(KindForbidden, code, message) }
+func NotFound(code, message string) *Error      { return New(KindNotFound, code, message) }
+func Conflict(code, message string) *Error      { return New(KindConflict, code, message) }
+func Unprocessable(code, message string) *Error { return New(KindUnprocessable, code, message) }
+func RateLimited(code, message string) *Error   { return New(KindRateLimited, code, message) }
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// CodeInternal is the code of every error without a known kind.
+const CodeInternal = "internal_error"
+
+// From returns the first *Error in err's chain. Errors without one are
+// internal, and their message must not reach the client.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Wrap(KindInternal, CodeInternal, "internal server error", err)
+}
+
+// KindOf returns the kind of the first *Error in err's chain.
+func KindOf(err error) Kind {
+	return From(err).Kind
+}
@@ -1,21 +1,37 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	CORS     CORSConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	JWT        JWTConfig
+	Auth       AuthConfig
+	Mail       MailConfig
+	OAuth      OAuthConfig
+	AuthServer AuthServerConfig
+	WebAuthn   WebAuthnConfig
+	CORS       CORSConfig
 }
 
+// ServerConfig says where to listen. The client IP is read from ProxyHeader
+// only on requests coming from one of TrustedProxies, IPs or CIDR ranges;
+// for everyone else it is the address of the connection.
 type ServerConfig struct {
-	Host string
-	Port string
-	Env  string
+	Host           string
+	Port           string
+	Env            string
+	ProxyHeader    string
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
@@ -28,20 +44,119 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
-	Secret    string
-	ExpiresIn string
+	Secret           string
+	PrivateKeyPath   string
+	KeyID            string
+	VerificationKeys string
+	AccessExpiresIn  time.Duration
+	RefreshExpiresIn time.Duration
+}
+
+// Email verification modes
+const (
+	// EmailVerificationOptional lets unverified users log in and use every route.
+	EmailVerificationOptional = "optional"
+	// EmailVerificationLogin refuses to log in unverified users.
+	EmailVerificationLogin = "login"
+	// EmailVerificationRoutes lets unverified users log in but keeps them out
+	// of routes guarded by middleware.RequireVerifiedEmail.
+	EmailVerificationRoutes = "routes"
+)
+
+type AuthConfig struct {
+	AppURL                     string
+	PasswordResetExpiresIn     time.Duration
+	EmailVerificationMode      string
+	EmailVerificationExpiresIn time.Duration
+	EmailChangeExpiresIn       time.Duration
+	MagicLinkEnabled           bool
+	MagicLinkExpiresIn         time.Duration
+	MagicLinkMaxRequests       int
+	MagicLinkRequestWindow     time.Duration
+	MFAIssuer                  string
+	MFATokenExpiresIn          time.Duration
+	BootstrapAdminEmail        string
+	ImpersonationExpiresIn     time.Duration
+	InvitationExpiresIn        time.Duration
+	LoginMaxAccountFailures    int
+	LoginMaxIPFailures         int
+	LoginBackoffBase           time.Duration
+	LoginLockoutDuration       time.Duration
+	LoginFailureWindow         time.Duration
+	PasswordArgon2Memory       int
+	PasswordArgon2Iterations   int
+	PasswordArgon2Parallelism  int
+	PasswordMinLength          int
+	PasswordMaxLength          int
+	PasswordRequireUpper       bool
+	PasswordRequireLower       bool
+	PasswordRequireDigit       bool
+	PasswordRequireSymbol      bool
+	PasswordBreachedPath       string
+}
+
+type MailConfig struct {
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	FileDir      string
+}
+
+// OAuthConfig lists the OpenID Connect providers users may sign in with.
+type OAuthConfig struct {
+	Providers      []OAuthProviderConfig
+	StateExpiresIn time.Duration
+}
+
+// OAuthProviderConfig is one OpenID Connect issuer. Endpoints and keys come
+// from the issuer's discovery document. RedirectURL is the client page that
+// receives the authorization response and posts it to the callback route.
+type OAuthProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RedirectURL  string
+}
+
+// AuthServerConfig configures the OAuth 2.0 authorization server that lets
+// registered client apps act on behalf of our users. Issuer is the public
+// base URL of this API; ConsentURL is the client page that asks the user to
+// approve a request.
+type AuthServerConfig struct {
+	Issuer        string
+	ConsentURL    string
+	CodeExpiresIn time.Duration
+}
+
+// WebAuthnConfig describes this app as a WebAuthn relying party. RPID is the
+// domain passkeys are bound to; Origins are the frontend origins allowed to
+// run the ceremonies, and must be RPID or subdomains of it.
+type WebAuthnConfig struct {
+	RPID    string
+	RPName  string
+	Origins []string
+	Timeout time.Duration
 }
 
 type CORSConfig struct {
 	Origins string
 }
 
-func Load() *Config {
-	return &Config{
+// Load reads the configuration from the environment and fails on settings
+// that are set but invalid, rather than silently using a default.
+func Load() (*Config, error) {
+	cfg := &Config{
 		Server: ServerConfig{
-			Host: getEnv("HOST", "0.0.0.0"),
-			Port: getEnv("PORT", "3000"),
-			Env:  getEnv("ENV", "development"),
+			Host:           getEnv("HOST", "0.0.0.0"),
+			Port:           getEnv("PORT", "3000"),
+			Env:            getEnv("ENV", "development"),
+			ProxyHeader:    getEnv("PROXY_HEADER", ""),
+			TrustedProxies: getEnvList("TRUSTED_PROXIES"),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -52,13 +167,139 @@ func Load() *Config {
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
 		JWT: JWTConfig{
-			Secret:    getEnv("JWT_SECRET", ""),
-			ExpiresIn: getEnv("JWT_EXPIRES_IN", "24h"),
+			Secret:           getEnv("JWT_SECRET", ""),
+			PrivateKeyPath:   getEnv("JWT_PRIVATE_KEY_PATH", ""),
+			KeyID:            getEnv("JWT_KEY_ID", ""),
+			VerificationKeys: getEnv("JWT_VERIFICATION_KEYS", ""),
+			AccessExpiresIn:  getEnvDuration("JWT_ACCESS_EXPIRES_IN", 15*time.Minute),
+			RefreshExpiresIn: getEnvDuration("JWT_REFRESH_EXPIRES_IN", 30*24*time.Hour),
+		},
+		Auth: AuthConfig{
+			AppURL:                     getEnv("APP_URL", "http://localhost:3000"),
+			PasswordResetExpiresIn:     getEnvDuration("PASSWORD_RESET_EXPIRES_IN", time.Hour),
+			EmailVerificationMode:      getEnv("EMAIL_VERIFICATION_MODE", EmailVerificationOptional),
+			EmailVerificationExpiresIn: getEnvDuration("EMAIL_VERIFICATION_EXPIRES_IN", 24*time.Hour),
+			EmailChangeExpiresIn:       getEnvDuration("EMAIL_CHANGE_EXPIRES_IN", 24*time.Hour),
+			MagicLinkEnabled:           getEnvBool("MAGIC_LINK_ENABLED", false),
+			MagicLinkExpiresIn:         getEnvDuration("MAGIC_LINK_EXPIRES_IN", 15*time.Minute),
+			MagicLinkMaxRequests:       getEnvInt("MAGIC_LINK_MAX_REQUESTS", 5),
+			MagicLinkRequestWindow:     getEnvDuration("MAGIC_LINK_REQUEST_WINDOW", time.Hour),
+			MFAIssuer:                  getEnv("MFA_ISSUER", "GoApp"),
+			MFATokenExpiresIn:          getEnvDuration("MFA_TOKEN_EXPIRES_IN", 5*time.Minute),
+			BootstrapAdminEmail:        getEnv("BOOTSTRAP_ADMIN_EMAIL", ""),
+			ImpersonationExpiresIn:     getEnvDuration("IMPERSONATION_EXPIRES_IN", 15*time.Minute),
+			InvitationExpiresIn:        getEnvDuration("INVITATION_EXPIRES_IN", 7*24*time.Hour),
+			LoginMaxAccountFailures:    getEnvInt("LOGIN_MAX_ACCOUNT_FAILURES", 10),
+			LoginMaxIPFailures:         getEnvInt("LOGIN_MAX_IP_FAILURES", 100),
+			LoginBackoffBase:           getEnvDuration("LOGIN_BACKOFF_BASE", time.Second),
+			LoginLockoutDuration:       getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+			LoginFailureWindow:         getEnvDuration("LOGIN_FAILURE_WINDOW", 24*time.Hour),
+			PasswordArgon2Memory:       getEnvInt("PASSWORD_ARGON2_MEMORY", 64*1024),
+			PasswordArgon2Iterations:   getEnvInt("PASSWORD_ARGON2_ITERATIONS", 3),
+			PasswordArgon2Parallelism:  getEnvInt("PASSWORD_ARGON2_PARALLELISM", 2),
+			PasswordMinLength:          getEnvInt("PASSWORD_MIN_LENGTH", 8),
+			PasswordMaxLength:          getEnvInt("PASSWORD_MAX_LENGTH", 128),
+			PasswordRequireUpper:       getEnvBool("PASSWORD_REQUIRE_UPPER", false),
+			PasswordRequireLower:       getEnvBool("PASSWORD_REQUIRE_LOWER", false),
+			PasswordRequireDigit:       getEnvBool("PASSWORD_REQUIRE_DIGIT", false),
+			PasswordRequireSymbol:      getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+			PasswordBreachedPath:       getEnv("PASSWORD_BREACHED_PATH", ""),
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "file"),
+			From:         getEnv("MAIL_FROM", "GoApp <no-reply@localhost>"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnvInt("SMTP_PORT", 1025),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			FileDir:      getEnv("MAIL_FILE_DIR", "tmp/mail"),
+		},
+		OAuth: OAuthConfig{
+			Providers:      loadOAuthProviders(getEnv("APP_URL", "http://localhost:3000")),
+			StateExpiresIn: getEnvDuration("OAUTH_STATE_EXPIRES_IN", 10*time.Minute),
+		},
+		AuthServer: AuthServerConfig{
+			Issuer:        getEnv("OAUTH_SERVER_ISSUER", "http://localhost:3000"),
+			ConsentURL:    getEnv("OAUTH_SERVER_CONSENT_URL", getEnv("APP_URL", "http://localhost:3000")+"/oauth/consent"),
+			CodeExpiresIn: getEnvDuration("OAUTH_SERVER_CODE_EXPIRES_IN", 5*time.Minute),
+		},
+		WebAuthn: WebAuthnConfig{
+			RPID:    getEnv("WEBAUTHN_RP_ID", "localhost"),
+			RPName:  getEnv("WEBAUTHN_RP_NAME", "GoApp"),
+			Origins: strings.Split(getEnv("WEBAUTHN_ORIGINS", getEnv("APP_URL", "http://localhost:3000")), ","),
+			Timeout: getEnvDuration("WEBAUTHN_TIMEOUT", 5*time.Minute),
 		},
 		CORS: CORSConfig{
 			Origins: getEnv("CORS_ORIGINS", "*"),
 		},
 	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) validate() error {
+	// A proxy header without trusted proxies would never be read
+	if c.Server.ProxyHeader != "" && len(c.Server.TrustedProxies) == 0 {
+		return errors.New("PROXY_HEADER requires TRUSTED_PROXIES, the addresses of the proxies that set it")
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, err := netip.ParseAddr(proxy); err == nil {
+			continue
+		}
+		if _, err := netip.ParsePrefix(proxy); err != nil {
+			return fmt.Errorf("TRUSTED_PROXIES: %q is neither an IP address nor a CIDR range", proxy)
+		}
+	}
+
+	switch c.Auth.EmailVerificationMode {
+	case EmailVerificationOptional, EmailVerificationLogin, EmailVerificationRoutes:
+	default:
+		return fmt.Errorf("EMAIL_VERIFICATION_MODE must be %s, %s or %s, got %q",
+			EmailVerificationOptional, EmailVerificationLogin, EmailVerificationRoutes, c.Auth.EmailVerificationMode)
+	}
+
+	// The argon2 parameters are stored as uint32 and uint8; out of range
+	// values would wrap around, and zero ones make hashing panic.
+	argon2 := []struct {
+		name       string
+		value, max int
+	}{
+		{"PASSWORD_ARGON2_MEMORY", c.Auth.PasswordArgon2Memory, math.MaxUint32},
+		{"PASSWORD_ARGON2_ITERATIONS", c.Auth.PasswordArgon2Iterations, math.MaxUint32},
+		{"PASSWORD_ARGON2_PARALLELISM", c.Auth.PasswordArgon2Parallelism, math.MaxUint8},
+	}
+	for _, param := range argon2 {
+		if param.value < 1 || param.value > param.max {
+			return fmt.Errorf("%s must be between 1 and %d, got %d", param.name, param.max, param.value)
+		}
+	}
+	return nil
+}
+
+// loadOAuthProviders reads OAUTH_PROVIDERS, a comma separated list of names,
+// and the OAUTH_<NAME>_* variables of each one.
+func loadOAuthProviders(appURL string) []OAuthProviderConfig {
+	var providers []OAuthProviderConfig
+	for _, name := range strings.Split(getEnv("OAUTH_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OAUTH_" + strings.ToUpper(name) + "_"
+
+		providers = append(providers, OAuthProviderConfig{
+			Name:         name,
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", appURL+"/oauth/callback/"+name),
+		})
+	}
+	return providers
 }
 
 func getEnv(key, defaultValue string) string {
@@ -68,6 +309,17 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList reads a comma-separated list, skipping empty entries.
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
@@ -76,3 +328,21 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
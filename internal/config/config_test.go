@@ -0,0 +1,58 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Auth.EmailVerificationMode != EmailVerificationOptional {
+		t.Errorf("email verification mode = %q", cfg.Auth.EmailVerificationMode)
+	}
+	if cfg.Server.ProxyHeader != "" || cfg.Server.TrustedProxies != nil {
+		t.Errorf("proxy settings = %q %v, want none", cfg.Server.ProxyHeader, cfg.Server.TrustedProxies)
+	}
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	t.Setenv("PROXY_HEADER", "X-Real-IP")
+	t.Setenv("TRUSTED_PROXIES", " 10.0.0.0/8, 192.168.1.1 ,,fd00::/8")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := []string{"10.0.0.0/8", "192.168.1.1", "fd00::/8"}
+	if !slices.Equal(cfg.Server.TrustedProxies, want) {
+		t.Errorf("trusted proxies = %v, want %v", cfg.Server.TrustedProxies, want)
+	}
+}
+
+func TestLoadRejectsInvalidSettings(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"unknown email verification mode", map[string]string{"EMAIL_VERIFICATION_MODE": "requried"}},
+		{"proxy header without trusted proxies", map[string]string{"PROXY_HEADER": "X-Forwarded-For"}},
+		{"argon2 parallelism overflowing uint8", map[string]string{"PASSWORD_ARGON2_PARALLELISM": "256"}},
+		{"zero argon2 iterations", map[string]string{"PASSWORD_ARGON2_ITERATIONS": "0"}},
+		{"negative argon2 memory", map[string]string{"PASSWORD_ARGON2_MEMORY": "-1"}},
+		{"invalid trusted proxy", map[string]string{"PROXY_HEADER": "X-Real-IP", "TRUSTED_PROXIES": "10.0.0.0/8,loadbalancer"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			if _, err := Load(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -25,3 +25,20 @@ func Connect(cfg config.DatabaseConfig) (*pgxpool.Pool, error) {
 
 	return pool, nil
 }
+
+// CheckRowSecurity fails if the connected role is a superuser or has
+// BYPASSRLS, as the tenant isolation policies would not apply to it.
+func CheckRowSecurity(ctx context.Context, pool *pgxpool.Pool) error {
+	var role string
+	var bypasses bool
+	err := pool.QueryRow(ctx,
+		"SELECT rolname, rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user",
+	).Scan(&role, &bypasses)
+	if err != nil {
+		return fmt.Errorf("failed to check database role: %w", err)
+	}
+	if bypasses {
+		return fmt.Errorf("database role %s bypasses row-level security; connect as goapp_app instead", role)
+	}
+	return nil
+}
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_mfa_recovery_codes_user_id;
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TRIGGER IF EXISTS update_user_mfa_updated_at ON user_mfa;
+DROP TABLE IF EXISTS user_mfa;
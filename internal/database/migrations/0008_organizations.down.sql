@@ -0,0 +1,8 @@
+DROP POLICY IF EXISTS organization_members_tenant_isolation ON organization_members;
+DROP POLICY IF EXISTS organizations_tenant_isolation ON organizations;
+DROP INDEX IF EXISTS idx_organization_members_user_id;
+DROP TABLE IF EXISTS organization_members;
+DROP TRIGGER IF EXISTS update_organizations_updated_at ON organizations;
+DROP TABLE IF EXISTS organizations;
+DROP FUNCTION IF EXISTS app_current_user();
+DROP FUNCTION IF EXISTS app_current_tenant();
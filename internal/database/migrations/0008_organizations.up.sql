@@ -0,0 +1,51 @@
+CREATE TABLE organizations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(100) UNIQUE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TRIGGER update_organizations_updated_at BEFORE UPDATE ON organizations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE organization_members (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX idx_organization_members_user_id ON organization_members(user_id);
+
+-- Row-level security. Repository.InTenant sets app.tenant_id and app.user_id
+-- for the duration of each transaction. Rows of other tenants are invisible
+-- even if a query forgets its organization_id filter. A user additionally
+-- sees their own memberships, so they can list the organizations they belong
+-- to. Superusers and roles with BYPASSRLS ignore these policies, so the
+-- application must connect as an ordinary role for them to take effect.
+CREATE OR REPLACE FUNCTION app_current_tenant() RETURNS UUID AS $$
+    SELECT NULLIF(current_setting('app.tenant_id', true), '')::uuid
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION app_current_user() RETURNS UUID AS $$
+    SELECT NULLIF(current_setting('app.user_id', true), '')::uuid
+$$ LANGUAGE sql STABLE;
+
+ALTER TABLE organizations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE organizations FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY organizations_tenant_isolation ON organizations
+    USING (
+        id = app_current_tenant()
+        OR id IN (SELECT organization_id FROM organization_members WHERE user_id = app_current_user())
+    )
+    WITH CHECK (id = app_current_tenant());
+
+ALTER TABLE organization_members ENABLE ROW LEVEL SECURITY;
+ALTER TABLE organization_members FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY organization_members_tenant_isolation ON organization_members
+    USING (organization_id = app_current_tenant() OR user_id = app_current_user())
+    WITH CHECK (organization_id = app_current_tenant());
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_audit_logs_created_at;
+DROP INDEX IF EXISTS idx_audit_logs_user_id;
+DROP TABLE IF EXISTS audit_logs;
+DROP INDEX IF EXISTS idx_account_unlock_tokens_user_id;
+DROP TABLE IF EXISTS account_unlock_tokens;
+DROP INDEX IF EXISTS idx_login_throttles_last_failed_at;
+DROP TABLE IF EXISTS login_throttles;
@@ -0,0 +1,7 @@
+ALTER TABLE refresh_tokens
+    DROP COLUMN IF EXISTS scopes,
+    DROP COLUMN IF EXISTS client_id;
+DROP INDEX IF EXISTS idx_oauth_authorization_codes_expires_at;
+DROP TABLE IF EXISTS oauth_authorization_codes;
+DROP INDEX IF EXISTS idx_oauth_clients_owner_id;
+DROP TABLE IF EXISTS oauth_clients;
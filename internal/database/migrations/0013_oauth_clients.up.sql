@@ -0,0 +1,39 @@
+-- Applications that get delegated access through our OAuth 2.0
+-- authorization server. Public clients (mobile and single-page apps) have no
+-- secret and rely on PKCE alone.
+CREATE TABLE oauth_clients (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    -- The user who registered the client; client_credentials tokens act as them
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    -- Empty for public clients
+    secret_hash VARCHAR(64) NOT NULL DEFAULT '',
+    redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+    grant_types TEXT[] NOT NULL DEFAULT '{}',
+    -- Permissions the client may ask for; capped by the user's own permissions
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_oauth_clients_owner_id ON oauth_clients(owner_id);
+
+-- Authorization codes between consent and the token request. redirect_uri is
+-- the value sent with the authorization request, which may be empty.
+CREATE TABLE oauth_authorization_codes (
+    code_hash VARCHAR(64) PRIMARY KEY,
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL DEFAULT '',
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    code_challenge VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_oauth_authorization_codes_expires_at ON oauth_authorization_codes(expires_at);
+
+-- Refresh tokens issued to OAuth clients share rotation and reuse detection
+-- with first-party ones. client_id is NULL for first-party tokens.
+ALTER TABLE refresh_tokens
+    ADD COLUMN client_id UUID REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    ADD COLUMN scopes TEXT[] NOT NULL DEFAULT '{}';
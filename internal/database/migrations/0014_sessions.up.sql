@@ -0,0 +1,19 @@
+-- One row per sign-in, shared by every token of its refresh token family: id
+-- is the family id, which access tokens carry as their sid claim. Refreshing
+-- moves last_seen_at and expires_at along.
+CREATE TABLE sessions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- Set for sessions of OAuth client apps
+    client_id UUID REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    -- Coarse label derived from the user agent, e.g. "Firefox on Windows"
+    device VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
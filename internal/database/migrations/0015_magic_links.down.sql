@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_magic_link_tokens_user_id_created_at;
+DROP TABLE IF EXISTS magic_link_tokens;
@@ -0,0 +1,8 @@
+-- Kept apart from users:write: signing in as someone else is more than
+-- editing their profile, and is granted to support staff deliberately
+INSERT INTO permissions (name, description) VALUES
+    ('users:impersonate', 'Sign in as another user to reproduce what they see');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p
+WHERE r.name = 'admin' AND p.name = 'users:impersonate';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_invitations_created_at;
+DROP INDEX IF EXISTS idx_invitations_email;
+DROP TABLE IF EXISTS invitations;
@@ -0,0 +1,20 @@
+-- An invitation is pending until it is accepted or revoked. A pending
+-- invitation past expires_at is reported as expired, and can be resent.
+CREATE TABLE invitations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    email VARCHAR(255) NOT NULL,
+    role_id UUID REFERENCES roles(id) ON DELETE SET NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'revoked')),
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    accepted_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    sent_count INTEGER NOT NULL DEFAULT 1,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_sent_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_invitations_email ON invitations(email);
+CREATE INDEX idx_invitations_created_at ON invitations(created_at);
@@ -0,0 +1,3 @@
+-- The language of API messages the user chose, e.g. 'de'. NULL follows the
+-- client's Accept-Language header
+ALTER TABLE users ADD COLUMN locale VARCHAR(35);
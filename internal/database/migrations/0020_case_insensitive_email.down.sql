@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_invitations_pending_email;
+DROP INDEX IF EXISTS idx_invitations_email;
+CREATE INDEX idx_invitations_email ON invitations(email);
+
+DROP INDEX IF EXISTS idx_users_email;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+CREATE INDEX idx_users_email ON users(email);
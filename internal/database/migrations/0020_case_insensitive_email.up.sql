@@ -0,0 +1,37 @@
+-- Emails are now stored lower-cased, but accounts and invitations created
+-- before keep their case, so lookups compare lower-cased addresses and
+-- uniqueness holds regardless of case.
+
+-- Accounts that only differ in case cannot be merged here; someone has to
+-- decide which one stays.
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(address, ', ') INTO duplicates
+    FROM (
+        SELECT lower(email) AS address FROM users
+        GROUP BY lower(email)
+        HAVING count(*) > 1
+    ) d;
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'users with emails that only differ in case: %', duplicates
+            USING HINT = 'Merge or rename these accounts, then run the migration again.';
+    END IF;
+END $$;
+
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX idx_users_email ON users(lower(email));
+
+-- Only the latest pending invitation of an address stays pending
+UPDATE invitations SET status = 'revoked', revoked_at = NOW()
+WHERE status = 'pending' AND id NOT IN (
+    SELECT DISTINCT ON (lower(email)) id FROM invitations
+    WHERE status = 'pending'
+    ORDER BY lower(email), created_at DESC, id
+);
+
+DROP INDEX IF EXISTS idx_invitations_email;
+CREATE INDEX idx_invitations_email ON invitations(lower(email));
+CREATE UNIQUE INDEX idx_invitations_pending_email ON invitations(lower(email)) WHERE status = 'pending';
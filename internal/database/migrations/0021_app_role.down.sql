@@ -0,0 +1,10 @@
+-- The role itself is kept, as it may have been created outside of
+-- migrations and still be in use.
+ALTER DEFAULT PRIVILEGES IN SCHEMA public
+    REVOKE USAGE, SELECT ON SEQUENCES FROM goapp_app;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public
+    REVOKE SELECT, INSERT, UPDATE, DELETE ON TABLES FROM goapp_app;
+
+REVOKE ALL ON ALL SEQUENCES IN SCHEMA public FROM goapp_app;
+REVOKE ALL ON ALL TABLES IN SCHEMA public FROM goapp_app;
+REVOKE USAGE ON SCHEMA public FROM goapp_app;
@@ -0,0 +1,37 @@
+-- The role the application connects as. Unlike the role running these
+-- migrations it is neither a superuser nor exempt from row-level security,
+-- so the tenant policies of 0008 apply to every query it runs. It owns no
+-- tables and may only read and write rows.
+--
+-- The password is set outside of migrations: docker/postgres/init-app-role.sh
+-- creates the role with one, elsewhere run ALTER ROLE goapp_app PASSWORD '...'.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'goapp_app') THEN
+        CREATE ROLE goapp_app LOGIN NOSUPERUSER NOBYPASSRLS;
+    END IF;
+
+    IF EXISTS (SELECT FROM pg_roles WHERE rolname = 'goapp_app' AND (rolsuper OR rolbypassrls)) THEN
+        RAISE EXCEPTION 'goapp_app must not be a superuser or bypass row-level security';
+    END IF;
+END
+$$;
+
+GRANT USAGE ON SCHEMA public TO goapp_app;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO goapp_app;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO goapp_app;
+
+-- Tables of later migrations, created by the same role as this one
+ALTER DEFAULT PRIVILEGES IN SCHEMA public
+    GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO goapp_app;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public
+    GRANT USAGE, SELECT ON SEQUENCES TO goapp_app;
+
+-- The migration history is not the application's to change
+DO $$
+BEGIN
+    IF to_regclass('public.schema_migrations') IS NOT NULL THEN
+        REVOKE ALL ON public.schema_migrations FROM goapp_app;
+    END IF;
+END
+$$;
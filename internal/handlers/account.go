@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	claims := c.Locals("claims").(*utils.Claims)
+
+	var req models.ChangePasswordRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	tokens, err := h.authService.ChangePassword(c.Context(), claims, &req, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, tokens, "Password changed; other sessions have been signed out")
+}
+
+func (h *AuthHandler) ChangeEmail(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.ChangeEmailRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.authService.RequestEmailChange(c.Context(), userID, &req, c.IP()); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "A confirmation link has been sent to the new email address")
+}
+
+func (h *AuthHandler) ConfirmEmailChange(c *fiber.Ctx) error {
+	var req models.ConfirmEmailChangeRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	user, err := h.authService.ConfirmEmailChange(c.Context(), req.Token)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, user, "Email address changed successfully")
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/services"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *services.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+func (h *APIKeyHandler) Create(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.CreateAPIKeyRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	key, err := h.apiKeyService.Create(c.Context(), userID, &req)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c.Status(fiber.StatusCreated), key, "API key created. Store it now; it will not be shown again")
+}
+
+func (h *APIKeyHandler) List(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	keys, err := h.apiKeyService.List(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, keys)
+}
+
+func (h *APIKeyHandler) Get(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	keyID, err := validator.UUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	key, err := h.apiKeyService.Get(c.Context(), userID, keyID)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, key)
+}
+
+func (h *APIKeyHandler) Revoke(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	keyID, err := validator.UUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.apiKeyService.Revoke(c.Context(), userID, keyID); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "API key revoked")
+}
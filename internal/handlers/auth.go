@@ -1,48 +1,37 @@
 package handlers
 
 import (
-	"strings"
-
-	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/ochko-b/goapp/internal/models"
 	"github.com/ochko-b/goapp/internal/services"
 	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
 )
 
 type AuthHandler struct {
 	authService *services.AuthService
-	validator   *validator.Validate
 }
 
 func NewAuthHandler(authService *services.AuthService) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
-		validator:   validator.New(),
 	}
 }
 
 func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	var req models.RegisterRequest
-	if err := c.BodyParser(&req); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
-	}
-
-	if err := h.validator.Struct(&req); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	if err := validator.Body(c, &req); err != nil {
+		return err
 	}
 
-	user, token, err := h.authService.Register(c.Context(), &req)
+	user, tokens, err := h.authService.Register(c.Context(), &req, clientInfo(c))
 	if err != nil {
-		if strings.Contains(err.Error(), "duplicate key") {
-			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Email already exists")
-		}
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	response := models.AuthResponse{
-		User:  *user,
-		Token: token,
+		User:      *user,
+		TokenPair: tokens,
 	}
 
 	return utils.SuccessResponse(c, response, "User registered successfully")
@@ -51,35 +40,142 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	var req models.LoginRequest
 
-	if err := c.BodyParser(&req); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	response, err := h.authService.Login(c.Context(), &req, clientInfo(c))
+	if err != nil {
+		return err
 	}
 
-	if err := h.validator.Struct(&req); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	if response.MFARequired {
+		return utils.SuccessResponse(c, response, "Two-factor authentication required")
 	}
 
-	user, token, err := h.authService.Login(c.Context(), &req)
+	return utils.SuccessResponse(c, response, "Login successful")
+}
+
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req models.RefreshRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	user, tokens, err := h.authService.Refresh(c.Context(), req.RefreshToken, clientInfo(c))
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Invalid credentials")
+		return err
 	}
 
 	response := models.AuthResponse{
-		User:  *user,
-		Token: token,
+		User:      *user,
+		TokenPair: tokens,
 	}
 
-	return utils.SuccessResponse(c, response, "Login successful")
+	return utils.SuccessResponse(c, response, "Token refreshed successfully")
 }
 
-func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	claims := c.Locals("claims").(*utils.Claims)
+
+	var req models.LogoutRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.authService.Logout(c.Context(), claims, req.RefreshToken); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "Logged out successfully")
+}
+
+func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
-	email := c.Locals("email").(string)
 
-	token, err := h.authService.RefreshToken(userID, email)
+	if err := h.authService.LogoutAll(c.Context(), userID); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "Logged out from all sessions")
+}
+
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req models.ForgotPasswordRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.authService.ForgotPassword(c.Context(), req.Email); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "If an account exists for this email, a password reset link has been sent")
+}
+
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req models.ResetPasswordRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.authService.ResetPassword(c.Context(), req.Token, req.Password); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "Password has been reset successfully")
+}
+
+func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
+	var req models.VerifyEmailRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.authService.VerifyEmail(c.Context(), req.Token); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "Email verified successfully")
+}
+
+func (h *AuthHandler) ResendVerification(c *fiber.Ctx) error {
+	var req models.ResendVerificationRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.authService.ResendVerification(c.Context(), req.Email); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "If this email needs verification, a new link has been sent")
+}
+
+func (h *AuthHandler) UnlockAccount(c *fiber.Ctx) error {
+	var req models.UnlockAccountRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.authService.UnlockWithToken(c.Context(), req.Token, c.IP()); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "Account unlocked")
+}
+
+func (h *AuthHandler) UnlockUser(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+
+	userID, err := validator.UUIDParam(c, "id")
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+		return err
+	}
+
+	if err := h.authService.UnlockAccount(c.Context(), actorID, userID, c.IP()); err != nil {
+		return err
 	}
 
-	return utils.SuccessResponse(c, fiber.Map{"token": token}, "Token refreshed successfully")
+	return utils.SuccessResponse(c, nil, "Account unlocked")
 }
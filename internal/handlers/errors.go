@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/i18n"
+	"github.com/ochko-b/goapp/internal/oauth"
+	"github.com/ochko-b/goapp/internal/services"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+// Errors of requests pkg/validator could not parse.
+var (
+	errInvalidBody  = apperror.Validation("invalid_request_body", "Invalid request body")
+	errInvalidQuery = apperror.Validation("invalid_query", "Invalid query parameters")
+)
+
+// errInternal is what clients see of any internal error.
+var errInternal = apperror.New(apperror.KindInternal, apperror.CodeInternal, "Internal server error")
+
+var kindStatus = map[apperror.Kind]int{
+	apperror.KindValidation:    fiber.StatusBadRequest,
+	apperror.KindUnauthorized:  fiber.StatusUnauthorized,
+	apperror.KindForbidden:     fiber.StatusForbidden,
+	apperror.KindNotFound:      fiber.StatusNotFound,
+	apperror.KindConflict:      fiber.StatusConflict,
+	apperror.KindUnprocessable: fiber.StatusUnprocessableEntity,
+	apperror.KindRateLimited:   fiber.StatusTooManyRequests,
+}
+
+// ErrorHandler is the app's fiber.Config.ErrorHandler. Handlers and
+// middleware return errors as they are, and this writes each kind of error
+// as an RFC 9457 problem, so errors look the same on every route. Internal
+// errors are logged and reach the client only as a generic message. Messages
+// are in the language of the request; the code of an apperror stays the same
+// in every language.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	locale := utils.Locale(c)
+
+	var fiberErr *fiber.Error
+	var invalid *validator.Error
+	var throttled *services.LoginThrottledError
+	var weak *services.PasswordPolicyError
+	var oauthErr *oauth.Error
+	switch {
+	case errors.As(err, &fiberErr):
+		return utils.ErrorResponse(c, fiberErr.Code, fiberErr.Message)
+	case errors.Is(err, validator.ErrInvalidBody):
+		return appErrorResponse(c, locale, fiber.StatusBadRequest, errInvalidBody)
+	case errors.Is(err, validator.ErrInvalidQuery):
+		return appErrorResponse(c, locale, fiber.StatusBadRequest, errInvalidQuery)
+	case errors.As(err, &invalid):
+		detail := i18n.T(locale, "details.validation", "One or more fields are invalid")
+		return utils.ValidationErrorResponse(c, detail, invalid.Localize(locale))
+	case errors.As(err, &throttled):
+		return tooManyAttemptsResponse(c, locale, throttled)
+	case errors.As(err, &weak):
+		return weakPasswordResponse(c, locale, weak)
+	case errors.As(err, &oauthErr):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, oauthErr.Description)
+	}
+
+	appErr := apperror.From(err)
+	status, ok := kindStatus[appErr.Kind]
+	if !ok {
+		log.Printf("[%v] %s %s: %v", c.Locals(utils.RequestIDKey), c.Method(), c.Path(), err)
+		return appErrorResponse(c, locale, fiber.StatusInternalServerError, errInternal)
+	}
+	return appErrorResponse(c, locale, status, appErr)
+}
+
+// appErrorResponse writes err with its message from the catalog of locale.
+// Errors without a catalog entry keep their own message.
+func appErrorResponse(c *fiber.Ctx, locale string, status int, err *apperror.Error) error {
+	return utils.ProblemResponse(c, utils.Problem{
+		Type:   utils.ProblemTypeBlank,
+		Status: status,
+		Detail: i18n.T(locale, "errors."+err.Code, err.Message),
+		Code:   err.Code,
+	})
+}
+
+func tooManyAttemptsResponse(c *fiber.Ctx, locale string, err *services.LoginThrottledError) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(err.RetryAfter.Seconds())))
+	return appErrorResponse(c, locale, fiber.StatusTooManyRequests, services.ErrTooManyLoginAttempts)
+}
+
+// weakPasswordResponse lists each failed policy rule as an error of the
+// password field, like the rules checked by the validator.
+func weakPasswordResponse(c *fiber.Ctx, locale string, err *services.PasswordPolicyError) error {
+	field := err.Field
+	if field == "" {
+		field = "password"
+	}
+
+	fields := make([]validator.FieldError, 0, len(err.Violations))
+	for _, v := range err.Violations {
+		fields = append(fields, validator.FieldError{
+			Field:   field,
+			Rule:    v.Rule,
+			Message: i18n.T(locale, "password."+v.Rule, field+" "+v.Message, field, v.Param),
+		})
+	}
+	weak := services.ErrWeakPassword
+	return utils.ValidationErrorResponse(c, i18n.T(locale, "errors."+weak.Code, weak.Message), fields)
+}
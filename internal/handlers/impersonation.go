@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+func (h *AuthHandler) Impersonate(c *fiber.Ctx) error {
+	claims := c.Locals("claims").(*utils.Claims)
+
+	var req models.ImpersonateRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	userID, err := validator.UUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	response, err := h.authService.Impersonate(c.Context(), claims, userID, &req, c.IP())
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, response, "Impersonation started")
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/services"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+type InvitationHandler struct {
+	invitationService *services.InvitationService
+}
+
+func NewInvitationHandler(invitationService *services.InvitationService) *InvitationHandler {
+	return &InvitationHandler{
+		invitationService: invitationService,
+	}
+}
+
+func (h *InvitationHandler) Create(c *fiber.Ctx) error {
+	claims := c.Locals("claims").(*utils.Claims)
+
+	var req models.CreateInvitationRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	invitation, err := h.invitationService.Create(c.Context(), claims, &req, c.IP())
+	if err != nil {
+		return err
+	}
+
+	c.Status(fiber.StatusCreated)
+	return utils.SuccessResponse(c, invitation, "Invitation sent")
+}
+
+func (h *InvitationHandler) List(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 10)
+	offset := c.QueryInt("offset", 0)
+
+	if limit > 100 {
+		limit = 100
+	}
+
+	invitations, err := h.invitationService.List(c.Context(), c.Query("status"), int32(limit), int32(offset))
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, fiber.Map{
+		"invitations": invitations,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+func (h *InvitationHandler) Resend(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	invitationID, err := validator.UUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	invitation, err := h.invitationService.Resend(c.Context(), userID, invitationID, c.IP())
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, invitation, "Invitation resent")
+}
+
+func (h *InvitationHandler) Revoke(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	invitationID, err := validator.UUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.invitationService.Revoke(c.Context(), userID, invitationID, c.IP()); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "Invitation revoked")
+}
+
+func (h *InvitationHandler) Lookup(c *fiber.Ctx) error {
+	var req models.InvitationTokenRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	details, err := h.invitationService.Lookup(c.Context(), req.Token)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, details)
+}
+
+func (h *InvitationHandler) Accept(c *fiber.Ctx) error {
+	var req models.AcceptInvitationRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	response, err := h.invitationService.Accept(c.Context(), &req, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	c.Status(fiber.StatusCreated)
+	return utils.SuccessResponse(c, response, "Invitation accepted")
+}
+
+func (h *InvitationHandler) AcceptWithOAuth(c *fiber.Ctx) error {
+	var req models.AcceptInvitationOAuthRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	response, err := h.invitationService.AcceptWithOAuth(c.Context(), c.Params("provider"), &req, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	c.Status(fiber.StatusCreated)
+	return utils.SuccessResponse(c, response, "Invitation accepted")
+}
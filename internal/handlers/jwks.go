@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+type JWKSHandler struct {
+	keys *utils.KeyRing
+}
+
+func NewJWKSHandler(keys *utils.KeyRing) *JWKSHandler {
+	return &JWKSHandler{
+		keys: keys,
+	}
+}
+
+// Keys publishes the public verification keys as a JWK Set (RFC 7517). The
+// body is the bare key set rather than the usual response envelope, since
+// JWKS clients expect the standard format.
+func (h *JWKSHandler) Keys(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(h.keys.JWKS())
+}
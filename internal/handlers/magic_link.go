@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+func (h *AuthHandler) RequestMagicLink(c *fiber.Ctx) error {
+	var req models.MagicLinkRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.authService.RequestMagicLink(c.Context(), req.Email, c.IP()); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "If an account exists for this email, a sign-in link has been sent")
+}
+
+func (h *AuthHandler) ConsumeMagicLink(c *fiber.Ctx) error {
+	var req models.ConsumeMagicLinkRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	response, err := h.authService.ConsumeMagicLink(c.Context(), req.Token, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	if response.MFARequired {
+		return utils.SuccessResponse(c, response, "Two-factor authentication required")
+	}
+
+	return utils.SuccessResponse(c, response, "Login successful")
+}
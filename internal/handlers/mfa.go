@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+func (h *AuthHandler) SetupTOTP(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	setup, err := h.authService.SetupTOTP(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, setup, "Scan the QR code and confirm with a code from your authenticator app")
+}
+
+func (h *AuthHandler) ConfirmTOTP(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.MFACodeRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	codes, err := h.authService.ConfirmTOTP(c.Context(), userID, req.Code)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, models.RecoveryCodesResponse{RecoveryCodes: codes}, "Two-factor authentication enabled")
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.MFACodeRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(c.Context(), userID, req.Code)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, models.RecoveryCodesResponse{RecoveryCodes: codes}, "Recovery codes regenerated")
+}
+
+func (h *AuthHandler) DisableMFA(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.MFACodeRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.authService.DisableMFA(c.Context(), userID, req.Code); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "Two-factor authentication disabled")
+}
+
+func (h *AuthHandler) VerifyMFA(c *fiber.Ctx) error {
+	var req models.MFAVerifyRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	response, err := h.authService.VerifyMFA(c.Context(), req.MFAToken, req.Code, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, response, "Login successful")
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/services"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+type OAuthHandler struct {
+	oauthService *services.OAuthService
+}
+
+func NewOAuthHandler(oauthService *services.OAuthService) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+	}
+}
+
+func (h *OAuthHandler) Providers(c *fiber.Ctx) error {
+	return utils.SuccessResponse(c, fiber.Map{"providers": h.oauthService.Providers()})
+}
+
+func (h *OAuthHandler) Authorize(c *fiber.Ctx) error {
+	authURL, err := h.oauthService.AuthorizationURL(c.Context(), c.Params("provider"), "")
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, models.OAuthAuthorizationResponse{AuthorizationURL: authURL})
+}
+
+func (h *OAuthHandler) Callback(c *fiber.Ctx) error {
+	var req models.OAuthCallbackRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	response, err := h.oauthService.Login(c.Context(), c.Params("provider"), &req, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	if response.MFARequired {
+		return utils.SuccessResponse(c, response, "Two-factor authentication required")
+	}
+
+	return utils.SuccessResponse(c, response, "Login successful")
+}
+
+func (h *OAuthHandler) AuthorizeLink(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	authURL, err := h.oauthService.AuthorizationURL(c.Context(), c.Params("provider"), userID)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, models.OAuthAuthorizationResponse{AuthorizationURL: authURL})
+}
+
+func (h *OAuthHandler) Link(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.OAuthCallbackRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	identity, err := h.oauthService.Link(c.Context(), userID, c.Params("provider"), &req, c.IP())
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c.Status(fiber.StatusCreated), identity, "Account linked successfully")
+}
+
+func (h *OAuthHandler) ListIdentities(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	identities, err := h.oauthService.ListIdentities(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, identities)
+}
+
+func (h *OAuthHandler) Unlink(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	identityID, err := validator.UUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.oauthService.Unlink(c.Context(), userID, identityID, c.IP()); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "Account unlinked successfully")
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/oauth"
+	"github.com/ochko-b/goapp/internal/services"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+// OAuthServerHandler serves the authorization server. The /oauth endpoints
+// speak plain OAuth 2.0 (form bodies, RFC 6749 error objects) because client
+// libraries expect that; client registration and the consent screen API use
+// the usual response envelope.
+type OAuthServerHandler struct {
+	oauthServerService *services.OAuthServerService
+}
+
+func NewOAuthServerHandler(oauthServerService *services.OAuthServerService) *OAuthServerHandler {
+	return &OAuthServerHandler{
+		oauthServerService: oauthServerService,
+	}
+}
+
+func (h *OAuthServerHandler) Metadata(c *fiber.Ctx) error {
+	return c.JSON(h.oauthServerService.Metadata())
+}
+
+// Authorize checks the request and sends the browser on to the consent
+// screen, or back to the client if the request is invalid.
+func (h *OAuthServerHandler) Authorize(c *fiber.Ctx) error {
+	var req models.AuthorizeRequest
+	if err := c.QueryParser(&req); err != nil {
+		return oauthProtocolError(c, &oauth.Error{Code: oauth.ErrorInvalidRequest})
+	}
+
+	redirect, err := h.oauthServerService.AuthorizeRedirect(c.Context(), &req)
+	if err != nil {
+		return oauthProtocolError(c, err)
+	}
+
+	return c.Redirect(redirect, fiber.StatusFound)
+}
+
+func (h *OAuthServerHandler) Token(c *fiber.Ctx) error {
+	var req models.TokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthProtocolError(c, &oauth.Error{Code: oauth.ErrorInvalidRequest})
+	}
+	if err := clientAuthentication(c, &req.ClientID, &req.ClientSecret); err != nil {
+		return oauthProtocolError(c, err)
+	}
+
+	tokens, err := h.oauthServerService.Token(c.Context(), &req, clientInfo(c))
+	if err != nil {
+		return oauthProtocolError(c, err)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderPragma, "no-cache")
+	return c.JSON(tokens)
+}
+
+func (h *OAuthServerHandler) Introspect(c *fiber.Ctx) error {
+	var req models.TokenActionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthProtocolError(c, &oauth.Error{Code: oauth.ErrorInvalidRequest})
+	}
+	if err := clientAuthentication(c, &req.ClientID, &req.ClientSecret); err != nil {
+		return oauthProtocolError(c, err)
+	}
+
+	response, err := h.oauthServerService.Introspect(c.Context(), &req)
+	if err != nil {
+		return oauthProtocolError(c, err)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(response)
+}
+
+func (h *OAuthServerHandler) Revoke(c *fiber.Ctx) error {
+	var req models.TokenActionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthProtocolError(c, &oauth.Error{Code: oauth.ErrorInvalidRequest})
+	}
+	if err := clientAuthentication(c, &req.ClientID, &req.ClientSecret); err != nil {
+		return oauthProtocolError(c, err)
+	}
+
+	if err := h.oauthServerService.Revoke(c.Context(), &req); err != nil {
+		return oauthProtocolError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// Consent returns what the consent screen should show for the request in the
+// query string.
+func (h *OAuthServerHandler) Consent(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.AuthorizeRequest
+	if err := validator.Query(c, &req); err != nil {
+		return err
+	}
+
+	consent, err := h.oauthServerService.Consent(c.Context(), userID, &req)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, consent)
+}
+
+// Decide records the user's answer on the consent screen. The response says
+// where the browser goes next, whether the user approved or not.
+func (h *OAuthServerHandler) Decide(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.AuthorizeDecisionRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	redirect, err := h.oauthServerService.Decide(c.Context(), userID, &req, c.IP())
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, redirect)
+}
+
+func (h *OAuthServerHandler) CreateClient(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.CreateOAuthClientRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	client, err := h.oauthServerService.CreateClient(c.Context(), userID, &req)
+	if err != nil {
+		return err
+	}
+
+	message := "OAuth client registered"
+	if client.ClientSecret != "" {
+		message = "OAuth client registered. Store the secret now; it will not be shown again"
+	}
+	return utils.SuccessResponse(c.Status(fiber.StatusCreated), client, message)
+}
+
+func (h *OAuthServerHandler) ListClients(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	clients, err := h.oauthServerService.ListClients(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, clients)
+}
+
+func (h *OAuthServerHandler) GetClient(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	clientID, err := validator.UUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	client, err := h.oauthServerService.GetClient(c.Context(), userID, clientID)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, client)
+}
+
+func (h *OAuthServerHandler) DeleteClient(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	clientID, err := validator.UUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.oauthServerService.DeleteClient(c.Context(), userID, clientID); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "OAuth client deleted")
+}
+
+// clientAuthentication reads client_secret_basic credentials into id and
+// secret. Clients may use the header or the body, but not both (RFC 6749
+// section 2.3).
+func clientAuthentication(c *fiber.Ctx, id, secret *string) error {
+	header := c.Get(fiber.HeaderAuthorization)
+	if header == "" {
+		return nil
+	}
+
+	scheme, encoded, _ := strings.Cut(header, " ")
+	if !strings.EqualFold(scheme, "Basic") {
+		return &oauth.Error{Code: oauth.ErrorInvalidClient, Description: "unsupported authentication scheme"}
+	}
+	if *secret != "" {
+		return &oauth.Error{Code: oauth.ErrorInvalidRequest, Description: "use only one client authentication method"}
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return &oauth.Error{Code: oauth.ErrorInvalidClient}
+	}
+	rawID, rawSecret, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return &oauth.Error{Code: oauth.ErrorInvalidClient}
+	}
+
+	// Both parts are form-encoded before they go into the header
+	if *id, err = url.QueryUnescape(rawID); err != nil {
+		return &oauth.Error{Code: oauth.ErrorInvalidClient}
+	}
+	if *secret, err = url.QueryUnescape(rawSecret); err != nil {
+		return &oauth.Error{Code: oauth.ErrorInvalidClient}
+	}
+	return nil
+}
+
+// oauthProtocolError writes an RFC 6749 error object. Unexpected errors are
+// logged, like internal errors in ErrorHandler, and reported as server_error.
+func oauthProtocolError(c *fiber.Ctx, err error) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	var oauthErr *oauth.Error
+	switch {
+	case errors.As(err, &oauthErr):
+	case errors.Is(err, services.ErrOAuthClientNotFound):
+		oauthErr = &oauth.Error{Code: oauth.ErrorInvalidRequest, Description: err.Error()}
+	default:
+		log.Printf("[%v] %s %s: %v", c.Locals(utils.RequestIDKey), c.Method(), c.Path(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(&oauth.Error{Code: "server_error"})
+	}
+
+	status := fiber.StatusBadRequest
+	if oauthErr.Code == oauth.ErrorInvalidClient {
+		status = fiber.StatusUnauthorized
+		c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="oauth"`)
+	}
+	return c.Status(status).JSON(oauthErr)
+}
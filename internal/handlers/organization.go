@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/services"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+type OrganizationHandler struct {
+	orgService *services.OrganizationService
+}
+
+func NewOrganizationHandler(orgService *services.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		orgService: orgService,
+	}
+}
+
+func (h *OrganizationHandler) Create(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.CreateOrganizationRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	org, err := h.orgService.Create(c.Context(), userID, &req)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c.Status(fiber.StatusCreated), org, "Organization created successfully")
+}
+
+func (h *OrganizationHandler) List(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	orgs, err := h.orgService.ListForUser(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, orgs)
+}
+
+func (h *OrganizationHandler) Get(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	orgID := c.Locals("org_id").(string)
+
+	org, err := h.orgService.Get(c.Context(), orgID, userID)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, org)
+}
+
+func (h *OrganizationHandler) Update(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	orgID := c.Locals("org_id").(string)
+
+	var req models.UpdateOrganizationRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	org, err := h.orgService.Update(c.Context(), orgID, userID, &req)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, org, "Organization updated successfully")
+}
+
+func (h *OrganizationHandler) ListMembers(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	orgID := c.Locals("org_id").(string)
+
+	members, err := h.orgService.ListMembers(c.Context(), orgID, userID)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, members)
+}
+
+func (h *OrganizationHandler) AddMember(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	orgID := c.Locals("org_id").(string)
+
+	var req models.AddMemberRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	member, err := h.orgService.AddMember(c.Context(), orgID, userID, &req)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, member, "Member added successfully")
+}
+
+func (h *OrganizationHandler) UpdateMemberRole(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	orgID := c.Locals("org_id").(string)
+
+	memberID, err := validator.UUIDParam(c, "userId")
+	if err != nil {
+		return err
+	}
+
+	var req models.UpdateMemberRoleRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.orgService.UpdateMemberRole(c.Context(), orgID, userID, memberID, req.Role); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "Member role updated successfully")
+}
+
+func (h *OrganizationHandler) RemoveMember(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	orgID := c.Locals("org_id").(string)
+
+	memberID, err := validator.UUIDParam(c, "userId")
+	if err != nil {
+		return err
+	}
+
+	if err := h.orgService.RemoveMember(c.Context(), orgID, userID, memberID); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "Member removed successfully")
+}
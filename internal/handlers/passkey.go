@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+func (h *AuthHandler) BeginPasskeyRegistration(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	options, err := h.authService.BeginPasskeyRegistration(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, options, "Pass the options to navigator.credentials.create()")
+}
+
+func (h *AuthHandler) FinishPasskeyRegistration(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.PasskeyRegisterRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	passkey, err := h.authService.FinishPasskeyRegistration(c.Context(), userID, &req, c.IP())
+	if err != nil {
+		return err
+	}
+
+	c.Status(fiber.StatusCreated)
+	return utils.SuccessResponse(c, passkey, "Passkey registered")
+}
+
+func (h *AuthHandler) ListPasskeys(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	passkeys, err := h.authService.ListPasskeys(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, passkeys)
+}
+
+func (h *AuthHandler) DeletePasskey(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	passkeyID, err := validator.UUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.authService.DeletePasskey(c.Context(), userID, passkeyID, c.IP()); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "Passkey removed")
+}
+
+func (h *AuthHandler) BeginPasskeyLogin(c *fiber.Ctx) error {
+	options, err := h.authService.BeginPasskeyLogin(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, options, "Pass the options to navigator.credentials.get()")
+}
+
+func (h *AuthHandler) FinishPasskeyLogin(c *fiber.Ctx) error {
+	var req models.PasskeyLoginRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	response, err := h.authService.FinishPasskeyLogin(c.Context(), &req, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, response, "Login successful")
+}
+
+func (h *AuthHandler) BeginPasskeyMFA(c *fiber.Ctx) error {
+	var req models.PasskeyMFABeginRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	options, err := h.authService.BeginPasskeyMFA(c.Context(), req.MFAToken)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, options, "Pass the options to navigator.credentials.get()")
+}
+
+func (h *AuthHandler) FinishPasskeyMFA(c *fiber.Ctx) error {
+	var req models.PasskeyMFARequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	response, err := h.authService.FinishPasskeyMFA(c.Context(), &req, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, response, "Login successful")
+}
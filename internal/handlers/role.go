@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/services"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+type RoleHandler struct {
+	rbacService *services.RBACService
+}
+
+func NewRoleHandler(rbacService *services.RBACService) *RoleHandler {
+	return &RoleHandler{
+		rbacService: rbacService,
+	}
+}
+
+func (h *RoleHandler) ListRoles(c *fiber.Ctx) error {
+	roles, err := h.rbacService.ListRoles(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, roles)
+}
+
+func (h *RoleHandler) AssignRole(c *fiber.Ctx) error {
+	userID, err := validator.UUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req models.AssignRoleRequest
+	if err := validator.Body(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.rbacService.GrantRole(c.Context(), userID, req.Role); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "Role assigned successfully")
+}
+
+func (h *RoleHandler) RemoveRole(c *fiber.Ctx) error {
+	userID, err := validator.UUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.rbacService.RevokeRole(c.Context(), userID, c.Params("role")); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "Role removed successfully")
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/services"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+type SessionHandler struct {
+	sessionService *services.SessionService
+}
+
+func NewSessionHandler(sessionService *services.SessionService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+	}
+}
+
+func (h *SessionHandler) ListMine(c *fiber.Ctx) error {
+	claims := c.Locals("claims").(*utils.Claims)
+
+	sessions, err := h.sessionService.List(c.Context(), claims.UserID, claims.SessionID)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, sessions)
+}
+
+func (h *SessionHandler) RevokeMine(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	sessionID, err := validator.UUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.sessionService.Revoke(c.Context(), userID, userID, sessionID, c.IP()); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "Session signed out")
+}
+
+// List returns the sessions of any user on behalf of an admin.
+func (h *SessionHandler) List(c *fiber.Ctx) error {
+	claims := c.Locals("claims").(*utils.Claims)
+
+	userID, err := validator.UUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	currentSessionID := ""
+	if userID == claims.UserID {
+		currentSessionID = claims.SessionID
+	}
+
+	sessions, err := h.sessionService.List(c.Context(), userID, currentSessionID)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, sessions)
+}
+
+// Revoke signs out a session of any user on behalf of an admin.
+func (h *SessionHandler) Revoke(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+
+	userID, err := validator.UUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	sessionID, err := validator.UUIDParam(c, "sessionId")
+	if err != nil {
+		return err
+	}
+
+	if err := h.sessionService.Revoke(c.Context(), actorID, userID, sessionID, c.IP()); err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, nil, "Session signed out")
+}
+
+// clientInfo describes the device the request came from, for the session a
+// sign-in starts.
+func clientInfo(c *fiber.Ctx) services.ClientInfo {
+	return services.ClientInfo{
+		IP:        c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+}
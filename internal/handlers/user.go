@@ -1,23 +1,20 @@
 package handlers
 
 import (
-	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
-	"github.com/google/uuid"
 	"github.com/ochko-b/goapp/internal/models"
 	"github.com/ochko-b/goapp/internal/services"
 	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
 )
 
 type UserHandler struct {
 	userService *services.UserService
-	validator   *validator.Validate
 }
 
 func NewUserHandler(userService *services.UserService) *UserHandler {
 	return &UserHandler{
 		userService: userService,
-		validator:   validator.New(),
 	}
 }
 
@@ -26,7 +23,7 @@ func (h *UserHandler) GetProfile(c *fiber.Ctx) error {
 
 	user, err := h.userService.GetByID(c.Context(), userID)
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "User Not Found")
+		return err
 	}
 
 	return utils.SuccessResponse(c, user)
@@ -36,54 +33,46 @@ func (h *UserHandler) UpdateProfile(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 
 	var req models.UpdateProfileRequest
-	if err := c.BodyParser(&req); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid Request Body")
-	}
-
-	if err := h.validator.Struct(&req); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	if err := validator.Body(c, &req); err != nil {
+		return err
 	}
 
 	user, err := h.userService.UpdateProfile(c.Context(), userID, &req)
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	return utils.SuccessResponse(c, user, "Profile updated successfully")
 }
 
 func (h *UserHandler) UpdateUserTransaction(c *fiber.Ctx) error {
-	userID := c.Params("id")
-	if _, err := uuid.Parse(userID); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID")
+	userID, err := validator.UUIDParam(c, "id")
+	if err != nil {
+		return err
 	}
 
 	var req models.UpdateProfileRequest
-	if err := c.BodyParser(&req); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid Request Body")
-	}
-
-	if err := h.validator.Struct(&req); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "here?")
+	if err := validator.Body(c, &req); err != nil {
+		return err
 	}
 
 	user, err := h.userService.UpdateUserWithTransaction(c.Context(), userID, &req)
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	return utils.SuccessResponse(c, user, "User updated successfully")
 }
 
 func (h *UserHandler) GetUser(c *fiber.Ctx) error {
-	userID := c.Params("id")
-	if _, err := uuid.Parse(userID); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID")
+	userID, err := validator.UUIDParam(c, "id")
+	if err != nil {
+		return err
 	}
 
 	user, err := h.userService.GetByID(c.Context(), userID)
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found")
+		return err
 	}
 
 	return utils.SuccessResponse(c, user)
@@ -99,7 +88,7 @@ func (h *UserHandler) ListUser(c *fiber.Ctx) error {
 
 	users, err := h.userService.List(c.Context(), int32(limit), int32(offset))
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	return utils.SuccessResponse(c, fiber.Map{
@@ -0,0 +1,70 @@
+// Package i18n holds the message catalogs of the API. Each locale is a flat
+// JSON file in locales/ mapping keys to messages, where {0}, {1}, ... are
+// parameters. Keys are grouped by prefix:
+//
+//	errors.<code>      the message of an apperror code
+//	titles.<status>    the title of a problem with that HTTP status
+//	password.<rule>    a password policy violation, {0} the field, {1} the limit
+//
+// The catalogs are added to the validator's translators, so validation and
+// error messages of a locale come from the same place.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+//go:embed locales
+var catalogFS embed.FS
+
+// Locales lists the supported locales, the default first.
+func Locales() []string {
+	return validator.Locales()
+}
+
+// Catalog reads the messages of locale.
+func Catalog(locale string) (map[string]string, error) {
+	data, err := catalogFS.ReadFile("locales/" + locale + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("i18n: no catalog for %s: %w", locale, err)
+	}
+
+	var messages map[string]string
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("i18n: failed to parse %s catalog: %w", locale, err)
+	}
+	return messages, nil
+}
+
+// Load adds the catalog of every locale to its translator. Until it is
+// called, T returns the fallback messages.
+func Load() error {
+	for _, locale := range Locales() {
+		messages, err := Catalog(locale)
+		if err != nil {
+			return err
+		}
+
+		trans := validator.Translator(locale)
+		for key, text := range messages {
+			if err := trans.Add(key, text, true); err != nil {
+				return fmt.Errorf("i18n: invalid %s message %s: %w", locale, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// T returns the message key in locale, or fallback when the catalog has no
+// such key. Unsupported locales get the default locale.
+func T(locale, key, fallback string, params ...string) string {
+	text, err := validator.Translator(locale).T(key, params...)
+	if err != nil || text == "" {
+		return fallback
+	}
+	return text
+}
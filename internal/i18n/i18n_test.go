@@ -0,0 +1,105 @@
+package i18n
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+var placeholder = regexp.MustCompile(`\{\d+\}`)
+
+// TestCatalogsComplete checks that every locale has a message for every key
+// of the default catalog, with the same parameters, and no keys of its own.
+func TestCatalogsComplete(t *testing.T) {
+	base, err := Catalog(validator.DefaultLocale)
+	if err != nil {
+		t.Fatalf("Catalog(%s): %v", validator.DefaultLocale, err)
+	}
+
+	for _, locale := range Locales() {
+		messages, err := Catalog(locale)
+		if err != nil {
+			t.Fatalf("Catalog(%s): %v", locale, err)
+		}
+
+		for key, text := range base {
+			got, ok := messages[key]
+			switch {
+			case !ok:
+				t.Errorf("%s: missing %s", locale, key)
+			case got == "":
+				t.Errorf("%s: empty %s", locale, key)
+			case !samePlaceholders(got, text):
+				t.Errorf("%s: %s has parameters %v, want %v", locale, key,
+					placeholder.FindAllString(got, -1), placeholder.FindAllString(text, -1))
+			}
+		}
+		for key := range messages {
+			if _, ok := base[key]; !ok {
+				t.Errorf("%s: %s is not in the %s catalog", locale, key, validator.DefaultLocale)
+			}
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	if err := Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if got, want := T("de", "errors.user_not_found", "user not found"), "Benutzer nicht gefunden"; got != want {
+		t.Errorf("T(de) = %q, want %q", got, want)
+	}
+	if got, want := T("fr", "errors.user_not_found", "fallback"), "user not found"; got != want {
+		t.Errorf("T(fr) = %q, want the default locale's %q", got, want)
+	}
+	if got, want := T("de", "errors.no_such_code", "fallback"), "fallback"; got != want {
+		t.Errorf("T(unknown key) = %q, want %q", got, want)
+	}
+	if got, want := T("en", "password.min_length", "", "password", "12"), "password must be at least 12 characters long"; got != want {
+		t.Errorf("T(params) = %q, want %q", got, want)
+	}
+}
+
+func samePlaceholders(a, b string) bool {
+	pa := placeholder.FindAllString(a, -1)
+	pb := placeholder.FindAllString(b, -1)
+	slices.Sort(pa)
+	slices.Sort(pb)
+	return slices.Equal(pa, pb)
+}
+
+// TestErrorCodesCovered checks that every apperror code in the source has a
+// message in the default catalog, so no error reaches clients untranslated.
+func TestErrorCodesCovered(t *testing.T) {
+	base, err := Catalog(validator.DefaultLocale)
+	if err != nil {
+		t.Fatalf("Catalog: %v", err)
+	}
+
+	code := regexp.MustCompile(`apperror\.\w+\((?:apperror\.Kind\w+, )?"([a-z_]+)"`)
+	err = filepath.WalkDir("..", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, m := range code.FindAllSubmatch(src, -1) {
+			if _, ok := base["errors."+string(m[1])]; !ok {
+				t.Errorf("%s: code %s has no message", path, m[1])
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ochko-b/goapp/internal/config"
+)
+
+// Mailer delivers a single email message.
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Mail drivers
+const (
+	DriverSMTP   = "smtp"
+	DriverFile   = "file"
+	DriverMemory = "memory"
+)
+
+// New returns the backend selected by cfg.Driver.
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg), nil
+	case DriverFile:
+		return NewFileMailer(cfg.FileDir, cfg.From)
+	case DriverMemory:
+		return NewMemoryMailer(cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,366 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ochko-b/goapp/internal/config"
+)
+
+// fakeSMTPServer is a local SMTP stand-in that accepts every message and
+// records the envelope and data of each session.
+type fakeSMTPServer struct {
+	t        *testing.T
+	listener net.Listener
+
+	// stall makes the server accept connections without ever greeting.
+	stall bool
+
+	mu       sync.Mutex
+	sessions []smtpSession
+}
+
+type smtpSession struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+func newFakeSMTPServer(t *testing.T, stall bool) *fakeSMTPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTPServer{t: t, listener: listener, stall: stall}
+	t.Cleanup(func() { listener.Close() })
+
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) config() config.MailConfig {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return config.MailConfig{
+		SMTPHost: host,
+		SMTPPort: portNumber,
+		From:     "GoApp <no-reply@example.com>",
+	}
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		if s.stall {
+			// Hold the connection open until the test ends.
+			s.t.Cleanup(func() { conn.Close() })
+			continue
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	var session smtpSession
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			_, credentials, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(credentials)
+			session.auth = string(decoded)
+			reply("235 Authentication successful")
+		case "MAIL":
+			session.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			reply("250 OK")
+		case "RCPT":
+			session.to = append(session.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			session.data = data.String()
+			s.mu.Lock()
+			s.sessions = append(s.sessions, session)
+			s.mu.Unlock()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) received() []smtpSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpSession(nil), s.sessions...)
+}
+
+func testMessage() *Message {
+	return &Message{
+		To:      []string{"Jane Doe <jane@example.com>", "john@example.com"},
+		Subject: "Grüße",
+		Text:    "Hello",
+		HTML:    "<p>Hello</p>",
+	}
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	server := newFakeSMTPServer(t, false)
+	cfg := server.config()
+	cfg.SMTPUsername = "user"
+	cfg.SMTPPassword = "secret"
+
+	if err := NewSMTPMailer(cfg).Send(context.Background(), testMessage()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	sessions := server.received()
+	if len(sessions) != 1 {
+		t.Fatalf("server received %d messages, want 1", len(sessions))
+	}
+	got := sessions[0]
+	if got.auth != "\x00user\x00secret" {
+		t.Errorf("auth = %q", got.auth)
+	}
+	if got.from != "no-reply@example.com" {
+		t.Errorf("MAIL FROM = %q", got.from)
+	}
+	if strings.Join(got.to, ",") != "jane@example.com,john@example.com" {
+		t.Errorf("RCPT TO = %v", got.to)
+	}
+	for _, want := range []string{
+		"From: GoApp <no-reply@example.com>\r\n",
+		"To: Jane Doe <jane@example.com>, john@example.com\r\n",
+		"Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=\r\n",
+		"Content-Type: text/plain; charset=utf-8",
+		"<p>Hello</p>",
+	} {
+		if !strings.Contains(got.data, want) {
+			t.Errorf("message does not contain %q:\n%s", want, got.data)
+		}
+	}
+}
+
+func TestSMTPMailerSendRespectsDeadline(t *testing.T) {
+	server := newFakeSMTPServer(t, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- NewSMTPMailer(server.config()).Send(ctx, testMessage()) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected an error from a server that never answers")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Send did not give up at the context deadline")
+	}
+}
+
+func TestSMTPMailerRejectsInvalidRecipient(t *testing.T) {
+	server := newFakeSMTPServer(t, false)
+
+	msg := testMessage()
+	msg.To = []string{"not an address"}
+	if err := NewSMTPMailer(server.config()).Send(context.Background(), msg); err == nil {
+		t.Fatal("expected an error for an invalid recipient")
+	}
+	if n := len(server.received()); n != 0 {
+		t.Errorf("server received %d messages, want 0", n)
+	}
+}
+
+func TestMemoryMailer(t *testing.T) {
+	m := NewMemoryMailer("no-reply@example.com")
+
+	if _, ok := m.Last(); ok {
+		t.Fatal("Last reported a message before any was sent")
+	}
+
+	if err := m.Send(context.Background(), testMessage()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	second := testMessage()
+	second.From = "other@example.com"
+	second.Subject = "Second"
+	if err := m.Send(context.Background(), second); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	messages := m.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(messages))
+	}
+	if messages[0].From != "no-reply@example.com" {
+		t.Errorf("default sender not applied: %q", messages[0].From)
+	}
+	if last, _ := m.Last(); last.Subject != "Second" || last.From != "other@example.com" {
+		t.Errorf("Last = %+v", last)
+	}
+
+	// Messages returns a copy.
+	messages[0].Subject = "changed"
+	if m.Messages()[0].Subject == "changed" {
+		t.Error("Messages exposes the mailer's own slice")
+	}
+
+	m.Reset()
+	if len(m.Messages()) != 0 {
+		t.Error("Reset kept messages")
+	}
+}
+
+func TestMemoryMailerRejectsInvalidMessages(t *testing.T) {
+	tests := map[string]func(*Message){
+		"no recipients":       func(m *Message) { m.To = nil },
+		"header injection":    func(m *Message) { m.Subject = "Hi\r\nBcc: evil@example.com" },
+		"recipient injection": func(m *Message) { m.To = []string{"a@example.com\nBcc: evil@example.com"} },
+	}
+
+	for name, modify := range tests {
+		m := NewMemoryMailer("no-reply@example.com")
+		msg := testMessage()
+		modify(msg)
+		if err := m.Send(context.Background(), msg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if len(m.Messages()) != 0 {
+			t.Errorf("%s: invalid message was kept", name)
+		}
+	}
+
+	if err := NewMemoryMailer("").Send(context.Background(), testMessage()); err == nil {
+		t.Error("expected an error without a sender")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := NewFileMailer(dir, "no-reply@example.com")
+	if err != nil {
+		t.Fatalf("NewFileMailer: %v", err)
+	}
+
+	for range 2 {
+		if err := m.Send(context.Background(), testMessage()); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want one per message", len(files))
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "From: no-reply@example.com\r\n") {
+		t.Errorf("file does not start with the sender:\n%s", data)
+	}
+
+	msg := testMessage()
+	msg.To = nil
+	if err := m.Send(context.Background(), msg); err == nil {
+		t.Error("expected an error for a message without recipients")
+	}
+}
+
+func TestNew(t *testing.T) {
+	want := map[string]string{
+		DriverSMTP:   "*mailer.SMTPMailer",
+		DriverFile:   "*mailer.FileMailer",
+		DriverMemory: "*mailer.MemoryMailer",
+	}
+	for driver, typ := range want {
+		m, err := New(config.MailConfig{Driver: driver, FileDir: t.TempDir()})
+		if err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+		if got := fmt.Sprintf("%T", m); got != typ {
+			t.Errorf("%s: got %s, want %s", driver, got, typ)
+		}
+	}
+
+	if _, err := New(config.MailConfig{Driver: "carrier-pigeon"}); err == nil {
+		t.Error("expected an error for an unknown driver")
+	}
+}
+
+func TestTemplatesRender(t *testing.T) {
+	templates, err := LoadTemplates()
+	if err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+
+	msg, err := templates.Render("password_reset", map[string]string{
+		"Email":     "jane@example.com",
+		"URL":       "https://app.example.com/reset?token=a&b",
+		"ExpiresIn": "1 hour",
+	})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if msg.Subject == "" || !strings.Contains(msg.Text, "https://app.example.com/reset?token=a&b") {
+		t.Errorf("text part = %q, subject = %q", msg.Text, msg.Subject)
+	}
+	if !strings.Contains(msg.HTML, "token=a&amp;b") {
+		t.Errorf("HTML part does not escape the URL:\n%s", msg.HTML)
+	}
+
+	if _, err := templates.Render("missing", nil); err == nil {
+		t.Error("expected an error for an unknown template")
+	}
+}
@@ -0,0 +1,82 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Each email is a pair of files in templates/: <name>.txt is a text/template
+// that also defines the "subject" block, and <name>.html defines the
+// "content" block rendered inside layout.html.
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+type Templates struct {
+	emails map[string]emailTemplate
+}
+
+func LoadTemplates() (*Templates, error) {
+	files, err := fs.Glob(templateFS, "templates/*.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Templates{emails: make(map[string]emailTemplate)}
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".txt")
+
+		text, err := texttemplate.ParseFS(templateFS, file)
+		if err != nil {
+			return nil, fmt.Errorf("mailer: failed to parse %s: %w", file, err)
+		}
+		if text.Lookup("subject") == nil {
+			return nil, fmt.Errorf("mailer: %s does not define a subject", file)
+		}
+
+		html, err := htmltemplate.ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html")
+		if err != nil {
+			return nil, fmt.Errorf("mailer: failed to parse %s.html: %w", name, err)
+		}
+
+		t.emails[name] = emailTemplate{text: text, html: html}
+	}
+
+	return t, nil
+}
+
+// Render builds a message from the named template. The caller fills in the
+// recipients.
+func (t *Templates) Render(name string, data any) (*Message, error) {
+	email, ok := t.emails[name]
+	if !ok {
+		return nil, fmt.Errorf("mailer: unknown template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := email.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := email.text.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err := email.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
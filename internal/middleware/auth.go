@@ -1,36 +1,118 @@
 package middleware
 
 import (
+	"context"
+	"fmt"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/apperror"
 	"github.com/ochko-b/goapp/internal/utils"
 )
 
-func JWTAuth(secret string) fiber.Handler {
+// RevocationChecker reports whether a validly signed token has been revoked.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, claims *utils.Claims) (bool, error)
+}
+
+// APIKeyAuthenticator resolves an API key to the claims of the user it acts
+// as, or to nil claims if the key is unknown, expired or revoked.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*utils.Claims, error)
+}
+
+// APIKeyHeader is an alternative to sending an API key as a Bearer token.
+const APIKeyHeader = "X-API-Key"
+
+// JWTAuth authenticates the request with an access token or, when apiKeys is
+// not nil, an API key sent as a Bearer token or in the X-API-Key header. Both
+// set the same locals, so handlers do not care which one was used. Access
+// tokens issued to OAuth clients are accepted like API keys: they act as the
+// user with the granted scopes as permissions.
+func JWTAuth(keys *utils.KeyRing, revocations RevocationChecker, apiKeys APIKeyAuthenticator) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		authHeader := c.Get("Authorization")
-		if authHeader == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Authorization header required",
-			})
-		}
+		credential := c.Get(APIKeyHeader)
+		if credential == "" {
+			authHeader := c.Get("Authorization")
+			if authHeader == "" {
+				return apperror.Unauthorized("authorization_required", "Authorization header required")
+			}
 
-		tokenParts := strings.Split(authHeader, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid Authorization Header format",
-			})
+			tokenParts := strings.Split(authHeader, " ")
+			if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+				return apperror.Unauthorized("invalid_authorization_header", "Invalid Authorization Header format")
+			}
+			credential = tokenParts[1]
 		}
 
-		claims, err := utils.ValidateToken(tokenParts[1], secret)
-		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid or expired token",
-			})
+		var claims *utils.Claims
+		if apiKeys != nil && (c.Get(APIKeyHeader) != "" || utils.IsAPIKey(credential)) {
+			var err error
+			claims, err = apiKeys.AuthenticateAPIKey(c.Context(), credential)
+			if err != nil {
+				return fmt.Errorf("failed to check API key: %w", err)
+			}
+			if claims == nil {
+				return apperror.Unauthorized("invalid_api_key", "Invalid or expired API key")
+			}
+		} else {
+			var err error
+			claims, err = utils.ValidateToken(credential, keys)
+			if err != nil || (claims.TokenUse != utils.TokenUseAccess && claims.TokenUse != utils.TokenUseOAuth) {
+				return apperror.Unauthorized("invalid_token", "Invalid or expired token")
+			}
+
+			revoked, err := revocations.IsRevoked(c.Context(), claims)
+			if err != nil {
+				return fmt.Errorf("failed to check token revocation: %w", err)
+			}
+			if revoked {
+				return apperror.Unauthorized("token_revoked", "Token has been revoked")
+			}
 		}
+
 		c.Locals("user_id", claims.UserID)
 		c.Locals("email", claims.Email)
+		c.Locals("email_verified", claims.EmailVerified)
+		c.Locals("roles", claims.Roles)
+		c.Locals("claims", claims)
+		if claims.Actor != nil {
+			c.Locals(utils.ImpersonatorKey, claims.Actor.UserID)
+		}
+		if claims.Locale != "" {
+			c.Locals(utils.LocaleKey, claims.Locale)
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireVerifiedEmail rejects users whose email is not verified. It must run
+// after JWTAuth. When enforce is false it lets every request through, so
+// routes can be wired the same way regardless of the verification mode.
+func RequireVerifiedEmail(enforce bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !enforce {
+			return c.Next()
+		}
+
+		if verified, _ := c.Locals("email_verified").(bool); !verified {
+			return apperror.Forbidden("email_not_verified", "Email address has not been verified")
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireUserToken rejects requests authenticated with an API key or an OAuth
+// client token. It guards routes that manage credentials, so a leaked key or
+// a third-party app cannot mint new ones or turn off the second factor. It
+// must run after JWTAuth.
+func RequireUserToken() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if claims, ok := c.Locals("claims").(*utils.Claims); !ok || claims.TokenUse != utils.TokenUseAccess {
+			return apperror.Forbidden("user_token_required", "This route requires a user access token")
+		}
 
 		return c.Next()
 	}
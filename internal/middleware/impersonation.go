@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+// ImpersonationAuditor records requests made with an impersonation token.
+type ImpersonationAuditor interface {
+	RecordImpersonatedRequest(ctx context.Context, claims *utils.Claims, method, path string, status int, clientIP string)
+}
+
+// AuditImpersonation records every request made while a support agent
+// impersonates a user, once the response status is known. Errors are handed
+// to the app's error handler here, so the recorded status is the one the
+// client gets. It must run after JWTAuth.
+func AuditImpersonation(auditor ImpersonationAuditor) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("claims").(*utils.Claims)
+		if !ok || claims.Actor == nil {
+			return c.Next()
+		}
+
+		if err := c.Next(); err != nil {
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+		auditor.RecordImpersonatedRequest(c.Context(), claims, c.Method(), c.Path(), c.Response().StatusCode(), c.IP())
+
+		return nil
+	}
+}
+
+// RejectImpersonation blocks a route while a support agent impersonates the
+// user. It guards changes to credentials and sign-in methods, which only the
+// user themselves may make, and admin actions on other users, which the
+// agent makes as themselves. It must run after JWTAuth.
+func RejectImpersonation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if claims, ok := c.Locals("claims").(*utils.Claims); ok && claims.Actor != nil {
+			return apperror.Forbidden("impersonation_not_allowed", "This action is not allowed while impersonating a user")
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+func TestRejectImpersonation(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			return c.Status(fiber.StatusForbidden).SendString(apperror.From(err).Code)
+		},
+	})
+	withClaims := func(claims *utils.Claims) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Locals("claims", claims)
+			return c.Next()
+		}
+	}
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) }
+
+	admin := &utils.Claims{UserID: "admin", Permissions: []string{"users:write", "roles:write"}}
+	impersonated := &utils.Claims{
+		UserID:      "customer",
+		Permissions: []string{"users:write"},
+		Actor:       &utils.Actor{UserID: "agent"},
+	}
+	app.Post("/own", withClaims(admin), RejectImpersonation(), ok)
+	app.Post("/impersonated", withClaims(impersonated), RejectImpersonation(), ok)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/own", fiber.StatusNoContent, ""},
+		{"/impersonated", fiber.StatusForbidden, "impersonation_not_allowed"},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, tt.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != tt.status || string(body) != tt.body {
+			t.Errorf("%s: got %d %q, want %d %q", tt.path, resp.StatusCode, body, tt.status, tt.body)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/i18n"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+// Locale picks the language of messages from the Accept-Language header. The
+// first supported locale wins, and clients that send none get the default.
+// JWTAuth replaces it with the language the user saved in their profile.
+func Locale() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Vary(fiber.HeaderAcceptLanguage)
+		c.Locals(utils.LocaleKey, c.AcceptsLanguages(i18n.Locales()...))
+		return c.Next()
+	}
+}
@@ -3,10 +3,22 @@ package middleware
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/ochko-b/goapp/internal/utils"
 )
 
+// Logger logs one line per request, with the request ID that error responses
+// carry. Requests made while impersonating a user end with the support
+// agent's user ID.
 func Logger() fiber.Handler {
 	return logger.New(logger.Config{
-		Format: "[${time}] ${status} - ${method} ${path} - ${latency}\n",
+		Format: "[${time}] ${locals:" + utils.RequestIDKey + "} ${status} - ${method} ${path} - ${latency}${impersonator}\n",
+		CustomTags: map[string]logger.LogFunc{
+			"impersonator": func(output logger.Buffer, c *fiber.Ctx, _ *logger.Data, _ string) (int, error) {
+				if actor, ok := c.Locals(utils.ImpersonatorKey).(string); ok {
+					return output.WriteString(" - impersonated by " + actor)
+				}
+				return 0, nil
+			},
+		},
 	})
 }
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+// RequirePermission rejects requests whose token lacks any of the given
+// permissions. It must run after JWTAuth.
+func RequirePermission(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("claims").(*utils.Claims)
+		if !ok {
+			return apperror.Unauthorized("authentication_required", "Authentication required")
+		}
+
+		for _, permission := range permissions {
+			if !claims.HasPermission(permission) {
+				return apperror.Forbidden("insufficient_permissions", "Insufficient permissions")
+			}
+		}
+
+		return c.Next()
+	}
+}
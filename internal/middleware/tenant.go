@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+// TenantHeader selects the organization a request acts on.
+const TenantHeader = "X-Organization-ID"
+
+// MembershipResolver returns the user's role in an organization, or an empty
+// role if they are not a member.
+type MembershipResolver interface {
+	MembershipRole(ctx context.Context, orgID, userID string) (string, error)
+}
+
+// Tenant resolves the active organization from the X-Organization-ID header,
+// or from the org_id claim of a pinned credential, and checks that the
+// authenticated user belongs to it. It must run after JWTAuth, and sets the
+// org_id and org_role locals.
+func Tenant(memberships MembershipResolver) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		orgID := c.Get(TenantHeader)
+
+		// Credentials pinned to an organization may only act on that one
+		if claims, ok := c.Locals("claims").(*utils.Claims); ok && claims.OrgID != "" {
+			if orgID == "" {
+				orgID = claims.OrgID
+			} else if orgID != claims.OrgID {
+				return apperror.Forbidden("credential_wrong_organization", "Credential is not valid for this organization")
+			}
+		}
+
+		if orgID == "" {
+			return apperror.Validation("organization_header_required", TenantHeader+" header required")
+		}
+		if _, err := uuid.Parse(orgID); err != nil {
+			return apperror.Validation("invalid_organization_id", "Invalid organization ID")
+		}
+
+		userID, _ := c.Locals("user_id").(string)
+		role, err := memberships.MembershipRole(c.Context(), orgID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to resolve organization: %w", err)
+		}
+		if role == "" {
+			return apperror.Forbidden("not_organization_member", "Not a member of this organization")
+		}
+
+		c.Locals("org_id", orgID)
+		c.Locals("org_role", role)
+
+		return c.Next()
+	}
+}
+
+// RequireOrgRole rejects members whose role in the active organization is
+// below min. It must run after Tenant.
+func RequireOrgRole(min string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals("org_role").(string)
+		if !models.OrgRoleAtLeast(role, min) {
+			return apperror.Forbidden("insufficient_org_role", "Insufficient organization role")
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" normalize:"trim" validate:"required,max=100"`
+	// Permissions the key may use, e.g. users:read
+	Scopes []string `json:"scopes" validate:"dive,required"`
+	// Pins the key to one organization the user administers
+	OrganizationID string     `json:"organization_id" validate:"omitempty,uuid"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+}
+
+type APIKeyResponse struct {
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	Prefix         string   `json:"prefix"`
+	OrganizationID string   `json:"organization_id,omitempty"`
+	Scopes         []string `json:"scopes"`
+	ExpiresAt      string   `json:"expires_at,omitempty"`
+	LastUsedAt     string   `json:"last_used_at,omitempty"`
+	RevokedAt      string   `json:"revoked_at,omitempty"`
+	CreatedAt      string   `json:"created_at"`
+}
+
+// CreatedAPIKeyResponse is the only response that includes the full key.
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
@@ -1,18 +1,109 @@
 package models
 
 type RegisterRequest struct {
-	Email     string `json:"email" validate:"required,email"`
-	Password  string `json:"password" validate:"required,min=8"`
-	FirstName string `json:"first_name" validate:"required,min=2"`
-	LastName  string `json:"last_name" validate:"required,min=2"`
+	Email     string `json:"email" normalize:"email" validate:"required,normalized_email"`
+	Password  string `json:"password" validate:"required,max=1024"`
+	FirstName string `json:"first_name" normalize:"name" validate:"required,min=2,max=100,human_name"`
+	LastName  string `json:"last_name" normalize:"name" validate:"required,min=2,max=100,human_name"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" validate:"required,min=2"`
+	Email    string `json:"email" normalize:"email" validate:"required,min=2"`
 	Password string `json:"password" validate:"required,min=2"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" normalize:"email" validate:"required,normalized_email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,max=1024"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" normalize:"email" validate:"required,normalized_email"`
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// AuthResponse carries no tokens when the user may not log in yet, e.g.
+// right after registering while email verification is required, or when a
+// second factor is needed. In the latter case MFAToken must be exchanged at
+// /auth/mfa/verify or /auth/mfa/passkey/finish, depending on MFAMethods.
 type AuthResponse struct {
-	User  UserResponse `json:"user"`
-	Token string       `json:"token"`
+	User UserResponse `json:"user"`
+	*TokenPair
+	MFARequired bool     `json:"mfa_required,omitempty"`
+	MFAToken    string   `json:"mfa_token,omitempty"`
+	MFAMethods  []string `json:"mfa_methods,omitempty"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" normalize:"email" validate:"required,normalized_email"`
+}
+
+type ConsumeMagicLinkRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type UnlockAccountRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type TOTPSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+	QRCode          string `json:"qr_code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// PasswordViolation is one password policy rule a new password failed.
+// Param is the rule's limit, if it has one, for localized messages.
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Param   string `json:"-"`
+}
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// ImpersonationResponse carries an access token that acts as User. There is
+// no refresh token; a new one is requested once it expires.
+type ImpersonationResponse struct {
+	User        UserResponse `json:"user"`
+	AccessToken string       `json:"access_token"`
+	TokenType   string       `json:"token_type"`
+	ExpiresIn   int64        `json:"expires_in"`
 }
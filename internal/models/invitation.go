@@ -0,0 +1,52 @@
+package models
+
+// CreateInvitationRequest invites an email address, optionally with a role
+// the new user gets on accepting.
+type CreateInvitationRequest struct {
+	Email string `json:"email" normalize:"email" validate:"required,normalized_email"`
+	Role  string `json:"role" validate:"max=50"`
+}
+
+type InvitationTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// AcceptInvitationRequest accepts an invitation by choosing a password.
+type AcceptInvitationRequest struct {
+	Token     string `json:"token" validate:"required"`
+	Password  string `json:"password" validate:"required,max=1024"`
+	FirstName string `json:"first_name" normalize:"name" validate:"required,min=2,max=100,human_name"`
+	LastName  string `json:"last_name" normalize:"name" validate:"required,min=2,max=100,human_name"`
+}
+
+// AcceptInvitationOAuthRequest accepts an invitation by signing in with a
+// provider. Code and State are what the provider redirected back with, after
+// a sign-in started at /auth/oauth/:provider/authorize.
+type AcceptInvitationOAuthRequest struct {
+	Token string `json:"token" validate:"required"`
+	OAuthCallbackRequest
+}
+
+// InvitationResponse is an invitation as admins see it. Status is pending,
+// expired, accepted or revoked.
+type InvitationResponse struct {
+	ID             string `json:"id"`
+	Email          string `json:"email"`
+	Role           string `json:"role,omitempty"`
+	Status         string `json:"status"`
+	InvitedBy      string `json:"invited_by,omitempty"`
+	AcceptedUserID string `json:"accepted_user_id,omitempty"`
+	SentCount      int32  `json:"sent_count"`
+	ExpiresAt      string `json:"expires_at"`
+	LastSentAt     string `json:"last_sent_at"`
+	AcceptedAt     string `json:"accepted_at,omitempty"`
+	RevokedAt      string `json:"revoked_at,omitempty"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// InvitationDetailsResponse is what the invitee sees before accepting.
+type InvitationDetailsResponse struct {
+	Email     string `json:"email"`
+	Role      string `json:"role,omitempty"`
+	ExpiresAt string `json:"expires_at"`
+}
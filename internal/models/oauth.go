@@ -0,0 +1,20 @@
+package models
+
+// OAuthCallbackRequest carries the authorization response the provider
+// redirected the browser back with.
+type OAuthCallbackRequest struct {
+	Code  string `json:"code" validate:"required"`
+	State string `json:"state" validate:"required"`
+}
+
+type OAuthAuthorizationResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+type IdentityResponse struct {
+	ID          string `json:"id"`
+	Provider    string `json:"provider"`
+	Email       string `json:"email,omitempty"`
+	LastLoginAt string `json:"last_login_at,omitempty"`
+	CreatedAt   string `json:"created_at"`
+}
@@ -0,0 +1,124 @@
+package models
+
+type CreateOAuthClientRequest struct {
+	Name string `json:"name" normalize:"trim" validate:"required,max=100"`
+	// Exact URIs the authorization response may be sent to
+	RedirectURIs []string `json:"redirect_uris" validate:"max=10,dive,required,max=2000"`
+	GrantTypes   []string `json:"grant_types" validate:"required,min=1,dive,oneof=authorization_code refresh_token client_credentials"`
+	// Permissions the client may ask for, e.g. users:read
+	Scopes []string `json:"scopes" validate:"dive,required"`
+	// Public clients (mobile and single-page apps) get no secret
+	Public bool `json:"public"`
+}
+
+type OAuthClientResponse struct {
+	ClientID     string   `json:"client_id"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	GrantTypes   []string `json:"grant_types"`
+	Scopes       []string `json:"scopes"`
+	Public       bool     `json:"public"`
+	CreatedAt    string   `json:"created_at"`
+}
+
+// CreatedOAuthClientResponse is the only response that includes the secret.
+type CreatedOAuthClientResponse struct {
+	OAuthClientResponse
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// AuthorizeRequest is an authorization request (RFC 6749 section 4.1.1) with
+// the PKCE parameters of RFC 7636.
+type AuthorizeRequest struct {
+	ResponseType        string `json:"response_type" query:"response_type"`
+	ClientID            string `json:"client_id" query:"client_id"`
+	RedirectURI         string `json:"redirect_uri" query:"redirect_uri"`
+	Scope               string `json:"scope" query:"scope"`
+	State               string `json:"state" query:"state"`
+	CodeChallenge       string `json:"code_challenge" query:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" query:"code_challenge_method"`
+}
+
+// AuthorizeDecisionRequest is the user's answer on the consent screen.
+type AuthorizeDecisionRequest struct {
+	AuthorizeRequest
+	Approve bool `json:"approve"`
+}
+
+type OAuthScope struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// OAuthConsentResponse is what the consent screen shows: the client and the
+// scopes it would get.
+type OAuthConsentResponse struct {
+	ClientID   string       `json:"client_id"`
+	ClientName string       `json:"client_name"`
+	Scopes     []OAuthScope `json:"scopes"`
+}
+
+// OAuthRedirectResponse tells the consent screen where to send the browser.
+type OAuthRedirectResponse struct {
+	RedirectURI string `json:"redirect_uri"`
+}
+
+// TokenRequest covers the token requests of every supported grant type.
+// Client credentials may come in the body or in a Basic Authorization header.
+type TokenRequest struct {
+	GrantType    string `json:"grant_type" form:"grant_type"`
+	Code         string `json:"code" form:"code"`
+	RedirectURI  string `json:"redirect_uri" form:"redirect_uri"`
+	CodeVerifier string `json:"code_verifier" form:"code_verifier"`
+	RefreshToken string `json:"refresh_token" form:"refresh_token"`
+	Scope        string `json:"scope" form:"scope"`
+	ClientID     string `json:"client_id" form:"client_id"`
+	ClientSecret string `json:"client_secret" form:"client_secret"`
+}
+
+// OAuthTokenResponse is the token response of RFC 6749 section 5.1.
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// TokenActionRequest is an introspection (RFC 7662) or revocation (RFC 7009)
+// request, authenticated like a token request.
+type TokenActionRequest struct {
+	Token         string `json:"token" form:"token"`
+	TokenTypeHint string `json:"token_type_hint" form:"token_type_hint"`
+	ClientID      string `json:"client_id" form:"client_id"`
+	ClientSecret  string `json:"client_secret" form:"client_secret"`
+}
+
+// IntrospectionResponse is the response of RFC 7662 section 2.2. Inactive
+// tokens only carry active=false.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	JTI       string `json:"jti,omitempty"`
+}
+
+// AuthorizationServerMetadata is the discovery document of RFC 8414.
+type AuthorizationServerMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	AuthorizationResponseIssSupported bool     `json:"authorization_response_iss_parameter_supported"`
+}
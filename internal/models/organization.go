@@ -0,0 +1,56 @@
+package models
+
+// Organization roles, from most to least privileged
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+var orgRoleRank = map[string]int{
+	OrgRoleOwner:  3,
+	OrgRoleAdmin:  2,
+	OrgRoleMember: 1,
+}
+
+// OrgRoleAtLeast reports whether role grants at least the rights of min.
+func OrgRoleAtLeast(role, min string) bool {
+	return orgRoleRank[role] >= orgRoleRank[min] && orgRoleRank[role] > 0
+}
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" normalize:"trim" validate:"required,min=2,max=255"`
+	// Derived from the name when empty
+	Slug string `json:"slug" normalize:"trim,lower" validate:"omitempty,min=2,max=100"`
+}
+
+type UpdateOrganizationRequest struct {
+	Name string `json:"name" normalize:"trim" validate:"required,min=2,max=255"`
+}
+
+type AddMemberRequest struct {
+	Email string `json:"email" normalize:"email" validate:"required,normalized_email"`
+	Role  string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+type OrganizationResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Slug      string `json:"slug"`
+	Role      string `json:"role,omitempty"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type MemberResponse struct {
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Role      string `json:"role"`
+	JoinedAt  string `json:"joined_at"`
+}
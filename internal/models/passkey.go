@@ -0,0 +1,36 @@
+package models
+
+import "github.com/ochko-b/goapp/internal/webauthn"
+
+// PasskeyRegisterRequest finishes a registration. Credential is the
+// PublicKeyCredential from navigator.credentials.create(), serialized as
+// by its toJSON() method.
+type PasskeyRegisterRequest struct {
+	Name       string                        `json:"name" validate:"max=100"`
+	Credential webauthn.RegistrationResponse `json:"credential"`
+}
+
+// PasskeyLoginRequest finishes a passwordless login with the
+// PublicKeyCredential from navigator.credentials.get().
+type PasskeyLoginRequest struct {
+	Credential webauthn.AssertionResponse `json:"credential"`
+}
+
+type PasskeyMFABeginRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+}
+
+// PasskeyMFARequest finishes a login that returned an MFA token, with a
+// passkey as the second factor.
+type PasskeyMFARequest struct {
+	MFAToken   string                     `json:"mfa_token" validate:"required"`
+	Credential webauthn.AssertionResponse `json:"credential"`
+}
+
+type PasskeyResponse struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Transports []string `json:"transports"`
+	CreatedAt  string   `json:"created_at"`
+	LastUsedAt string   `json:"last_used_at,omitempty"`
+}
@@ -0,0 +1,10 @@
+package models
+
+type AssignRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+type RoleResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
@@ -1,15 +1,36 @@
 package models
 
 type UpdateProfileRequest struct {
-	FirstName string `json:"first_name" validate:"required,min=2"`
-	LastName  string `json:"last_name" validate:"required,min=2"`
+	FirstName string `json:"first_name" normalize:"name" validate:"required,min=2,max=100,human_name"`
+	LastName  string `json:"last_name" normalize:"name" validate:"required,min=2,max=100,human_name"`
+	// Locale is the language of messages, e.g. "de". Empty follows the
+	// client's Accept-Language header.
+	Locale string `json:"locale" validate:"omitempty,locale"`
 }
 
 type UserResponse struct {
-	ID        string `json:"id"`
-	Email     string `json:"email"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	Locale        string `json:"locale,omitempty"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,max=1024"`
+}
+
+// ChangeEmailRequest asks for the password again, so a hijacked session
+// alone cannot move the account to another mailbox.
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" normalize:"email" validate:"required,normalized_email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" validate:"required"`
 }
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"slices"
+
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+// Rules over several fields of a request, which a tag on one field cannot
+// express. They report fields by their JSON names.
+func init() {
+	err := validator.RegisterRule(validator.Rule{
+		Tag: "required_for_grant",
+		Messages: map[string]string{
+			"en": "{0} is required for the {1} grant",
+			"de": "{0} ist für den Grant {1} erforderlich",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	err = validator.RegisterRule(validator.Rule{
+		Tag: "public_client_grant",
+		Messages: map[string]string{
+			"en": "{0} cannot include {1} for a public client",
+			"de": "{0} darf für einen öffentlichen Client {1} nicht enthalten",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	validator.RegisterStructRule(validateChangePassword, ChangePasswordRequest{})
+	validator.RegisterStructRule(validateCreateOAuthClient, CreateOAuthClientRequest{})
+}
+
+// validateChangePassword rejects a new password that is the current one,
+// which would leave a leaked password in use.
+func validateChangePassword(sl validator.StructLevel) {
+	req := sl.Current().Interface().(ChangePasswordRequest)
+	if req.NewPassword != "" && req.NewPassword == req.CurrentPassword {
+		sl.ReportError(req.NewPassword, "new_password", "NewPassword", "nefield", "current_password")
+	}
+}
+
+// validateCreateOAuthClient checks the grants against the rest of the
+// client, like OAuthServerService.CreateClient does.
+func validateCreateOAuthClient(sl validator.StructLevel) {
+	req := sl.Current().Interface().(CreateOAuthClientRequest)
+	if slices.Contains(req.GrantTypes, "authorization_code") && len(req.RedirectURIs) == 0 {
+		sl.ReportError(req.RedirectURIs, "redirect_uris", "RedirectURIs", "required_for_grant", "authorization_code")
+	}
+	if req.Public && slices.Contains(req.GrantTypes, "client_credentials") {
+		sl.ReportError(req.GrantTypes, "grant_types", "GrantTypes", "public_client_grant", "client_credentials")
+	}
+}
@@ -0,0 +1,225 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+// mockProvider is a minimal OpenID Connect provider for tests. It serves
+// discovery, JWKS, an authorization endpoint that approves every request
+// straight away, and a token endpoint that enforces PKCE and client
+// authentication.
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+
+	clientID     string
+	clientSecret string
+
+	mu      sync.Mutex
+	keys    []*mockKey
+	codes   map[string]mockGrant
+	subject string
+	email   string
+	// mutate, when set, edits the ID token claims before they are signed
+	mutate func(claims jwt.MapClaims)
+}
+
+type mockKey struct {
+	kid     string
+	private *rsa.PrivateKey
+}
+
+type mockGrant struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+
+	m := &mockProvider{
+		t:            t,
+		clientID:     "test-client",
+		clientSecret: "test-secret",
+		codes:        map[string]mockGrant{},
+		subject:      "user-123",
+		email:        "jane@example.com",
+	}
+	m.rotateKey("key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("GET /jwks", m.jwks)
+	mux.HandleFunc("GET /authorize", m.authorize)
+	mux.HandleFunc("POST /token", m.token)
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockProvider) issuer() string {
+	return m.server.URL
+}
+
+// rotateKey publishes a new signing key and signs with it from now on.
+func (m *mockProvider) rotateKey(kid string) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		m.t.Fatalf("failed to generate key: %v", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = append(m.keys, &mockKey{kid: kid, private: private})
+}
+
+func (m *mockProvider) signingKey() *mockKey {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.keys[len(m.keys)-1]
+}
+
+func (m *mockProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                m.issuer(),
+		"authorization_endpoint":                m.issuer() + "/authorize",
+		"token_endpoint":                        m.issuer() + "/token",
+		"jwks_uri":                              m.issuer() + "/jwks",
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (m *mockProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	set := utils.JWKSet{}
+	for _, key := range m.keys {
+		signingKey, err := utils.NewSigningKey(key.kid, &key.private.PublicKey)
+		if err != nil {
+			m.t.Errorf("failed to wrap key: %v", err)
+			continue
+		}
+		jwk, _ := signingKey.JWK()
+		set.Keys = append(set.Keys, jwk)
+	}
+	writeJSON(w, http.StatusOK, set)
+}
+
+func (m *mockProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" || q.Get("client_id") != m.clientID || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code, err := NewVerifier()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	m.mu.Lock()
+	m.codes[code] = mockGrant{
+		clientID:    q.Get("client_id"),
+		redirectURI: q.Get("redirect_uri"),
+		challenge:   q.Get("code_challenge"),
+		nonce:       q.Get("nonce"),
+	}
+	m.mu.Unlock()
+
+	redirect, _ := url.Parse(q.Get("redirect_uri"))
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (m *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	}
+	if !ok || id != m.clientID || secret != m.clientSecret {
+		writeJSON(w, http.StatusUnauthorized, Error{Code: "invalid_client"})
+		return
+	}
+
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, Error{Code: "unsupported_grant_type"})
+		return
+	}
+
+	m.mu.Lock()
+	grant, found := m.codes[r.PostFormValue("code")]
+	delete(m.codes, r.PostFormValue("code"))
+	m.mu.Unlock()
+
+	switch {
+	case !found, grant.redirectURI != r.PostFormValue("redirect_uri"):
+		writeJSON(w, http.StatusBadRequest, Error{Code: "invalid_grant"})
+		return
+	case S256Challenge(r.PostFormValue("code_verifier")) != grant.challenge:
+		writeJSON(w, http.StatusBadRequest, Error{Code: "invalid_grant", Description: "PKCE verification failed"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            m.issuer(),
+		"sub":            m.subject,
+		"aud":            grant.clientID,
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          grant.nonce,
+		"email":          m.email,
+		"email_verified": true,
+		"given_name":     "Jane",
+		"family_name":    "Doe",
+	}
+	if m.mutate != nil {
+		m.mutate(claims)
+	}
+
+	writeJSON(w, http.StatusOK, Token{
+		AccessToken: "mock-access-token",
+		TokenType:   "Bearer",
+		ExpiresIn:   3600,
+		IDToken:     m.sign(claims),
+	})
+}
+
+func (m *mockProvider) sign(claims jwt.MapClaims) string {
+	key := m.signingKey()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = key.kid
+	signed, err := token.SignedString(key.private)
+	if err != nil {
+		m.t.Fatalf("failed to sign ID token: %v", err)
+	}
+	return signed
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
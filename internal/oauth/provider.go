@@ -0,0 +1,277 @@
+// Package oauth signs users in with external OpenID Connect providers using
+// the authorization code flow with PKCE. Provider endpoints and signing keys
+// are found through OpenID Connect Discovery, and ID tokens are verified
+// locally against the provider's published keys.
+//
+// Only OpenID Connect is supported; plain OAuth 2.0 providers without ID
+// tokens, such as GitHub's user login, need their own adapter.
+package oauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ochko-b/goapp/internal/config"
+)
+
+var (
+	ErrInvalidIDToken = errors.New("oauth: invalid ID token")
+	ErrMissingIDToken = errors.New("oauth: token response has no ID token")
+)
+
+// Clock skew tolerated when checking exp and iat
+const idTokenLeeway = time.Minute
+
+// idTokenAlgs are the signature algorithms accepted on ID tokens. Symmetric
+// algorithms are left out on purpose: they would be keyed with the client
+// secret, which is not how any provider we support signs.
+var idTokenAlgs = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Error codes of RFC 6749 sections 4.1.2.1 and 5.2
+const (
+	ErrorInvalidRequest          = "invalid_request"
+	ErrorInvalidClient           = "invalid_client"
+	ErrorInvalidGrant            = "invalid_grant"
+	ErrorUnauthorizedClient      = "unauthorized_client"
+	ErrorUnsupportedGrantType    = "unsupported_grant_type"
+	ErrorUnsupportedResponseType = "unsupported_response_type"
+	ErrorInvalidScope            = "invalid_scope"
+	ErrorAccessDenied            = "access_denied"
+)
+
+// Error is an OAuth 2.0 error response (RFC 6749 section 5.2). The provider
+// gets it from token endpoints; the authorization server sends it.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return "oauth: " + e.Code
+	}
+	return fmt.Sprintf("oauth: %s: %s", e.Code, e.Description)
+}
+
+// Metadata is the part of the discovery document we use.
+type Metadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	IDToken      string `json:"id_token"`
+}
+
+// IDTokenClaims are the verified claims of an ID token. Subject is the stable
+// identifier of the user at the provider; Email may change over time.
+type IDTokenClaims struct {
+	Email           string `json:"email"`
+	EmailVerified   bool   `json:"email_verified"`
+	Name            string `json:"name"`
+	GivenName       string `json:"given_name"`
+	FamilyName      string `json:"family_name"`
+	Nonce           string `json:"nonce"`
+	AuthorizedParty string `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// Provider is one configured OpenID Connect issuer. Discovery happens on
+// first use and is retried until it succeeds, so a provider that is down at
+// startup does not keep the server from booting.
+type Provider struct {
+	cfg    config.OAuthProviderConfig
+	client *http.Client
+
+	mu   sync.Mutex
+	meta *Metadata
+	keys *remoteKeySet
+}
+
+func NewProvider(cfg config.OAuthProviderConfig, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Provider{
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// AuthCodeURL builds the URL to send the user to. state and nonce must be
+// unguessable and kept until the callback, along with the PKCE verifier.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {S256Challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Authenticate exchanges the authorization code and verifies the ID token
+// that comes back.
+func (p *Provider) Authenticate(ctx context.Context, code, verifier, nonce string) (*IDTokenClaims, error) {
+	token, err := p.Exchange(ctx, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, ErrMissingIDToken
+	}
+	return p.VerifyIDToken(ctx, token.IDToken, nonce)
+}
+
+// Exchange redeems an authorization code at the token endpoint.
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*Token, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {p.cfg.ClientID},
+	}
+
+	// client_secret_basic is the default when the provider does not say
+	useBasic := p.cfg.ClientSecret != "" &&
+		(len(meta.TokenEndpointAuthMethodsSupported) == 0 || slices.Contains(meta.TokenEndpointAuthMethodsSupported, "client_secret_basic"))
+	if p.cfg.ClientSecret != "" && !useBasic {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasic {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oauth: token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		oauthErr := &Error{}
+		if err := json.NewDecoder(resp.Body).Decode(oauthErr); err != nil || oauthErr.Code == "" {
+			return nil, fmt.Errorf("oauth: token endpoint returned %s", resp.Status)
+		}
+		return nil, oauthErr
+	}
+
+	var token Token
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("oauth: invalid token response: %w", err)
+	}
+	return &token, nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience, lifetime and nonce
+// of an ID token (OpenID Connect Core section 3.1.3.7).
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*IDTokenClaims, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &IDTokenClaims{}
+	_, err = jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.keys.key(ctx, kid)
+	},
+		jwt.WithValidMethods(idTokenAlgs),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(idTokenLeeway),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if (len(claims.Audience) > 1 || claims.AuthorizedParty != "") && claims.AuthorizedParty != p.cfg.ClientID {
+		return nil, fmt.Errorf("%w: token was issued to another client", ErrInvalidIDToken)
+	}
+
+	return claims, nil
+}
+
+func (p *Provider) metadata(ctx context.Context) (*Metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	var meta Metadata
+	discoveryURL := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, p.client, discoveryURL, &meta); err != nil {
+		return nil, fmt.Errorf("oauth: discovery for %s failed: %w", p.cfg.Name, err)
+	}
+
+	// The document must describe the issuer we asked about, or tokens from
+	// another issuer could be accepted (OpenID Connect Discovery section 4.3)
+	if meta.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("oauth: discovery for %s returned issuer %q", p.cfg.Name, meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("oauth: discovery for %s is missing endpoints", p.cfg.Name)
+	}
+
+	p.meta = &meta
+	p.keys = newRemoteKeySet(meta.JWKSURI, p.client)
+	return p.meta, nil
+}
@@ -0,0 +1,236 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ochko-b/goapp/internal/config"
+)
+
+const testRedirectURL = "https://app.example.com/oauth/callback/mock"
+
+func newTestProvider(m *mockProvider) *Provider {
+	return NewProvider(config.OAuthProviderConfig{
+		Name:         "mock",
+		Issuer:       m.issuer(),
+		ClientID:     m.clientID,
+		ClientSecret: m.clientSecret,
+		Scopes:       []string{"openid", "email", "profile"},
+		RedirectURL:  testRedirectURL,
+	}, m.server.Client())
+}
+
+// login runs the browser leg of the flow: it opens the authorization URL and
+// returns the code and state the provider redirects back with.
+func login(t *testing.T, p *Provider, state, nonce, verifier string) (code, returnedState string) {
+	t.Helper()
+
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %s", resp.Status)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect: %v", err)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func newFlowValues(t *testing.T) (state, nonce, verifier string) {
+	t.Helper()
+
+	values := make([]string, 3)
+	for i := range values {
+		v, err := NewVerifier()
+		if err != nil {
+			t.Fatalf("NewVerifier: %v", err)
+		}
+		values[i] = v
+	}
+	return values[0], values[1], values[2]
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	m := newMockProvider(t)
+	p := newTestProvider(m)
+	state, nonce, verifier := newFlowValues(t)
+
+	code, returnedState := login(t, p, state, nonce, verifier)
+	if returnedState != state {
+		t.Fatalf("state = %q, want %q", returnedState, state)
+	}
+
+	claims, err := p.Authenticate(context.Background(), code, verifier, nonce)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	if claims.Subject != m.subject {
+		t.Errorf("subject = %q, want %q", claims.Subject, m.subject)
+	}
+	if claims.Email != m.email || !claims.EmailVerified {
+		t.Errorf("email = %q (verified %v), want %q verified", claims.Email, claims.EmailVerified, m.email)
+	}
+	if claims.GivenName != "Jane" || claims.FamilyName != "Doe" {
+		t.Errorf("name = %q %q, want Jane Doe", claims.GivenName, claims.FamilyName)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	m := newMockProvider(t)
+	p := newTestProvider(m)
+	state, nonce, verifier := newFlowValues(t)
+
+	code, _ := login(t, p, state, nonce, verifier)
+
+	_, err := p.Authenticate(context.Background(), code, verifier+"x", nonce)
+
+	var oauthErr *Error
+	if !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_grant" {
+		t.Fatalf("err = %v, want invalid_grant", err)
+	}
+}
+
+func TestExchangeRejectsReusedCode(t *testing.T) {
+	m := newMockProvider(t)
+	p := newTestProvider(m)
+	state, nonce, verifier := newFlowValues(t)
+
+	code, _ := login(t, p, state, nonce, verifier)
+	if _, err := p.Authenticate(context.Background(), code, verifier, nonce); err != nil {
+		t.Fatalf("first Authenticate: %v", err)
+	}
+
+	var oauthErr *Error
+	if _, err := p.Authenticate(context.Background(), code, verifier, nonce); !errors.As(err, &oauthErr) {
+		t.Fatalf("err = %v, want an OAuth error", err)
+	}
+}
+
+func TestVerifyIDTokenRejectsNonceMismatch(t *testing.T) {
+	m := newMockProvider(t)
+	p := newTestProvider(m)
+	state, nonce, verifier := newFlowValues(t)
+
+	code, _ := login(t, p, state, nonce, verifier)
+
+	_, err := p.Authenticate(context.Background(), code, verifier, "another-nonce")
+	if !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("err = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestVerifyIDTokenRejectsBadClaims(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(jwt.MapClaims)
+	}{
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "someone-else" }},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"missing expiry", func(c jwt.MapClaims) { delete(c, "exp") }},
+		{"issued in the future", func(c jwt.MapClaims) { c["iat"] = time.Now().Add(time.Hour).Unix() }},
+		{"missing subject", func(c jwt.MapClaims) { delete(c, "sub") }},
+		{"other authorized party", func(c jwt.MapClaims) {
+			c["aud"] = []string{c["aud"].(string), "other-client"}
+			c["azp"] = "other-client"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockProvider(t)
+			m.mutate = tt.mutate
+			p := newTestProvider(m)
+			state, nonce, verifier := newFlowValues(t)
+
+			code, _ := login(t, p, state, nonce, verifier)
+
+			_, err := p.Authenticate(context.Background(), code, verifier, nonce)
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Fatalf("err = %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRejectsSymmetricSignature(t *testing.T) {
+	m := newMockProvider(t)
+	p := newTestProvider(m)
+
+	// A token "signed" with the client secret must not be accepted, even
+	// though the secret is known to both sides.
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":   m.issuer(),
+		"sub":   m.subject,
+		"aud":   m.clientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": "n",
+	})
+	token.Header["kid"] = "key-1"
+	raw, err := token.SignedString([]byte(m.clientSecret))
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+
+	if _, err := p.VerifyIDToken(context.Background(), raw, "n"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("err = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestVerifyIDTokenFollowsKeyRotation(t *testing.T) {
+	saved := minKeyRefresh
+	minKeyRefresh = 0
+	t.Cleanup(func() { minKeyRefresh = saved })
+
+	m := newMockProvider(t)
+	p := newTestProvider(m)
+
+	state, nonce, verifier := newFlowValues(t)
+	code, _ := login(t, p, state, nonce, verifier)
+	if _, err := p.Authenticate(context.Background(), code, verifier, nonce); err != nil {
+		t.Fatalf("Authenticate before rotation: %v", err)
+	}
+
+	m.rotateKey("key-2")
+
+	state, nonce, verifier = newFlowValues(t)
+	code, _ = login(t, p, state, nonce, verifier)
+	if _, err := p.Authenticate(context.Background(), code, verifier, nonce); err != nil {
+		t.Fatalf("Authenticate after rotation: %v", err)
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	m := newMockProvider(t)
+	p := NewProvider(config.OAuthProviderConfig{
+		Name:     "mock",
+		Issuer:   m.issuer() + "/",
+		ClientID: m.clientID,
+	}, m.server.Client())
+
+	// The document is fetched from the same URL but names the issuer without
+	// the trailing slash; issuers must match exactly.
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "v"); err == nil {
+		t.Fatal("expected discovery to fail")
+	}
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ochko-b/goapp/internal/apperror"
+)
+
+// Postgres error codes that say something about the request rather than the
+// database. See https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgForeignKeyViolation       = "23503"
+	pgUniqueViolation           = "23505"
+	pgCheckViolation            = "23514"
+	pgInvalidTextRepresentation = "22P02"
+)
+
+// translate turns pgx errors into domain errors. The original error stays
+// wrapped, so errors.Is(err, pgx.ErrNoRows) and errors.As with a
+// *pgconn.PgError keep working for callers that need the detail.
+func translate(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apperror.Wrap(apperror.KindNotFound, "not_found", "resource not found", err)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return apperror.Wrap(apperror.KindConflict, "already_exists", "resource already exists", err)
+		case pgForeignKeyViolation:
+			return apperror.Wrap(apperror.KindConflict, "reference_conflict", "resource is referenced by or refers to another resource", err)
+		case pgCheckViolation, pgInvalidTextRepresentation:
+			return apperror.Wrap(apperror.KindValidation, "invalid_value", "invalid value", err)
+		}
+	}
+	return err
+}
+
+// db wraps a pool or transaction so that every error the generated queries
+// return has been through translate.
+type db struct {
+	conn interface {
+		Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+		Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+		QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	}
+}
+
+func (d db) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	tag, err := d.conn.Exec(ctx, sql, args...)
+	return tag, translate(err)
+}
+
+func (d db) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	rows, err := d.conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, translate(err)
+	}
+	return translatedRows{rows}, nil
+}
+
+func (d db) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return translatedRow{d.conn.QueryRow(ctx, sql, args...)}
+}
+
+type translatedRow struct {
+	pgx.Row
+}
+
+func (r translatedRow) Scan(dest ...any) error {
+	return translate(r.Row.Scan(dest...))
+}
+
+type translatedRows struct {
+	pgx.Rows
+}
+
+func (r translatedRows) Scan(dest ...any) error {
+	return translate(r.Rows.Scan(dest...))
+}
+
+func (r translatedRows) Err() error {
+	return translate(r.Rows.Err())
+}
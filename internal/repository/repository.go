@@ -10,25 +10,27 @@ import (
 
 type Repository struct {
 	*sqlc.Queries
-	db *pgxpool.Pool
+	pool *pgxpool.Pool
 }
 
-func New(db *pgxpool.Pool) *Repository {
+func New(pool *pgxpool.Pool) *Repository {
 	return &Repository{
-		Queries: sqlc.New(db),
-		db:      db,
+		Queries: sqlc.New(db{conn: pool}),
+		pool:    pool,
 	}
 }
 
+// WithTx runs the queries in tx. It does not use Queries.WithTx, which would
+// bypass the error translation.
 func (r *Repository) WithTx(tx pgx.Tx) *Repository {
 	return &Repository{
-		Queries: r.Queries.WithTx(tx),
-		db:      r.db,
+		Queries: sqlc.New(db{conn: tx}),
+		pool:    r.pool,
 	}
 }
 
 func (r *Repository) BeginTx(ctx context.Context) (pgx.Tx, error) {
-	tx, err := r.db.Begin(ctx)
+	tx, err := r.pool.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
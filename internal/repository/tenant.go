@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ochko-b/goapp/generated/sqlc"
+)
+
+// TenantRepository exposes the tenant-scoped queries with the organization
+// and acting user already bound, so callers cannot pass another tenant's ID.
+// It only exists inside Repository.InTenant.
+type TenantRepository struct {
+	queries  *sqlc.Queries
+	TenantID pgtype.UUID
+	UserID   pgtype.UUID
+}
+
+// InTenant runs fn in a transaction with app.tenant_id and app.user_id set,
+// which the row-level security policies check. The settings are local to the
+// transaction, so they never leak to the next user of the pooled connection.
+// tenantID may be invalid for queries that only act on the user's own rows.
+func (r *Repository) InTenant(ctx context.Context, tenantID, userID pgtype.UUID, fn func(*TenantRepository) error) error {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		"SELECT set_config('app.tenant_id', $1, true), set_config('app.user_id', $2, true)",
+		tenantID.String(), userID.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set tenant: %w", err)
+	}
+
+	if err := fn(&TenantRepository{
+		queries:  sqlc.New(db{conn: tx}),
+		TenantID: tenantID,
+		UserID:   userID,
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// CreateOrganization inserts the organization under the current tenant ID, so
+// callers pick the ID up front and open the tenant with it.
+func (r *TenantRepository) CreateOrganization(ctx context.Context, name, slug string) (sqlc.Organization, error) {
+	return r.queries.CreateOrganization(ctx, sqlc.CreateOrganizationParams{
+		ID:   r.TenantID,
+		Name: name,
+		Slug: slug,
+	})
+}
+
+func (r *TenantRepository) GetOrganization(ctx context.Context) (sqlc.Organization, error) {
+	return r.queries.GetOrganization(ctx, r.TenantID)
+}
+
+func (r *TenantRepository) UpdateOrganization(ctx context.Context, name string) (sqlc.Organization, error) {
+	return r.queries.UpdateOrganization(ctx, sqlc.UpdateOrganizationParams{
+		ID:   r.TenantID,
+		Name: name,
+	})
+}
+
+// ListUserOrganizations lists the acting user's memberships across tenants.
+func (r *TenantRepository) ListUserOrganizations(ctx context.Context) ([]sqlc.ListUserOrganizationsRow, error) {
+	return r.queries.ListUserOrganizations(ctx, r.UserID)
+}
+
+func (r *TenantRepository) AddMember(ctx context.Context, userID pgtype.UUID, role string) (sqlc.OrganizationMember, error) {
+	return r.queries.AddOrganizationMember(ctx, sqlc.AddOrganizationMemberParams{
+		OrganizationID: r.TenantID,
+		UserID:         userID,
+		Role:           role,
+	})
+}
+
+func (r *TenantRepository) GetMember(ctx context.Context, userID pgtype.UUID) (sqlc.OrganizationMember, error) {
+	return r.queries.GetOrganizationMember(ctx, sqlc.GetOrganizationMemberParams{
+		OrganizationID: r.TenantID,
+		UserID:         userID,
+	})
+}
+
+func (r *TenantRepository) ListMembers(ctx context.Context) ([]sqlc.ListOrganizationMembersRow, error) {
+	return r.queries.ListOrganizationMembers(ctx, r.TenantID)
+}
+
+func (r *TenantRepository) UpdateMemberRole(ctx context.Context, userID pgtype.UUID, role string) (sqlc.OrganizationMember, error) {
+	return r.queries.UpdateOrganizationMemberRole(ctx, sqlc.UpdateOrganizationMemberRoleParams{
+		OrganizationID: r.TenantID,
+		UserID:         userID,
+		Role:           role,
+	})
+}
+
+func (r *TenantRepository) RemoveMember(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	return r.queries.RemoveOrganizationMember(ctx, sqlc.RemoveOrganizationMemberParams{
+		OrganizationID: r.TenantID,
+		UserID:         userID,
+	})
+}
+
+func (r *TenantRepository) CountOwners(ctx context.Context) (int64, error) {
+	return r.queries.CountOrganizationOwners(ctx, r.TenantID)
+}
+
+// GetUserByEmail looks up a global user, e.g. to add them as a member.
+func (r *TenantRepository) GetUserByEmail(ctx context.Context, email string) (sqlc.User, error) {
+	return r.queries.GetUserByEmail(ctx, email)
+}
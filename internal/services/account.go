@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+var (
+	ErrInvalidCurrentPassword  = apperror.Forbidden("invalid_current_password", "current password is incorrect")
+	ErrEmailTaken              = apperror.Conflict("email_taken", "email address is already in use")
+	ErrSameEmail               = apperror.Validation("same_email", "new email address must differ from the current one")
+	ErrInvalidEmailChangeToken = apperror.Validation("invalid_email_change_token", "invalid or expired email change token")
+)
+
+// ChangePassword sets a new password for a signed-in user. Every other
+// session is signed out; the current one continues with the returned token
+// pair, which replaces the caller's tokens.
+func (s *AuthService) ChangePassword(ctx context.Context, claims *utils.Claims, req *models.ChangePasswordRequest, client ClientInfo) (*models.TokenPair, error) {
+	user, err := s.reauthenticate(ctx, claims.UserID, req.CurrentPassword, client.IP)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.policy.Check(req.NewPassword, PasswordIdentity{
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+	})
+	if err != nil {
+		var weak *PasswordPolicyError
+		if errors.As(err, &weak) {
+			weak.Field = "new_password"
+		}
+		return nil, err
+	}
+
+	hashedPassword, err := s.passwords.Hash(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	familyID := newFamilyID()
+	if claims.SessionID != "" {
+		if familyID, err = utils.ParseUUID(claims.SessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	err = txRepo.UpdateUserPassword(ctx, sqlc.UpdateUserPasswordParams{
+		ID:           user.ID,
+		PasswordHash: hashedPassword,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if err := txRepo.InvalidateUserPasswordResetTokens(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	if err := txRepo.RevokeUserRefreshTokens(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	err = txRepo.RevokeOtherUserSessions(ctx, sqlc.RevokeOtherUserSessionsParams{
+		UserID: user.ID,
+		ID:     familyID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Access tokens are cut off before the replacement pair is minted, so
+	// only the caller's session survives.
+	if err := s.revocations.RevokeAllForUser(ctx, claims.UserID); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.issueTokenPair(ctx, s.repo, user, familyID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	sendInBackground("password changed email", func(ctx context.Context) error {
+		return s.notifier.SendPasswordChanged(ctx, user.Email)
+	})
+
+	return tokens, nil
+}
+
+// RequestEmailChange mails a confirmation link to the new address. The
+// account keeps its current email until the link is opened.
+func (s *AuthService) RequestEmailChange(ctx context.Context, userID string, req *models.ChangeEmailRequest, clientIP string) error {
+	user, err := s.reauthenticate(ctx, userID, req.Password, clientIP)
+	if err != nil {
+		return err
+	}
+
+	newEmail := strings.TrimSpace(req.NewEmail)
+	if strings.EqualFold(newEmail, user.Email) {
+		return ErrSameEmail
+	}
+
+	_, err = s.repo.GetUserByEmail(ctx, newEmail)
+	if err == nil {
+		return ErrEmailTaken
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.repo.CreateEmailChangeToken(ctx, sqlc.CreateEmailChangeTokenParams{
+		UserID:    user.ID,
+		NewEmail:  newEmail,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: pgtype.Timestamptz{
+			Time:  time.Now().Add(s.authConfig.EmailChangeExpiresIn),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store email change token: %w", err)
+	}
+
+	confirmURL := s.authConfig.AppURL + "/confirm-email-change?token=" + url.QueryEscape(token)
+
+	sendInBackground("email change confirmation", func(ctx context.Context) error {
+		return s.notifier.SendEmailChangeConfirmation(ctx, newEmail, confirmURL, s.authConfig.EmailChangeExpiresIn)
+	})
+
+	return nil
+}
+
+// ConfirmEmailChange consumes a confirmation token and moves the account to
+// the new address, which counts as verified. The old address is told.
+func (s *AuthService) ConfirmEmailChange(ctx context.Context, token string) (*models.UserResponse, error) {
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	stored, err := txRepo.GetEmailChangeTokenByHash(ctx, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidEmailChangeToken
+		}
+		return nil, err
+	}
+
+	if stored.UsedAt.Valid || time.Now().After(stored.ExpiresAt.Time) {
+		return nil, ErrInvalidEmailChangeToken
+	}
+
+	rows, err := txRepo.MarkEmailChangeTokenUsed(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, ErrInvalidEmailChangeToken
+	}
+
+	old, err := txRepo.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidEmailChangeToken
+		}
+		return nil, err
+	}
+
+	user, err := txRepo.UpdateUserEmail(ctx, sqlc.UpdateUserEmailParams{
+		ID:    stored.UserID,
+		Email: stored.NewEmail,
+	})
+	if err != nil {
+		// Someone registered the address after the change was requested
+		if isUniqueViolation(err) {
+			return nil, ErrEmailTaken
+		}
+		return nil, fmt.Errorf("failed to update email: %w", err)
+	}
+
+	if err := txRepo.InvalidateUserEmailChangeTokens(ctx, stored.UserID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	sendInBackground("email changed notice", func(ctx context.Context) error {
+		return s.notifier.SendEmailChanged(ctx, old.Email, user.Email)
+	})
+
+	return toUserResponse(user), nil
+}
+
+// reauthenticate checks the password of a signed-in user before a sensitive
+// change. Wrong guesses count towards the login throttle, so a stolen session
+// cannot be used to brute-force the password.
+func (s *AuthService) reauthenticate(ctx context.Context, userID, password, clientIP string) (sqlc.User, error) {
+	var user sqlc.User
+
+	id, err := utils.ParseUUID(userID)
+	if err != nil {
+		return user, err
+	}
+
+	user, err = s.repo.GetUserByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user, ErrUserNotFound
+		}
+		return user, err
+	}
+
+	if err := s.throttle.Check(ctx, user.Email, clientIP); err != nil {
+		return user, err
+	}
+
+	ok, _, err := s.passwords.Verify(password, user.PasswordHash)
+	if err != nil {
+		return user, err
+	}
+	if !ok {
+		if err := s.throttle.RecordFailure(ctx, user.Email, clientIP, &user); err != nil {
+			return user, err
+		}
+		return user, ErrInvalidCurrentPassword
+	}
+
+	return user, nil
+}
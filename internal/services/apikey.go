@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/repository"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+var (
+	ErrAPIKeyNotFound = apperror.NotFound("api_key_not_found", "API key not found")
+	ErrInvalidScope   = apperror.Validation("invalid_scope", "API key scopes must be permissions you hold")
+	ErrInvalidExpiry  = apperror.Validation("invalid_expiry", "expiry must be in the future")
+)
+
+type APIKeyService struct {
+	repo *repository.Repository
+	orgs *OrganizationService
+}
+
+func NewAPIKeyService(repo *repository.Repository, orgs *OrganizationService) *APIKeyService {
+	return &APIKeyService{
+		repo: repo,
+		orgs: orgs,
+	}
+}
+
+// Create issues a new key acting as the user. The full key is returned only
+// here; afterwards only its prefix is shown.
+func (s *APIKeyService) Create(ctx context.Context, userID string, req *models.CreateAPIKeyRequest) (*models.CreatedAPIKeyResponse, error) {
+	id, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.repo.GetUserPermissionNames(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	scopes := []string{}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(permissions, scope) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	var expiresAt pgtype.Timestamptz
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			return nil, ErrInvalidExpiry
+		}
+		expiresAt = pgtype.Timestamptz{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	var orgID pgtype.UUID
+	if req.OrganizationID != "" {
+		role, err := s.orgs.MembershipRole(ctx, req.OrganizationID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if role == "" {
+			return nil, ErrNotOrganizationMember
+		}
+		if !models.OrgRoleAtLeast(role, models.OrgRoleAdmin) {
+			return nil, ErrInsufficientOrgRole
+		}
+		if orgID, err = utils.ParseUUID(req.OrganizationID); err != nil {
+			return nil, err
+		}
+	}
+
+	key, lookup, secret, err := utils.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := s.repo.CreateAPIKey(ctx, sqlc.CreateAPIKeyParams{
+		UserID:         id,
+		OrganizationID: orgID,
+		Name:           req.Name,
+		Prefix:         lookup,
+		SecretHash:     utils.HashToken(secret),
+		Scopes:         scopes,
+		ExpiresAt:      expiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store API key: %w", err)
+	}
+
+	return &models.CreatedAPIKeyResponse{
+		APIKeyResponse: *toAPIKeyResponse(apiKey),
+		Key:            key,
+	}, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, userID string) ([]*models.APIKeyResponse, error) {
+	id, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := s.repo.ListUserAPIKeys(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*models.APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		responses = append(responses, toAPIKeyResponse(key))
+	}
+	return responses, nil
+}
+
+func (s *APIKeyService) Get(ctx context.Context, userID, keyID string) (*models.APIKeyResponse, error) {
+	uid, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := utils.ParseUUID(keyID)
+	if err != nil {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	key, err := s.repo.GetUserAPIKey(ctx, sqlc.GetUserAPIKeyParams{ID: kid, UserID: uid})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	return toAPIKeyResponse(key), nil
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, userID, keyID string) error {
+	uid, err := utils.ParseUUID(userID)
+	if err != nil {
+		return err
+	}
+	kid, err := utils.ParseUUID(keyID)
+	if err != nil {
+		return ErrAPIKeyNotFound
+	}
+
+	rows, err := s.repo.RevokeAPIKey(ctx, sqlc.RevokeAPIKeyParams{ID: kid, UserID: uid})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// AuthenticateAPIKey resolves a key to claims for its user, or to nil claims
+// if the key is not valid. The key's scopes are intersected with the user's
+// current permissions, so revoking a role also narrows every key they issued.
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, key string) (*utils.Claims, error) {
+	lookup, secret, ok := utils.ParseAPIKey(key)
+	if !ok {
+		return nil, nil
+	}
+
+	apiKey, err := s.repo.GetAPIKeyByPrefix(ctx, lookup)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(apiKey.SecretHash)) != 1 {
+		return nil, nil
+	}
+	if apiKey.RevokedAt.Valid || (apiKey.ExpiresAt.Valid && time.Now().After(apiKey.ExpiresAt.Time)) {
+		return nil, nil
+	}
+
+	user, err := s.repo.GetUserByID(ctx, apiKey.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	permissions, err := s.repo.GetUserPermissionNames(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	granted := []string{}
+	for _, scope := range apiKey.Scopes {
+		if slices.Contains(permissions, scope) {
+			granted = append(granted, scope)
+		}
+	}
+
+	if err := s.repo.TouchAPIKey(ctx, apiKey.ID); err != nil {
+		return nil, fmt.Errorf("failed to record API key usage: %w", err)
+	}
+
+	return &utils.Claims{
+		UserID:        user.ID.String(),
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		TokenUse:      utils.TokenUseAPIKey,
+		Permissions:   granted,
+		OrgID:         apiKey.OrganizationID.String(),
+	}, nil
+}
+
+func toAPIKeyResponse(key sqlc.ApiKey) *models.APIKeyResponse {
+	response := &models.APIKeyResponse{
+		ID:             key.ID.String(),
+		Name:           key.Name,
+		Prefix:         utils.APIKeyPrefix + key.Prefix,
+		OrganizationID: key.OrganizationID.String(),
+		Scopes:         key.Scopes,
+		CreatedAt:      key.CreatedAt.Time.Format(time.RFC3339),
+	}
+	if key.ExpiresAt.Valid {
+		response.ExpiresAt = key.ExpiresAt.Time.Format(time.RFC3339)
+	}
+	if key.LastUsedAt.Valid {
+		response.LastUsedAt = key.LastUsedAt.Time.Format(time.RFC3339)
+	}
+	if key.RevokedAt.Valid {
+		response.RevokedAt = key.RevokedAt.Time.Format(time.RFC3339)
+	}
+	return response
+}
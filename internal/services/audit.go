@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/repository"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+// Audit actions
+const (
+	AuditLoginLocked        = "login.locked"
+	AuditLoginUnlocked      = "login.unlocked"
+	AuditOAuthLinked        = "oauth.linked"
+	AuditOAuthUnlinked      = "oauth.unlinked"
+	AuditOAuthConsent       = "oauth.consent"
+	AuditMagicLinkSent      = "magic_link.sent"
+	AuditMagicLinkThrottled = "magic_link.throttled"
+	AuditMagicLinkLogin     = "magic_link.login"
+	AuditPasskeyRegistered  = "passkey.registered"
+	AuditPasskeyRemoved     = "passkey.removed"
+	AuditPasskeyLogin       = "passkey.login"
+	// AuditImpersonationStarted is recorded when a token is issued, and
+	// AuditImpersonatedRequest for every request made with it
+	AuditImpersonationStarted = "impersonation.started"
+	AuditImpersonatedRequest  = "impersonation.request"
+	AuditInvitationCreated    = "invitation.created"
+	AuditInvitationResent     = "invitation.resent"
+	AuditInvitationRevoked    = "invitation.revoked"
+	AuditInvitationAccepted   = "invitation.accepted"
+)
+
+// AuditEntry describes one security-relevant event. UserID is the affected
+// account; ActorID is set when someone else caused the event. During
+// impersonation Record fills it in with the support agent.
+type AuditEntry struct {
+	UserID   pgtype.UUID
+	ActorID  pgtype.UUID
+	Action   string
+	IP       string
+	Metadata map[string]any
+}
+
+type AuditService struct {
+	repo *repository.Repository
+}
+
+func NewAuditService(repo *repository.Repository) *AuditService {
+	return &AuditService{
+		repo: repo,
+	}
+}
+
+// Record stores the entry. Auditing is best effort: a failure is logged but
+// never fails the operation being audited.
+func (s *AuditService) Record(ctx context.Context, entry AuditEntry) {
+	if !entry.ActorID.Valid {
+		if impersonator, ok := ctx.Value(utils.ImpersonatorKey).(string); ok {
+			entry.ActorID, _ = utils.ParseUUID(impersonator)
+		}
+	}
+
+	metadata := []byte("{}")
+	if len(entry.Metadata) > 0 {
+		encoded, err := json.Marshal(entry.Metadata)
+		if err != nil {
+			log.Printf("failed to encode audit metadata for %s: %v", entry.Action, err)
+		} else {
+			metadata = encoded
+		}
+	}
+
+	err := s.repo.CreateAuditLog(ctx, sqlc.CreateAuditLogParams{
+		UserID:    entry.UserID,
+		ActorID:   entry.ActorID,
+		Action:    entry.Action,
+		IpAddress: entry.IP,
+		Metadata:  metadata,
+	})
+	if err != nil {
+		log.Printf("failed to record audit entry %s: %v", entry.Action, err)
+	}
+}
+
+// RecordImpersonatedRequest stores one request made with an impersonation
+// token, so the audit trail shows everything the agent did as the user.
+func (s *AuditService) RecordImpersonatedRequest(ctx context.Context, claims *utils.Claims, method, path string, status int, clientIP string) {
+	userID, err := utils.ParseUUID(claims.UserID)
+	if err != nil {
+		return
+	}
+	actorID, err := utils.ParseUUID(claims.Actor.UserID)
+	if err != nil {
+		return
+	}
+
+	s.Record(ctx, AuditEntry{
+		UserID:  userID,
+		ActorID: actorID,
+		Action:  AuditImpersonatedRequest,
+		IP:      clientIP,
+		Metadata: map[string]any{
+			"method": method,
+			"path":   path,
+			"status": status,
+		},
+	})
+}
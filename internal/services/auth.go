@@ -2,91 +2,443 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/apperror"
 	"github.com/ochko-b/goapp/internal/config"
 	"github.com/ochko-b/goapp/internal/models"
 	"github.com/ochko-b/goapp/internal/repository"
 	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/internal/webauthn"
+)
+
+var (
+	ErrInvalidRefreshToken = apperror.Unauthorized("invalid_refresh_token", "invalid or expired refresh token")
+	ErrRefreshTokenReused  = apperror.Unauthorized("refresh_token_reused", "refresh token reuse detected")
+	ErrEmailNotVerified    = apperror.Forbidden("email_not_verified", "email address has not been verified")
+	ErrInvalidCredentials  = apperror.Unauthorized("invalid_credentials", "invalid credentials")
 )
 
 type AuthService struct {
-	repo      *repository.Repository
-	jwtConfig config.JWTConfig
+	repo        *repository.Repository
+	jwtConfig   config.JWTConfig
+	authConfig  config.AuthConfig
+	keys        *utils.KeyRing
+	revocations *RevocationService
+	notifier    Notifier
+	throttle    *LoginThrottle
+	passwords   *utils.PasswordHasher
+	policy      *PasswordPolicy
+	audit       *AuditService
+	webauthn    *webauthn.RelyingParty
 }
 
-func NewAuthService(repo *repository.Repository, jwtConfig config.JWTConfig) *AuthService {
+func NewAuthService(repo *repository.Repository, jwtConfig config.JWTConfig, authConfig config.AuthConfig, keys *utils.KeyRing, revocations *RevocationService, notifier Notifier, throttle *LoginThrottle, policy *PasswordPolicy, audit *AuditService, rp *webauthn.RelyingParty) *AuthService {
 	return &AuthService{
-		repo:      repo,
-		jwtConfig: jwtConfig,
+		repo:        repo,
+		jwtConfig:   jwtConfig,
+		authConfig:  authConfig,
+		keys:        keys,
+		revocations: revocations,
+		notifier:    notifier,
+		throttle:    throttle,
+		policy:      policy,
+		audit:       audit,
+		webauthn:    rp,
+		passwords: utils.NewPasswordHasher(utils.Argon2Params{
+			Memory:      uint32(authConfig.PasswordArgon2Memory),
+			Iterations:  uint32(authConfig.PasswordArgon2Iterations),
+			Parallelism: uint8(authConfig.PasswordArgon2Parallelism),
+			SaltLength:  utils.DefaultArgon2Params.SaltLength,
+			KeyLength:   utils.DefaultArgon2Params.KeyLength,
+		}),
 	}
 }
 
-func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest) (*models.UserResponse, string, error) {
-	hashedPassword, err := utils.HashPassword(req.Password)
+func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest, client ClientInfo) (*models.UserResponse, *models.TokenPair, error) {
+	err := s.policy.Check(req.Password, PasswordIdentity{
+		Email:     req.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashedPassword, err := s.passwords.Hash(req.Password)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	user, err := s.repo.CreateUser(ctx, sqlc.CreateUserParams{
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	// The user, the verification token and the session are created together,
+	// so a failure leaves no account behind that the client was told about.
+	user, err := txRepo.CreateUser(ctx, sqlc.CreateUserParams{
 		Email:        req.Email,
 		FirstName:    req.FirstName,
 		LastName:     req.LastName,
 		PasswordHash: hashedPassword,
 	})
 	if err != nil {
-		return nil, "", err
+		if isUniqueViolation(err) {
+			return nil, nil, ErrEmailTaken
+		}
+		return nil, nil, err
 	}
 
-	duration, _ := time.ParseDuration(s.jwtConfig.ExpiresIn)
-	token, err := utils.GenerateToken(user.ID.String(), user.Email, s.jwtConfig.Secret, duration)
+	verifyURL, err := s.createVerificationLink(ctx, txRepo, user)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
+	}
+
+	// Unverified users may not log in yet, so there is nothing to hand out.
+	var tokens *models.TokenPair
+	if s.authConfig.EmailVerificationMode != config.EmailVerificationLogin {
+		tokens, err = s.issueTokenPair(ctx, txRepo, user, newFamilyID(), client)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
-	userResponse := &models.UserResponse{
-		ID:        user.ID.String(),
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		CreatedAt: user.CreatedAt.Time.Format(time.RFC3339),
-		UpdatedAt: user.UpdatedAt.Time.Format(time.RFC3339),
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return userResponse, token, nil
+	s.mailVerification(user, verifyURL)
+	return toUserResponse(user), tokens, nil
 }
 
-func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*models.UserResponse, string, error) {
+// Login checks the password. Users with MFA enabled get a short-lived MFA
+// token instead of a token pair, to be exchanged at /auth/mfa/verify.
+// Failures are throttled per account and per client IP.
+func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest, client ClientInfo) (*models.AuthResponse, error) {
+	if err := s.throttle.Check(ctx, req.Email, client.IP); err != nil {
+		return nil, err
+	}
+
 	user, err := s.repo.GetUserByEmail(ctx, req.Email)
 	if err != nil {
-		return nil, "", err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, s.loginFailed(ctx, req.Email, client.IP, nil)
+		}
+		return nil, err
+	}
+
+	ok, needsRehash, err := s.passwords.Verify(req.Password, user.PasswordHash)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, s.loginFailed(ctx, req.Email, client.IP, &user)
+	}
+	if needsRehash {
+		s.rehashPassword(ctx, user, req.Password)
+	}
+
+	return s.completeLogin(ctx, user, client)
+}
+
+// completeLogin finishes a sign-in once the user has proven who they are,
+// with a password or an external identity provider.
+func (s *AuthService) completeLogin(ctx context.Context, user sqlc.User, client ClientInfo) (*models.AuthResponse, error) {
+	if s.authConfig.EmailVerificationMode == config.EmailVerificationLogin && !user.EmailVerifiedAt.Valid {
+		return nil, ErrEmailNotVerified
+	}
+
+	methods, err := s.mfaMethods(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	// With MFA on, the counter is only reset once the second factor passes,
+	// so guessing codes cannot be interleaved with correct passwords.
+	if len(methods) > 0 {
+		mfaToken, err := utils.GenerateToken(utils.Claims{
+			UserID:   user.ID.String(),
+			Email:    user.Email,
+			TokenUse: utils.TokenUseMFAPending,
+		}, s.keys, s.authConfig.MFATokenExpiresIn)
+		if err != nil {
+			return nil, err
+		}
+
+		return &models.AuthResponse{
+			User:        *toUserResponse(user),
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			MFAMethods:  methods,
+		}, nil
+	}
+
+	return s.finishLogin(ctx, user, client)
+}
+
+// finishLogin clears the failed login counter and starts a session once every
+// required factor has passed.
+func (s *AuthService) finishLogin(ctx context.Context, user sqlc.User, client ClientInfo) (*models.AuthResponse, error) {
+	if err := s.throttle.Reset(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.issueTokenPair(ctx, s.repo, user, newFamilyID(), client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		User:      *toUserResponse(user),
+		TokenPair: tokens,
+	}, nil
+}
+
+// UnlockAccount lifts the lockout of another user on behalf of an admin.
+func (s *AuthService) UnlockAccount(ctx context.Context, actorID, userID, clientIP string) error {
+	actor, err := utils.ParseUUID(actorID)
+	if err != nil {
+		return err
+	}
+	id, err := utils.ParseUUID(userID)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	return s.throttle.Unlock(ctx, user, actor, clientIP, "admin")
+}
+
+// UnlockWithToken lets the owner lift a lockout with the link from the
+// lockout email.
+func (s *AuthService) UnlockWithToken(ctx context.Context, token, clientIP string) error {
+	return s.throttle.UnlockWithToken(ctx, token, clientIP)
+}
+
+// rehashPassword stores the password under the current hashing parameters.
+// Hashes are upgraded this way as users sign in, so nobody has to reset their
+// password when the algorithm or its cost changes. Failure is not fatal: the
+// old hash keeps working and the upgrade is retried on the next login.
+func (s *AuthService) rehashPassword(ctx context.Context, user sqlc.User, password string) {
+	hashedPassword, err := s.passwords.Hash(password)
+	if err == nil {
+		err = s.repo.UpdateUserPassword(ctx, sqlc.UpdateUserPasswordParams{
+			ID:           user.ID,
+			PasswordHash: hashedPassword,
+		})
+	}
+	if err != nil {
+		log.Printf("failed to upgrade password hash for user %s: %v", user.ID.String(), err)
+	}
+}
+
+func (s *AuthService) loginFailed(ctx context.Context, email, clientIP string, user *sqlc.User) error {
+	if err := s.throttle.RecordFailure(ctx, email, clientIP, user); err != nil {
+		return err
+	}
+	return ErrInvalidCredentials
+}
+
+// Refresh exchanges a refresh token for a new token pair. Refresh tokens are
+// single-use: presenting one that was already rotated or revoked is treated as
+// theft and revokes every token in its family.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string, client ClientInfo) (*models.UserResponse, *models.TokenPair, error) {
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	stored, err := txRepo.GetRefreshTokenByHash(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, err
+	}
+
+	// Tokens of OAuth clients are refreshed at /oauth/token
+	if stored.ClientID.Valid {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	if stored.UsedAt.Valid || stored.RevokedAt.Valid {
+		return nil, nil, s.revokeFamily(ctx, tx, txRepo, stored.FamilyID)
+	}
+
+	if time.Now().After(stored.ExpiresAt.Time) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	// A concurrent refresh may have consumed the token between the read and
+	// this update; that is indistinguishable from reuse.
+	rows, err := txRepo.MarkRefreshTokenUsed(ctx, stored.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rows == 0 {
+		return nil, nil, s.revokeFamily(ctx, tx, txRepo, stored.FamilyID)
+	}
+
+	user, err := txRepo.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, err
+	}
+
+	tokens, err := s.issueTokenPair(ctx, txRepo, user, stored.FamilyID, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return toUserResponse(user), tokens, nil
+}
+
+// Logout revokes the presented access token and the session it was issued
+// with, or the session of the given refresh token.
+func (s *AuthService) Logout(ctx context.Context, claims *utils.Claims, refreshToken string) error {
+	if claims.SessionID != "" {
+		sessionID, err := utils.ParseUUID(claims.SessionID)
+		if err != nil {
+			return err
+		}
+		if err := s.revocations.RevokeSession(ctx, sessionID); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken != "" {
+		stored, err := s.repo.GetRefreshTokenByHash(ctx, utils.HashToken(refreshToken))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		if err == nil && stored.UserID.String() == claims.UserID && stored.FamilyID.String() != claims.SessionID {
+			if err := s.revocations.RevokeSession(ctx, stored.FamilyID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.revocations.RevokeToken(ctx, claims)
+}
+
+// LogoutAll ends every session of the user, revoking their refresh tokens and
+// every access token issued to them up to now.
+func (s *AuthService) LogoutAll(ctx context.Context, userID string) error {
+	id, err := utils.ParseUUID(userID)
+	if err != nil {
+		return err
 	}
 
-	if !utils.CheckPassword(req.Password, user.PasswordHash) {
-		return nil, "", fmt.Errorf("invalid credentials")
+	if err := s.repo.RevokeUserRefreshTokens(ctx, id); err != nil {
+		return err
+	}
+	if err := s.repo.RevokeUserSessions(ctx, id); err != nil {
+		return err
 	}
 
-	duration, _ := time.ParseDuration(s.jwtConfig.ExpiresIn)
-	token, err := utils.GenerateToken(user.ID.String(), user.Email, s.jwtConfig.Secret, duration)
+	return s.revocations.RevokeAllForUser(ctx, userID)
+}
+
+func (s *AuthService) revokeFamily(ctx context.Context, tx pgx.Tx, txRepo *repository.Repository, familyID pgtype.UUID) error {
+	if err := txRepo.RevokeRefreshTokenFamily(ctx, familyID); err != nil {
+		return err
+	}
+	if err := txRepo.RevokeSession(ctx, familyID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return ErrRefreshTokenReused
+}
+
+// issueTokenPair mints a token pair in the given refresh token family and
+// records the session it belongs to.
+func (s *AuthService) issueTokenPair(ctx context.Context, repo *repository.Repository, user sqlc.User, familyID pgtype.UUID, client ClientInfo) (*models.TokenPair, error) {
+	roles, err := repo.GetUserRoleNames(ctx, user.ID)
 	if err != nil {
-		return nil, "", err
+		return nil, fmt.Errorf("failed to load roles: %w", err)
+	}
+	permissions, err := repo.GetUserPermissionNames(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load permissions: %w", err)
+	}
+
+	claims := utils.Claims{
+		UserID:        user.ID.String(),
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		Roles:         roles,
+		Permissions:   permissions,
+		SessionID:     familyID.String(),
+		Locale:        user.Locale.String,
+	}
+
+	accessToken, err := utils.GenerateToken(claims, s.keys, s.jwtConfig.AccessExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
 	}
 
-	userResponse := &models.UserResponse{
-		ID:        user.ID.String(),
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		CreatedAt: user.CreatedAt.Time.Format(time.RFC3339),
-		UpdatedAt: user.UpdatedAt.Time.Format(time.RFC3339),
+	expiresAt := time.Now().Add(s.jwtConfig.RefreshExpiresIn)
+	_, err = repo.CreateRefreshToken(ctx, sqlc.CreateRefreshTokenParams{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: pgtype.Timestamptz{
+			Time:  expiresAt,
+			Valid: true,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
 	}
 
-	return userResponse, token, nil
+	if err := recordSession(ctx, repo, user.ID, pgtype.UUID{}, familyID, client, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.jwtConfig.AccessExpiresIn.Seconds()),
+	}, nil
 }
 
-func (s *AuthService) RefreshToken(userID, email string) (string, error) {
-	duration, _ := time.ParseDuration(s.jwtConfig.ExpiresIn)
-	return utils.GenerateToken(userID, email, s.jwtConfig.Secret, duration)
+func newFamilyID() pgtype.UUID {
+	return pgtype.UUID{
+		Bytes: uuid.New(),
+		Valid: true,
+	}
 }
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ochko-b/goapp/internal/config"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	repo := testRepository(t)
+	ctx := context.Background()
+
+	jwtConfig := config.JWTConfig{AccessExpiresIn: 15 * time.Minute, RefreshExpiresIn: time.Hour}
+	s := &AuthService{
+		repo:        repo,
+		jwtConfig:   jwtConfig,
+		keys:        testKeyRing(t),
+		revocations: NewRevocationService(repo, jwtConfig.AccessExpiresIn),
+	}
+	user := createTestUser(t, repo)
+	client := ClientInfo{IP: "127.0.0.1", UserAgent: "test"}
+
+	first, err := s.issueTokenPair(ctx, repo, user, newFamilyID(), client)
+	if err != nil {
+		t.Fatalf("issueTokenPair: %v", err)
+	}
+	// An unrelated session of the same user, which must survive.
+	other, err := s.issueTokenPair(ctx, repo, user, newFamilyID(), client)
+	if err != nil {
+		t.Fatalf("issueTokenPair: %v", err)
+	}
+
+	_, rotated, err := s.Refresh(ctx, first.RefreshToken, client)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if rotated.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+
+	// Replaying the rotated token is reuse, and ends the whole family.
+	if _, _, err := s.Refresh(ctx, first.RefreshToken, client); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replayed refresh token: error = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, _, err := s.Refresh(ctx, rotated.RefreshToken, client); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("sibling refresh token after reuse: error = %v, want ErrRefreshTokenReused", err)
+	}
+
+	// The family's access tokens are rejected too, from the database and not
+	// only from this process's cache.
+	claims, err := utils.ValidateToken(rotated.AccessToken, s.keys)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	revoked, err := NewRevocationService(repo, jwtConfig.AccessExpiresIn).IsRevoked(ctx, claims)
+	if err != nil || !revoked {
+		t.Errorf("access token of the revoked family: revoked=%v err=%v", revoked, err)
+	}
+
+	if _, _, err := s.Refresh(ctx, other.RefreshToken, client); err != nil {
+		t.Errorf("other session after reuse: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/repository"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+var ErrInvalidVerificationToken = apperror.Validation("invalid_verification_token", "invalid or expired email verification token")
+
+// VerifyEmail consumes a verification token and marks the owner's address as
+// verified. Any outstanding token works; the others are invalidated with it.
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	stored, err := txRepo.GetEmailVerificationTokenByHash(ctx, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+
+	if stored.UsedAt.Valid || time.Now().After(stored.ExpiresAt.Time) {
+		return ErrInvalidVerificationToken
+	}
+
+	rows, err := txRepo.MarkEmailVerificationTokenUsed(ctx, stored.ID)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrInvalidVerificationToken
+	}
+
+	if err := txRepo.MarkUserEmailVerified(ctx, stored.UserID); err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	if err := txRepo.InvalidateUserEmailVerificationTokens(ctx, stored.UserID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ResendVerification mails a fresh verification link. Like ForgotPassword it
+// does not reveal whether the address belongs to an account, or whether it
+// is already verified.
+func (s *AuthService) ResendVerification(ctx context.Context, email string) error {
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		return nil
+	}
+
+	s.sendVerification(user)
+	return nil
+}
+
+// sendVerification stores a verification token and mails its link in the
+// background, so known and unknown addresses do the same work before the
+// response and take as long.
+func (s *AuthService) sendVerification(user sqlc.User) {
+	sendInBackground("verification email", func(ctx context.Context) error {
+		verifyURL, err := s.createVerificationLink(ctx, s.repo, user)
+		if err != nil {
+			return err
+		}
+		return s.notifier.SendEmailVerification(ctx, user.Email, verifyURL, s.authConfig.EmailVerificationExpiresIn)
+	})
+}
+
+// createVerificationLink stores a verification token for user with repo, so
+// it can be part of the transaction that creates the user. Register mails the
+// link with mailVerification once the transaction commits.
+func (s *AuthService) createVerificationLink(ctx context.Context, repo *repository.Repository, user sqlc.User) (string, error) {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = repo.CreateEmailVerificationToken(ctx, sqlc.CreateEmailVerificationTokenParams{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: pgtype.Timestamptz{
+			Time:  time.Now().Add(s.authConfig.EmailVerificationExpiresIn),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store email verification token: %w", err)
+	}
+
+	return s.authConfig.AppURL + "/verify-email?token=" + url.QueryEscape(token), nil
+}
+
+func (s *AuthService) mailVerification(user sqlc.User, verifyURL string) {
+	sendInBackground("verification email", func(ctx context.Context) error {
+		return s.notifier.SendEmailVerification(ctx, user.Email, verifyURL, s.authConfig.EmailVerificationExpiresIn)
+	})
+}
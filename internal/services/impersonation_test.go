@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/config"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+func TestCanImpersonate(t *testing.T) {
+	support := []string{"users:read", PermissionImpersonate}
+
+	tests := []struct {
+		name   string
+		actor  []string
+		target []string
+		want   bool
+	}{
+		{"user without permissions", support, nil, true},
+		{"subset of the actor's", support, []string{"users:read"}, true},
+		{"permission the actor lacks", support, []string{"users:read", "roles:write"}, false},
+		{"another impersonator", []string{"users:read", "roles:write", PermissionImpersonate}, []string{PermissionImpersonate}, false},
+		{"actor without permissions", nil, []string{"users:read"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := canImpersonate(tt.actor, tt.target); got != tt.want {
+			t.Errorf("%s: canImpersonate(%v, %v) = %v, want %v", tt.name, tt.actor, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestImpersonate(t *testing.T) {
+	repo := testRepository(t)
+	ctx := context.Background()
+
+	s := &AuthService{
+		repo:       repo,
+		authConfig: config.AuthConfig{ImpersonationExpiresIn: 15 * time.Minute},
+		keys:       testKeyRing(t),
+		audit:      NewAuditService(repo),
+	}
+
+	admin, err := repo.GetRoleByName(ctx, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newAdmin := func() sqlc.User {
+		user := createTestUser(t, repo)
+		if err := repo.AssignUserRole(ctx, sqlc.AssignUserRoleParams{UserID: user.ID, RoleID: admin.ID}); err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+	agent := newAdmin()
+	otherAdmin := newAdmin()
+	customer := createTestUser(t, repo)
+
+	actor := &utils.Claims{UserID: agent.ID.String(), Email: agent.Email}
+	req := &models.ImpersonateRequest{Reason: "test"}
+
+	resp, err := s.Impersonate(ctx, actor, customer.ID.String(), req, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Impersonate customer: %v", err)
+	}
+	claims, err := utils.ValidateToken(resp.AccessToken, s.keys)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if claims.UserID != customer.ID.String() || claims.Actor == nil || claims.Actor.UserID != agent.ID.String() {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	tests := []struct {
+		name   string
+		actor  *utils.Claims
+		target string
+		want   error
+	}{
+		{"another admin", actor, otherAdmin.ID.String(), ErrCannotImpersonate},
+		{"themselves", actor, agent.ID.String(), ErrCannotImpersonateSelf},
+		{"from an impersonation token", claims, otherAdmin.ID.String(), ErrCannotImpersonate},
+	}
+	for _, tt := range tests {
+		if _, err := s.Impersonate(ctx, tt.actor, tt.target, req, "127.0.0.1"); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
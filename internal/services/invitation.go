@@ -0,0 +1,494 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/config"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/repository"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+var (
+	ErrInvitationNotFound      = apperror.NotFound("invitation_not_found", "invitation not found")
+	ErrInvalidInvitation       = apperror.Unauthorized("invalid_invitation", "invalid or expired invitation")
+	ErrInvitationPending       = apperror.Conflict("invitation_pending", "this email address already has a pending invitation; resend it instead")
+	ErrInvitationNotPending    = apperror.Conflict("invitation_not_pending", "invitation has already been accepted or revoked")
+	ErrInvitationRoleForbidden = apperror.Forbidden("invitation_role_forbidden", "inviting with a role requires the roles:write permission")
+	ErrInvalidInvitationStatus = apperror.Validation("invalid_invitation_status", "status must be one of pending, expired, accepted or revoked")
+)
+
+// Invitation statuses. Only pending, accepted and revoked are stored; a
+// pending invitation past its expiry is reported as expired.
+const (
+	InvitationPending  = "pending"
+	InvitationExpired  = "expired"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+)
+
+// InvitationService lets admins invite people by email. Accepting creates
+// the account, with the email already verified since the invitation reached
+// it, and grants the role the invitation was created with.
+type InvitationService struct {
+	repo       *repository.Repository
+	auth       *AuthService
+	oauth      *OAuthService
+	audit      *AuditService
+	notifier   Notifier
+	authConfig config.AuthConfig
+}
+
+func NewInvitationService(repo *repository.Repository, auth *AuthService, oauth *OAuthService, audit *AuditService, notifier Notifier, authConfig config.AuthConfig) *InvitationService {
+	return &InvitationService{
+		repo:       repo,
+		auth:       auth,
+		oauth:      oauth,
+		audit:      audit,
+		notifier:   notifier,
+		authConfig: authConfig,
+	}
+}
+
+// Create invites an email address and mails the invitation. Granting a role
+// this way needs the same permission as granting it directly.
+func (s *InvitationService) Create(ctx context.Context, actor *utils.Claims, req *models.CreateInvitationRequest, clientIP string) (*models.InvitationResponse, error) {
+	actorID, err := utils.ParseUUID(actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var roleID pgtype.UUID
+	if req.Role != "" {
+		if !actor.HasPermission("roles:write") {
+			return nil, ErrInvitationRoleForbidden
+		}
+		role, err := s.repo.GetRoleByName(ctx, req.Role)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrRoleNotFound
+			}
+			return nil, err
+		}
+		roleID = role.ID
+	}
+
+	if _, err := s.repo.GetUserByEmail(ctx, req.Email); err == nil {
+		return nil, ErrEmailTaken
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	// An expired invitation still counts: resending it starts it over
+	if _, err := s.repo.GetPendingInvitationByEmail(ctx, req.Email); err == nil {
+		return nil, ErrInvitationPending
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.repo.CreateInvitation(ctx, sqlc.CreateInvitationParams{
+		Email:     req.Email,
+		RoleID:    roleID,
+		TokenHash: utils.HashToken(token),
+		InvitedBy: actorID,
+		ExpiresAt: pgtype.Timestamptz{
+			Time:  time.Now().Add(s.authConfig.InvitationExpiresIn),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrInvitationPending
+		}
+		return nil, fmt.Errorf("failed to store invitation: %w", err)
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:  actorID,
+		Action:   AuditInvitationCreated,
+		IP:       clientIP,
+		Metadata: map[string]any{"invitation_id": invitation.ID.String(), "email": invitation.Email, "role": req.Role},
+	})
+
+	s.send(invitation.Email, token)
+
+	return toInvitationResponse(invitation, req.Role), nil
+}
+
+// List returns invitations, newest first, optionally only those with the
+// given status.
+func (s *InvitationService) List(ctx context.Context, status string, limit, offset int32) ([]*models.InvitationResponse, error) {
+	switch status {
+	case "", InvitationPending, InvitationExpired, InvitationAccepted, InvitationRevoked:
+	default:
+		return nil, ErrInvalidInvitationStatus
+	}
+
+	rows, err := s.repo.ListInvitations(ctx, sqlc.ListInvitationsParams{
+		Status: status,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+
+	responses := make([]*models.InvitationResponse, 0, len(rows))
+	for _, row := range rows {
+		responses = append(responses, toInvitationResponse(sqlc.Invitation{
+			ID:             row.ID,
+			Email:          row.Email,
+			RoleID:         row.RoleID,
+			TokenHash:      row.TokenHash,
+			Status:         row.Status,
+			InvitedBy:      row.InvitedBy,
+			AcceptedUserID: row.AcceptedUserID,
+			SentCount:      row.SentCount,
+			ExpiresAt:      row.ExpiresAt,
+			LastSentAt:     row.LastSentAt,
+			AcceptedAt:     row.AcceptedAt,
+			RevokedAt:      row.RevokedAt,
+			CreatedAt:      row.CreatedAt,
+		}, row.RoleName))
+	}
+	return responses, nil
+}
+
+// Resend mails a pending or expired invitation again. The token is replaced,
+// so earlier emails stop working, and the expiry starts over.
+func (s *InvitationService) Resend(ctx context.Context, actorID, invitationID, clientIP string) (*models.InvitationResponse, error) {
+	actor, err := utils.ParseUUID(actorID)
+	if err != nil {
+		return nil, err
+	}
+	id, err := utils.ParseUUID(invitationID)
+	if err != nil {
+		return nil, ErrInvitationNotFound
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.repo.RenewInvitation(ctx, sqlc.RenewInvitationParams{
+		ID:        id,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: pgtype.Timestamptz{
+			Time:  time.Now().Add(s.authConfig.InvitationExpiresIn),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, s.notPending(ctx, id)
+		}
+		return nil, err
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:  actor,
+		Action:   AuditInvitationResent,
+		IP:       clientIP,
+		Metadata: map[string]any{"invitation_id": invitation.ID.String(), "email": invitation.Email},
+	})
+
+	s.send(invitation.Email, token)
+
+	roleName, err := s.roleName(ctx, invitation.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	return toInvitationResponse(invitation, roleName), nil
+}
+
+// Revoke cancels a pending invitation.
+func (s *InvitationService) Revoke(ctx context.Context, actorID, invitationID, clientIP string) error {
+	actor, err := utils.ParseUUID(actorID)
+	if err != nil {
+		return err
+	}
+	id, err := utils.ParseUUID(invitationID)
+	if err != nil {
+		return ErrInvitationNotFound
+	}
+
+	rows, err := s.repo.RevokeInvitation(ctx, id)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return s.notPending(ctx, id)
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:  actor,
+		Action:   AuditInvitationRevoked,
+		IP:       clientIP,
+		Metadata: map[string]any{"invitation_id": id.String()},
+	})
+
+	return nil
+}
+
+// Lookup returns what the invitee needs to see before accepting.
+func (s *InvitationService) Lookup(ctx context.Context, token string) (*models.InvitationDetailsResponse, error) {
+	invitation, err := s.pendingInvitation(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	roleName, err := s.roleName(ctx, invitation.RoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.InvitationDetailsResponse{
+		Email:     invitation.Email,
+		Role:      roleName,
+		ExpiresAt: invitation.ExpiresAt.Time.Format(time.RFC3339),
+	}, nil
+}
+
+// Accept creates the invited account with a password and signs the user in.
+func (s *InvitationService) Accept(ctx context.Context, req *models.AcceptInvitationRequest, client ClientInfo) (*models.AuthResponse, error) {
+	invitation, err := s.pendingInvitation(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.policy.Check(req.Password, PasswordIdentity{
+		Email:     invitation.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := s.auth.passwords.Hash(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.accept(ctx, req.Token, client.IP, func(txRepo *repository.Repository, invitation sqlc.Invitation) (sqlc.User, error) {
+		return txRepo.CreateUser(ctx, sqlc.CreateUserParams{
+			Email:        invitation.Email,
+			FirstName:    req.FirstName,
+			LastName:     req.LastName,
+			PasswordHash: hashedPassword,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.auth.finishLogin(ctx, user, client)
+}
+
+// AcceptWithOAuth creates the invited account without a password, linked to
+// the provider identity the user just signed in with, and signs them in.
+func (s *InvitationService) AcceptWithOAuth(ctx context.Context, providerName string, req *models.AcceptInvitationOAuthRequest, client ClientInfo) (*models.AuthResponse, error) {
+	// Checked before the provider's one-time code is spent
+	if _, err := s.pendingInvitation(ctx, req.Token); err != nil {
+		return nil, err
+	}
+
+	claims, err := s.oauth.authenticate(ctx, providerName, &req.OAuthCallbackRequest, pgtype.UUID{})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetUserIdentity(ctx, sqlc.GetUserIdentityParams{Provider: providerName, Subject: claims.Subject}); err == nil {
+		return nil, ErrIdentityLinked
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(strings.TrimSpace(claims.Name), " ")
+	}
+
+	user, err := s.accept(ctx, req.Token, client.IP, func(txRepo *repository.Repository, invitation sqlc.Invitation) (sqlc.User, error) {
+		user, err := txRepo.CreateExternalUser(ctx, sqlc.CreateExternalUserParams{
+			Email:           invitation.Email,
+			FirstName:       firstName,
+			LastName:        lastName,
+			EmailVerifiedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		})
+		if err != nil {
+			return user, err
+		}
+		_, err = s.oauth.createIdentity(ctx, txRepo, user.ID, providerName, claims, client.IP)
+		return user, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.auth.finishLogin(ctx, user, client)
+}
+
+// accept runs create and completes the invitation in one transaction, with
+// the invitation locked so it cannot be accepted twice.
+func (s *InvitationService) accept(ctx context.Context, token, clientIP string, create func(txRepo *repository.Repository, invitation sqlc.Invitation) (sqlc.User, error)) (sqlc.User, error) {
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return sqlc.User{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	invitation, err := txRepo.LockInvitationByTokenHash(ctx, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.User{}, ErrInvalidInvitation
+		}
+		return sqlc.User{}, err
+	}
+	if invitationStatus(invitation) != InvitationPending {
+		return sqlc.User{}, ErrInvalidInvitation
+	}
+
+	user, err := create(txRepo, invitation)
+	if err != nil {
+		// Registered with the same email since the invitation was sent
+		if isUniqueViolation(err) {
+			return sqlc.User{}, ErrEmailTaken
+		}
+		return sqlc.User{}, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	if err := txRepo.MarkUserEmailVerified(ctx, user.ID); err != nil {
+		return sqlc.User{}, fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	if invitation.RoleID.Valid {
+		err := txRepo.AssignUserRole(ctx, sqlc.AssignUserRoleParams{UserID: user.ID, RoleID: invitation.RoleID})
+		if err != nil {
+			return sqlc.User{}, fmt.Errorf("failed to assign role: %w", err)
+		}
+	}
+
+	err = txRepo.AcceptInvitation(ctx, sqlc.AcceptInvitationParams{ID: invitation.ID, AcceptedUserID: user.ID})
+	if err != nil {
+		return sqlc.User{}, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	// Re-read so the token claims see the verified email
+	user, err = txRepo.GetUserByID(ctx, user.ID)
+	if err != nil {
+		return sqlc.User{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return sqlc.User{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		UserID:   user.ID,
+		ActorID:  invitation.InvitedBy,
+		Action:   AuditInvitationAccepted,
+		IP:       clientIP,
+		Metadata: map[string]any{"invitation_id": invitation.ID.String()},
+	})
+
+	return user, nil
+}
+
+func (s *InvitationService) pendingInvitation(ctx context.Context, token string) (sqlc.Invitation, error) {
+	invitation, err := s.repo.GetInvitationByTokenHash(ctx, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return invitation, ErrInvalidInvitation
+		}
+		return invitation, err
+	}
+	if invitationStatus(invitation) != InvitationPending {
+		return invitation, ErrInvalidInvitation
+	}
+	return invitation, nil
+}
+
+// notPending tells a missing invitation apart from one that can no longer
+// change, after an update matched no pending row.
+func (s *InvitationService) notPending(ctx context.Context, id pgtype.UUID) error {
+	if _, err := s.repo.GetInvitationByID(ctx, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvitationNotFound
+		}
+		return err
+	}
+	return ErrInvitationNotPending
+}
+
+func (s *InvitationService) roleName(ctx context.Context, roleID pgtype.UUID) (string, error) {
+	if !roleID.Valid {
+		return "", nil
+	}
+	role, err := s.repo.GetRoleByID(ctx, roleID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return role.Name, nil
+}
+
+func (s *InvitationService) send(email, token string) {
+	acceptURL := s.authConfig.AppURL + "/invitations/accept?token=" + url.QueryEscape(token)
+
+	sendInBackground("invitation email", func(ctx context.Context) error {
+		return s.notifier.SendInvitation(ctx, email, acceptURL, s.authConfig.InvitationExpiresIn)
+	})
+}
+
+func invitationStatus(invitation sqlc.Invitation) string {
+	if invitation.Status == InvitationPending && !invitation.ExpiresAt.Time.After(time.Now()) {
+		return InvitationExpired
+	}
+	return invitation.Status
+}
+
+func toInvitationResponse(invitation sqlc.Invitation, roleName string) *models.InvitationResponse {
+	response := &models.InvitationResponse{
+		ID:         invitation.ID.String(),
+		Email:      invitation.Email,
+		Role:       roleName,
+		Status:     invitationStatus(invitation),
+		SentCount:  invitation.SentCount,
+		ExpiresAt:  invitation.ExpiresAt.Time.Format(time.RFC3339),
+		LastSentAt: invitation.LastSentAt.Time.Format(time.RFC3339),
+		CreatedAt:  invitation.CreatedAt.Time.Format(time.RFC3339),
+	}
+	if invitation.InvitedBy.Valid {
+		response.InvitedBy = invitation.InvitedBy.String()
+	}
+	if invitation.AcceptedUserID.Valid {
+		response.AcceptedUserID = invitation.AcceptedUserID.String()
+	}
+	if invitation.AcceptedAt.Valid {
+		response.AcceptedAt = invitation.AcceptedAt.Time.Format(time.RFC3339)
+	}
+	if invitation.RevokedAt.Valid {
+		response.RevokedAt = invitation.RevokedAt.Time.Format(time.RFC3339)
+	}
+	return response
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/config"
+	"github.com/ochko-b/goapp/internal/repository"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+// Throttle scopes
+const (
+	throttleAccount = "account"
+	throttleIP      = "ip"
+)
+
+const (
+	maxLockout     = 24 * time.Hour
+	unlockTokenTTL = 24 * time.Hour
+)
+
+var (
+	ErrTooManyLoginAttempts = apperror.RateLimited("too_many_login_attempts", "too many failed login attempts")
+	ErrInvalidUnlockToken   = apperror.Validation("invalid_unlock_token", "invalid or expired unlock token")
+)
+
+// LoginThrottledError is returned while an account or client IP is backing
+// off or locked out. It wraps ErrTooManyLoginAttempts.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", ErrTooManyLoginAttempts, e.RetryAfter)
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
+// LoginThrottle tracks failed sign-ins per account and per client IP in
+// Postgres, so every replica sees the same counters. From half the failure
+// limit on, each further failure delays the next attempt exponentially;
+// reaching the limit locks the subject out, again doubling per failure.
+type LoginThrottle struct {
+	repo     *repository.Repository
+	cfg      config.AuthConfig
+	audit    *AuditService
+	notifier Notifier
+}
+
+func NewLoginThrottle(repo *repository.Repository, cfg config.AuthConfig, audit *AuditService, notifier Notifier) *LoginThrottle {
+	return &LoginThrottle{
+		repo:     repo,
+		cfg:      cfg,
+		audit:    audit,
+		notifier: notifier,
+	}
+}
+
+// Check returns a *LoginThrottledError if the account or IP may not try yet.
+func (t *LoginThrottle) Check(ctx context.Context, email, ip string) error {
+	var wait time.Duration
+	for _, key := range t.keys(email, ip) {
+		row, err := t.repo.GetLoginThrottle(ctx, sqlc.GetLoginThrottleParams{Scope: key.scope, Subject: key.subject})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			return err
+		}
+		if row.LockedUntil.Valid {
+			wait = max(wait, time.Until(row.LockedUntil.Time))
+		}
+	}
+
+	if wait > 0 {
+		return &LoginThrottledError{RetryAfter: time.Duration(math.Ceil(wait.Seconds())) * time.Second}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt and applies backoff or a lockout.
+// user is nil when the email does not belong to an account; the attempt is
+// counted all the same, so responses do not reveal which emails exist.
+func (t *LoginThrottle) RecordFailure(ctx context.Context, email, ip string, user *sqlc.User) error {
+	now := time.Now()
+
+	for _, key := range t.keys(email, ip) {
+		row, err := t.repo.RecordLoginFailure(ctx, sqlc.RecordLoginFailureParams{
+			Scope:       key.scope,
+			Subject:     key.subject,
+			WindowStart: pgtype.Timestamptz{Time: now.Add(-t.cfg.LoginFailureWindow), Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record login failure: %w", err)
+		}
+
+		wait, lockout := t.penalty(int(row.Failures), key.limit)
+		if wait == 0 {
+			continue
+		}
+
+		lockedUntil := now.Add(wait)
+		err = t.repo.LockLogin(ctx, sqlc.LockLoginParams{
+			Scope:       key.scope,
+			Subject:     key.subject,
+			LockedUntil: pgtype.Timestamptz{Time: lockedUntil, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to lock login: %w", err)
+		}
+
+		if !lockout {
+			continue
+		}
+
+		entry := AuditEntry{
+			Action: AuditLoginLocked,
+			IP:     ip,
+			Metadata: map[string]any{
+				"scope":        key.scope,
+				"failures":     row.Failures,
+				"locked_until": lockedUntil.UTC().Format(time.RFC3339),
+			},
+		}
+		if key.scope == throttleAccount && user != nil {
+			entry.UserID = user.ID
+			t.notifyLocked(ctx, *user, wait)
+		}
+		t.audit.Record(ctx, entry)
+	}
+
+	return nil
+}
+
+// Reset clears the account's failures after a successful sign-in. The IP
+// counter is left to expire, so one known account cannot be used to keep
+// resetting it.
+func (t *LoginThrottle) Reset(ctx context.Context, email string) error {
+	_, err := t.repo.DeleteLoginThrottle(ctx, sqlc.DeleteLoginThrottleParams{
+		Scope:   throttleAccount,
+		Subject: validator.NormalizeEmail(email),
+	})
+	return err
+}
+
+// Unlock lifts an account lockout and records who did it. actorID is left
+// invalid when the owner unlocks their own account.
+func (t *LoginThrottle) Unlock(ctx context.Context, user sqlc.User, actorID pgtype.UUID, ip, reason string) error {
+	rows, err := t.repo.DeleteLoginThrottle(ctx, sqlc.DeleteLoginThrottleParams{
+		Scope:   throttleAccount,
+		Subject: validator.NormalizeEmail(user.Email),
+	})
+	if err != nil {
+		return err
+	}
+
+	if rows > 0 {
+		t.audit.Record(ctx, AuditEntry{
+			UserID:   user.ID,
+			ActorID:  actorID,
+			Action:   AuditLoginUnlocked,
+			IP:       ip,
+			Metadata: map[string]any{"reason": reason},
+		})
+	}
+	return nil
+}
+
+// UnlockWithToken consumes an emailed unlock token.
+func (t *LoginThrottle) UnlockWithToken(ctx context.Context, token, ip string) error {
+	userID, err := t.repo.ConsumeAccountUnlockToken(ctx, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidUnlockToken
+		}
+		return err
+	}
+
+	user, err := t.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidUnlockToken
+		}
+		return err
+	}
+
+	return t.Unlock(ctx, user, pgtype.UUID{}, ip, "email_link")
+}
+
+// RunSweeper periodically drops counters that have expired.
+func (t *LoginThrottle) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cutoff := pgtype.Timestamptz{Time: time.Now().Add(-t.cfg.LoginFailureWindow), Valid: true}
+			if err := t.repo.DeleteStaleLoginThrottles(ctx, cutoff); err != nil {
+				log.Printf("failed to sweep login throttles: %v", err)
+			}
+		}
+	}
+}
+
+// penalty returns how long the subject has to wait after its failures-th
+// failure, and whether that wait is a lockout rather than a backoff delay.
+func (t *LoginThrottle) penalty(failures, limit int) (time.Duration, bool) {
+	if failures >= limit {
+		return doubled(t.cfg.LoginLockoutDuration, failures-limit, maxLockout), true
+	}
+	// Backoff delays never exceed the lockout itself
+	if backoffFrom := limit / 2; failures >= backoffFrom {
+		return doubled(t.cfg.LoginBackoffBase, failures-backoffFrom, t.cfg.LoginLockoutDuration), false
+	}
+	return 0, false
+}
+
+func (t *LoginThrottle) notifyLocked(ctx context.Context, user sqlc.User, lockedFor time.Duration) {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		log.Printf("failed to generate unlock token: %v", err)
+		return
+	}
+
+	_, err = t.repo.CreateAccountUnlockToken(ctx, sqlc.CreateAccountUnlockTokenParams{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(unlockTokenTTL), Valid: true},
+	})
+	if err != nil {
+		log.Printf("failed to store unlock token: %v", err)
+		return
+	}
+
+	unlockURL := t.cfg.AppURL + "/unlock-account?token=" + url.QueryEscape(token)
+
+	sendInBackground("account locked email", func(ctx context.Context) error {
+		return t.notifier.SendAccountLocked(ctx, user.Email, unlockURL, lockedFor)
+	})
+}
+
+type throttleKey struct {
+	scope   string
+	subject string
+	limit   int
+}
+
+func (t *LoginThrottle) keys(email, ip string) []throttleKey {
+	keys := []throttleKey{{throttleAccount, validator.NormalizeEmail(email), t.cfg.LoginMaxAccountFailures}}
+	if ip != "" {
+		keys = append(keys, throttleKey{throttleIP, ip, t.cfg.LoginMaxIPFailures})
+	}
+	return keys
+}
+
+// doubled returns base * 2^n, capped at limit.
+func doubled(base time.Duration, n int, limit time.Duration) time.Duration {
+	d := base
+	for i := 0; i < n && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/config"
+	"github.com/ochko-b/goapp/internal/repository"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+func TestDoubled(t *testing.T) {
+	tests := []struct {
+		base  time.Duration
+		n     int
+		limit time.Duration
+		want  time.Duration
+	}{
+		{time.Second, 0, time.Minute, time.Second},
+		{time.Second, 1, time.Minute, 2 * time.Second},
+		{time.Second, 5, time.Minute, 32 * time.Second},
+		{time.Second, 6, time.Minute, time.Minute},
+		{time.Second, 1000, time.Minute, time.Minute},
+		{2 * time.Minute, 0, time.Minute, time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := doubled(tt.base, tt.n, tt.limit); got != tt.want {
+			t.Errorf("doubled(%s, %d, %s) = %s, want %s", tt.base, tt.n, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestPenalty(t *testing.T) {
+	throttle := &LoginThrottle{cfg: config.AuthConfig{
+		LoginBackoffBase:     time.Minute,
+		LoginLockoutDuration: 15 * time.Minute,
+	}}
+
+	// With a limit of 10, backoff starts at the 5th failure and doubles up to
+	// the lockout duration; from the 10th failure on the subject is locked
+	// out, again doubling, for at most a day.
+	tests := []struct {
+		failures int
+		wait     time.Duration
+		lockout  bool
+	}{
+		{1, 0, false},
+		{4, 0, false},
+		{5, time.Minute, false},
+		{6, 2 * time.Minute, false},
+		{8, 8 * time.Minute, false},
+		{9, 15 * time.Minute, false},
+		{10, 15 * time.Minute, true},
+		{11, 30 * time.Minute, true},
+		{13, 2 * time.Hour, true},
+		{16, 16 * time.Hour, true},
+		{17, maxLockout, true},
+		{100, maxLockout, true},
+	}
+
+	for _, tt := range tests {
+		wait, lockout := throttle.penalty(tt.failures, 10)
+		if wait != tt.wait || lockout != tt.lockout {
+			t.Errorf("failure %d: got %s lockout=%v, want %s lockout=%v", tt.failures, wait, lockout, tt.wait, tt.lockout)
+		}
+	}
+
+	// A limit of 1 locks out on the first failure without any backoff.
+	if wait, lockout := throttle.penalty(1, 1); wait != 15*time.Minute || !lockout {
+		t.Errorf("limit 1: got %s lockout=%v", wait, lockout)
+	}
+}
+
+// testRepository connects to the migrated database at TEST_DATABASE_URL and
+// skips the test without one.
+func testRepository(t *testing.T) *repository.Repository {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return repository.New(pool)
+}
+
+// createTestUser creates a user with a unique address, deactivated again
+// when the test ends.
+func createTestUser(t *testing.T, repo *repository.Repository) sqlc.User {
+	t.Helper()
+	ctx := context.Background()
+
+	suffix, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := repo.CreateUser(ctx, sqlc.CreateUserParams{Email: "test-" + suffix + "@example.com", FirstName: "Test", LastName: "User"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	t.Cleanup(func() { repo.DeactiviateUser(ctx, user.ID) })
+	return user
+}
+
+// testKeyRing signs with a shared secret.
+func testKeyRing(t *testing.T) *utils.KeyRing {
+	t.Helper()
+	keys, err := utils.LoadKeyRing("", "", "test-secret", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestRecordLoginFailureWindow(t *testing.T) {
+	repo := testRepository(t)
+	ctx := context.Background()
+
+	subject, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		repo.DeleteLoginThrottle(ctx, sqlc.DeleteLoginThrottleParams{Scope: throttleIP, Subject: subject})
+	})
+
+	record := func(windowStart time.Time) int32 {
+		t.Helper()
+		row, err := repo.RecordLoginFailure(ctx, sqlc.RecordLoginFailureParams{
+			Scope:       throttleIP,
+			Subject:     subject,
+			WindowStart: pgtype.Timestamptz{Time: windowStart, Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("RecordLoginFailure: %v", err)
+		}
+		return row.Failures
+	}
+
+	hourAgo := time.Now().Add(-time.Hour)
+	if got := record(hourAgo); got != 1 {
+		t.Errorf("first failure: failures = %d, want 1", got)
+	}
+	if got := record(hourAgo); got != 2 {
+		t.Errorf("failure within the window: failures = %d, want 2", got)
+	}
+
+	// A window starting after the last failure means it has aged out, so
+	// counting starts over.
+	if got := record(time.Now().Add(time.Minute)); got != 1 {
+		t.Errorf("failure after the window: failures = %d, want 1", got)
+	}
+	if got := record(hourAgo); got != 2 {
+		t.Errorf("failure after the reset: failures = %d, want 2", got)
+	}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+var (
+	ErrMagicLinkDisabled     = apperror.NotFound("magic_link_disabled", "magic link login is disabled")
+	ErrInvalidMagicLinkToken = apperror.Unauthorized("invalid_magic_link_token", "invalid or expired login link")
+)
+
+// RequestMagicLink mails a single-use sign-in link. Like ForgotPassword it
+// returns nil for unknown addresses, and requests over the per-address limit
+// are dropped just as quietly, so neither reveals whether an account exists.
+func (s *AuthService) RequestMagicLink(ctx context.Context, email, clientIP string) error {
+	if !s.authConfig.MagicLinkEnabled {
+		return ErrMagicLinkDisabled
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	// Check the limit, store the token and deliver in the background, so
+	// known and unknown addresses do the same work before the response and
+	// take as long.
+	sendInBackground("magic link email", func(ctx context.Context) error {
+		return s.sendMagicLink(ctx, user, clientIP)
+	})
+
+	return nil
+}
+
+func (s *AuthService) sendMagicLink(ctx context.Context, user sqlc.User, clientIP string) error {
+	recent, err := s.repo.CountRecentMagicLinkTokens(ctx, sqlc.CountRecentMagicLinkTokensParams{
+		UserID:    user.ID,
+		CreatedAt: pgtype.Timestamptz{Time: time.Now().Add(-s.authConfig.MagicLinkRequestWindow), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if recent >= int64(s.authConfig.MagicLinkMaxRequests) {
+		s.audit.Record(ctx, AuditEntry{UserID: user.ID, Action: AuditMagicLinkThrottled, IP: clientIP})
+		return nil
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.repo.CreateMagicLinkToken(ctx, sqlc.CreateMagicLinkTokenParams{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: pgtype.Timestamptz{
+			Time:  time.Now().Add(s.authConfig.MagicLinkExpiresIn),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store magic link token: %w", err)
+	}
+
+	s.audit.Record(ctx, AuditEntry{UserID: user.ID, Action: AuditMagicLinkSent, IP: clientIP})
+
+	loginURL := s.authConfig.AppURL + "/magic-link?token=" + url.QueryEscape(token)
+	return s.notifier.SendMagicLink(ctx, user.Email, loginURL, s.authConfig.MagicLinkExpiresIn)
+}
+
+// ConsumeMagicLink signs the user in with a link from RequestMagicLink. The
+// link proves control of the mailbox, so it also verifies the address; users
+// with MFA enabled still have to pass the second factor.
+func (s *AuthService) ConsumeMagicLink(ctx context.Context, token string, client ClientInfo) (*models.AuthResponse, error) {
+	if !s.authConfig.MagicLinkEnabled {
+		return nil, ErrMagicLinkDisabled
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	stored, err := txRepo.ConsumeMagicLinkToken(ctx, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidMagicLinkToken
+		}
+		return nil, err
+	}
+
+	// Any other link still in the mailbox stops working with this one
+	if err := txRepo.InvalidateUserMagicLinkTokens(ctx, stored.UserID); err != nil {
+		return nil, err
+	}
+
+	if err := txRepo.MarkUserEmailVerified(ctx, stored.UserID); err != nil {
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	user, err := txRepo.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidMagicLinkToken
+		}
+		return nil, err
+	}
+	if !user.IsActive.Bool {
+		return nil, ErrInvalidMagicLinkToken
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.audit.Record(ctx, AuditEntry{UserID: user.ID, Action: AuditMagicLinkLogin, IP: client.IP})
+
+	return s.completeLogin(ctx, user, client)
+}
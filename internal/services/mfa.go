@@ -0,0 +1,352 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/repository"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/qrcode"
+)
+
+var (
+	ErrMFAAlreadyEnabled = apperror.Conflict("mfa_already_enabled", "two-factor authentication is already enabled")
+	ErrMFANotEnrolled    = apperror.Validation("mfa_not_enrolled", "two-factor authentication has not been set up")
+	ErrInvalidMFACode    = apperror.Unauthorized("invalid_mfa_code", "invalid authentication code")
+	ErrInvalidMFAToken   = apperror.Unauthorized("invalid_mfa_token", "invalid or expired MFA token")
+)
+
+// Second factors, as listed in AuthResponse.MFAMethods
+const (
+	MFAMethodTOTP    = "totp"
+	MFAMethodPasskey = "passkey"
+)
+
+const (
+	recoveryCodeCount = 10
+	qrCodeScale       = 6
+)
+
+// SetupTOTP starts TOTP enrollment with a fresh secret. MFA stays disabled
+// until ConfirmTOTP sees a first valid code.
+func (s *AuthService) SetupTOTP(ctx context.Context, userID string) (*models.TOTPSetupResponse, error) {
+	id, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.repo.UpsertPendingTOTP(ctx, sqlc.UpsertPendingTOTPParams{
+		UserID:     id,
+		TotpSecret: secret,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	uri := utils.TOTPProvisioningURI(s.authConfig.MFAIssuer, user.Email, secret)
+	png, err := qrcode.PNG(uri, qrCodeScale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render QR code: %w", err)
+	}
+
+	return &models.TOTPSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: uri,
+		QRCode:          "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// ConfirmTOTP enables MFA once the user proves their authenticator works and
+// returns the recovery codes. They are shown only this once.
+func (s *AuthService) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	id, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	mfa, err := txRepo.GetUserMFA(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	if mfa.EnabledAt.Valid {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := utils.ValidateTOTP(mfa.TotpSecret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	if _, err := txRepo.UseTOTPStep(ctx, sqlc.UseTOTPStepParams{UserID: id, LastUsedStep: step}); err != nil {
+		return nil, err
+	}
+
+	if err := txRepo.EnableUserMFA(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to enable MFA: %w", err)
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, txRepo, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes invalidates all recovery codes and issues new ones.
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	id, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	if err := checkMFACode(ctx, txRepo, id, code); err != nil {
+		return nil, err
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, txRepo, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return codes, nil
+}
+
+// DisableMFA turns MFA off. It requires a current code, so a stolen access
+// token alone cannot remove the second factor.
+func (s *AuthService) DisableMFA(ctx context.Context, userID, code string) error {
+	id, err := utils.ParseUUID(userID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	if err := checkMFACode(ctx, txRepo, id, code); err != nil {
+		return err
+	}
+
+	if err := txRepo.DeleteUserRecoveryCodes(ctx, id); err != nil {
+		return err
+	}
+	if err := txRepo.DeleteUserMFA(ctx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyMFA completes a login started with Login by exchanging the MFA token
+// and a TOTP or recovery code for a token pair. Wrong codes count towards the
+// same lockout as wrong passwords.
+func (s *AuthService) VerifyMFA(ctx context.Context, mfaToken, code string, client ClientInfo) (*models.AuthResponse, error) {
+	user, err := s.mfaPendingUser(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.throttle.Check(ctx, user.Email, client.IP); err != nil {
+		return nil, err
+	}
+
+	if err := checkMFACode(ctx, s.repo, user.ID, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if err := s.throttle.RecordFailure(ctx, user.Email, client.IP, &user); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	return s.finishLogin(ctx, user, client)
+}
+
+// mfaPendingUser returns the user an MFA token from Login was issued to.
+func (s *AuthService) mfaPendingUser(ctx context.Context, mfaToken string) (sqlc.User, error) {
+	claims := &utils.Claims{}
+	if err := utils.ParseClaims(mfaToken, claims, s.keys); err != nil || claims.TokenUse != utils.TokenUseMFAPending {
+		return sqlc.User{}, ErrInvalidMFAToken
+	}
+
+	id, err := utils.ParseUUID(claims.UserID)
+	if err != nil {
+		return sqlc.User{}, ErrInvalidMFAToken
+	}
+
+	user, err := s.repo.GetUserByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.User{}, ErrInvalidMFAToken
+		}
+		return sqlc.User{}, err
+	}
+
+	return user, nil
+}
+
+// mfaMethods lists the second factors a user can complete a login with:
+// "totp" once enrollment is confirmed, and "passkey" when a passkey is
+// registered. It is empty when MFA is off.
+func (s *AuthService) mfaMethods(ctx context.Context, userID pgtype.UUID) ([]string, error) {
+	var methods []string
+
+	mfa, err := s.repo.GetUserMFA(ctx, userID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if err == nil && mfa.EnabledAt.Valid {
+		methods = append(methods, MFAMethodTOTP)
+	}
+
+	passkeys, err := s.repo.CountUserWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if passkeys > 0 {
+		methods = append(methods, MFAMethodPasskey)
+	}
+
+	return methods, nil
+}
+
+// checkMFACode accepts either a TOTP code for a time step that has not been
+// used yet, or an unused recovery code. Both are consumed on success.
+func checkMFACode(ctx context.Context, repo *repository.Repository, userID pgtype.UUID, code string) error {
+	mfa, err := repo.GetUserMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrMFANotEnrolled
+		}
+		return err
+	}
+	if !mfa.EnabledAt.Valid {
+		return ErrMFANotEnrolled
+	}
+
+	code = strings.TrimSpace(code)
+
+	if step, ok := utils.ValidateTOTP(mfa.TotpSecret, code, time.Now()); ok {
+		rows, err := repo.UseTOTPStep(ctx, sqlc.UseTOTPStepParams{UserID: userID, LastUsedStep: step})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	rows, err := repo.UseRecoveryCode(ctx, sqlc.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: utils.HashToken(normalizeRecoveryCode(code)),
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, repo *repository.Repository, userID pgtype.UUID) ([]string, error) {
+	if err := repo.DeleteUserRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		err = repo.CreateRecoveryCode(ctx, sqlc.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: utils.HashToken(normalizeRecoveryCode(code)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to store recovery code: %w", err)
+		}
+		codes[i] = code
+	}
+
+	return codes, nil
+}
+
+// generateRecoveryCode returns a code like "k3j9d-q2m8x" (50 bits).
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	s := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return s[:5] + "-" + s[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ochko-b/goapp/internal/mailer"
+)
+
+// Notifier delivers account lifecycle messages to users.
+type Notifier interface {
+	SendPasswordReset(ctx context.Context, to, resetURL string, expiresIn time.Duration) error
+	SendEmailVerification(ctx context.Context, to, verifyURL string, expiresIn time.Duration) error
+	SendAccountLocked(ctx context.Context, to, unlockURL string, lockedFor time.Duration) error
+	SendPasswordChanged(ctx context.Context, to string) error
+	SendEmailChangeConfirmation(ctx context.Context, to, confirmURL string, expiresIn time.Duration) error
+	SendEmailChanged(ctx context.Context, to, newEmail string) error
+	SendMagicLink(ctx context.Context, to, loginURL string, expiresIn time.Duration) error
+	SendInvitation(ctx context.Context, to, acceptURL string, expiresIn time.Duration) error
+}
+
+// mailTimeout bounds one background delivery, so a stalled mail server
+// cannot hold on to a goroutine per email.
+const mailTimeout = 30 * time.Second
+
+// sendInBackground runs send without holding up the request, with its own
+// deadline as the request's context ends with the response. Failures are
+// logged as failing to send what.
+func sendInBackground(what string, send func(ctx context.Context) error) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		if err := send(ctx); err != nil {
+			log.Printf("failed to send %s: %v", what, err)
+		}
+	}()
+}
+
+// MailNotifier renders the embedded email templates and hands them to a
+// mailer backend.
+type MailNotifier struct {
+	mailer    mailer.Mailer
+	templates *mailer.Templates
+}
+
+func NewMailNotifier(m mailer.Mailer, templates *mailer.Templates) *MailNotifier {
+	return &MailNotifier{
+		mailer:    m,
+		templates: templates,
+	}
+}
+
+type linkEmailData struct {
+	Email     string
+	URL       string
+	ExpiresIn string
+}
+
+func (n *MailNotifier) SendPasswordReset(ctx context.Context, to, resetURL string, expiresIn time.Duration) error {
+	return n.send(ctx, "password_reset", to, linkEmailData{
+		Email:     to,
+		URL:       resetURL,
+		ExpiresIn: humanizeDuration(expiresIn),
+	})
+}
+
+func (n *MailNotifier) SendEmailVerification(ctx context.Context, to, verifyURL string, expiresIn time.Duration) error {
+	return n.send(ctx, "email_verification", to, linkEmailData{
+		Email:     to,
+		URL:       verifyURL,
+		ExpiresIn: humanizeDuration(expiresIn),
+	})
+}
+
+type lockoutEmailData struct {
+	Email     string
+	URL       string
+	LockedFor string
+}
+
+func (n *MailNotifier) SendAccountLocked(ctx context.Context, to, unlockURL string, lockedFor time.Duration) error {
+	return n.send(ctx, "account_locked", to, lockoutEmailData{
+		Email:     to,
+		URL:       unlockURL,
+		LockedFor: humanizeDuration(lockedFor),
+	})
+}
+
+type noticeEmailData struct {
+	Email string
+}
+
+func (n *MailNotifier) SendPasswordChanged(ctx context.Context, to string) error {
+	return n.send(ctx, "password_changed", to, noticeEmailData{Email: to})
+}
+
+func (n *MailNotifier) SendEmailChangeConfirmation(ctx context.Context, to, confirmURL string, expiresIn time.Duration) error {
+	return n.send(ctx, "email_change", to, linkEmailData{
+		Email:     to,
+		URL:       confirmURL,
+		ExpiresIn: humanizeDuration(expiresIn),
+	})
+}
+
+type emailChangeData struct {
+	Email    string
+	NewEmail string
+}
+
+// SendEmailChanged tells the old address where the account has moved.
+func (n *MailNotifier) SendEmailChanged(ctx context.Context, to, newEmail string) error {
+	return n.send(ctx, "email_changed", to, emailChangeData{
+		Email:    to,
+		NewEmail: newEmail,
+	})
+}
+
+func (n *MailNotifier) SendMagicLink(ctx context.Context, to, loginURL string, expiresIn time.Duration) error {
+	return n.send(ctx, "magic_link", to, linkEmailData{
+		Email:     to,
+		URL:       loginURL,
+		ExpiresIn: humanizeDuration(expiresIn),
+	})
+}
+
+func (n *MailNotifier) SendInvitation(ctx context.Context, to, acceptURL string, expiresIn time.Duration) error {
+	return n.send(ctx, "invitation", to, linkEmailData{
+		Email:     to,
+		URL:       acceptURL,
+		ExpiresIn: humanizeDuration(expiresIn),
+	})
+}
+
+func (n *MailNotifier) send(ctx context.Context, template, to string, data any) error {
+	msg, err := n.templates.Render(template, data)
+	if err != nil {
+		return err
+	}
+	msg.To = []string{to}
+
+	return n.mailer.Send(ctx, msg)
+}
+
+func humanizeDuration(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d >= time.Hour && d%time.Hour == 0:
+		return plural(int(d/time.Hour), "hour")
+	default:
+		return plural(int(d.Round(time.Minute)/time.Minute), "minute")
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,414 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/config"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/oauth"
+	"github.com/ochko-b/goapp/internal/repository"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/pkg/validator"
+)
+
+var (
+	ErrUnknownProvider       = apperror.NotFound("unknown_provider", "unknown sign-in provider")
+	ErrInvalidOAuthState     = apperror.Unauthorized("invalid_oauth_state", "invalid or expired sign-in request")
+	ErrOAuthFailed           = apperror.Unauthorized("oauth_failed", "sign-in with the provider failed")
+	ErrOAuthEmailRequired    = apperror.Unprocessable("oauth_email_required", "the provider did not share an email address")
+	ErrAccountLinkRequired   = apperror.Conflict("account_link_required", "an account with this email already exists; sign in and link the provider from your account settings")
+	ErrIdentityLinked        = apperror.Conflict("identity_linked", "this provider account is linked to another user")
+	ErrProviderAlreadyLinked = apperror.Conflict("provider_already_linked", "another account of this provider is already linked")
+	ErrIdentityNotFound      = apperror.NotFound("identity_not_found", "linked account not found")
+	ErrLastSignInMethod      = apperror.Conflict("last_sign_in_method", "cannot remove the only way to sign in; set a password first")
+)
+
+// OAuthService signs users in through external OpenID Connect providers and
+// manages the identities linked to their accounts.
+//
+// An unknown provider identity is matched to an existing account by email
+// only when the provider vouches for the address and the account has
+// verified it too. Otherwise someone could register a victim's email with a
+// password of their own and wait for the victim to arrive through SSO, or
+// use a provider that lets people claim any address. In every other case the
+// user has to sign in first and link the provider themselves.
+type OAuthService struct {
+	repo      *repository.Repository
+	auth      *AuthService
+	audit     *AuditService
+	cfg       config.OAuthConfig
+	providers map[string]*oauth.Provider
+}
+
+func NewOAuthService(repo *repository.Repository, auth *AuthService, audit *AuditService, cfg config.OAuthConfig) *OAuthService {
+	providers := make(map[string]*oauth.Provider, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		providers[p.Name] = oauth.NewProvider(p, nil)
+	}
+
+	return &OAuthService{
+		repo:      repo,
+		auth:      auth,
+		audit:     audit,
+		cfg:       cfg,
+		providers: providers,
+	}
+}
+
+// Providers lists the names of the configured providers.
+func (s *OAuthService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// AuthorizationURL starts a sign-in, or a link when userID is set, and
+// returns where to send the browser.
+func (s *OAuthService) AuthorizationURL(ctx context.Context, providerName, userID string) (string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+
+	var uid pgtype.UUID
+	if userID != "" {
+		var err error
+		if uid, err = utils.ParseUUID(userID); err != nil {
+			return "", err
+		}
+	}
+
+	state, err := oauth.NewVerifier()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oauth.NewVerifier()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := oauth.NewVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.repo.CreateOAuthState(ctx, sqlc.CreateOAuthStateParams{
+		StateHash:    utils.HashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		UserID:       uid,
+		ExpiresAt:    pgtype.Timestamptz{Time: time.Now().Add(s.cfg.StateExpiresIn), Valid: true},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store OAuth state: %w", err)
+	}
+
+	return authURL, nil
+}
+
+// Login completes a sign-in started by AuthorizationURL. Like a password
+// login it may ask for the second factor instead of returning tokens.
+func (s *OAuthService) Login(ctx context.Context, providerName string, req *models.OAuthCallbackRequest, client ClientInfo) (*models.AuthResponse, error) {
+	claims, err := s.authenticate(ctx, providerName, req, pgtype.UUID{})
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolveUser(ctx, providerName, claims, client.IP)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.auth.completeLogin(ctx, user, client)
+}
+
+// Link attaches the provider account to the signed-in user.
+func (s *OAuthService) Link(ctx context.Context, userID, providerName string, req *models.OAuthCallbackRequest, clientIP string) (*models.IdentityResponse, error) {
+	uid, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := s.authenticate(ctx, providerName, req, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := s.repo.GetUserIdentity(ctx, sqlc.GetUserIdentityParams{Provider: providerName, Subject: claims.Subject})
+	switch {
+	case err == nil && identity.UserID == uid:
+		return toIdentityResponse(identity), nil
+	case err == nil:
+		return nil, ErrIdentityLinked
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, err
+	}
+
+	identity, err = s.createIdentity(ctx, s.repo, uid, providerName, claims, clientIP)
+	if err != nil {
+		return nil, err
+	}
+	return toIdentityResponse(identity), nil
+}
+
+func (s *OAuthService) ListIdentities(ctx context.Context, userID string) ([]*models.IdentityResponse, error) {
+	uid, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	identities, err := s.repo.ListUserIdentities(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*models.IdentityResponse, 0, len(identities))
+	for _, identity := range identities {
+		responses = append(responses, toIdentityResponse(identity))
+	}
+	return responses, nil
+}
+
+// Unlink removes a linked identity, unless it is the account's only way in.
+func (s *OAuthService) Unlink(ctx context.Context, userID, identityID, clientIP string) error {
+	uid, err := utils.ParseUUID(userID)
+	if err != nil {
+		return err
+	}
+	iid, err := utils.ParseUUID(identityID)
+	if err != nil {
+		return ErrIdentityNotFound
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	user, err := txRepo.GetUserByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	rows, err := txRepo.DeleteUserIdentity(ctx, sqlc.DeleteUserIdentityParams{ID: iid, UserID: uid})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrIdentityNotFound
+	}
+
+	if user.PasswordHash == "" {
+		hasOther, err := hasPasswordlessSignIn(ctx, txRepo, uid)
+		if err != nil {
+			return err
+		}
+		if !hasOther {
+			return ErrLastSignInMethod
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		UserID:   uid,
+		Action:   AuditOAuthUnlinked,
+		IP:       clientIP,
+		Metadata: map[string]any{"identity_id": identityID},
+	})
+	return nil
+}
+
+// RunSweeper periodically deletes sign-in requests that were never completed.
+func (s *OAuthService) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.repo.DeleteExpiredOAuthStates(ctx); err != nil {
+				log.Printf("failed to sweep OAuth states: %v", err)
+			}
+		}
+	}
+}
+
+// authenticate consumes the state, redeems the code and verifies the ID
+// token. userID must match the user who started the request, which is
+// invalid for sign-ins.
+func (s *OAuthService) authenticate(ctx context.Context, providerName string, req *models.OAuthCallbackRequest, userID pgtype.UUID) (*oauth.IDTokenClaims, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	state, err := s.repo.ConsumeOAuthState(ctx, utils.HashToken(req.State))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidOAuthState
+		}
+		return nil, err
+	}
+	if state.Provider != providerName || state.UserID != userID {
+		return nil, ErrInvalidOAuthState
+	}
+
+	claims, err := provider.Authenticate(ctx, req.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOAuthFailed, err)
+	}
+	return claims, nil
+}
+
+// resolveUser finds or creates the account for a provider identity,
+// following the linking rules on OAuthService.
+func (s *OAuthService) resolveUser(ctx context.Context, providerName string, claims *oauth.IDTokenClaims, clientIP string) (sqlc.User, error) {
+	identity, err := s.repo.GetUserIdentity(ctx, sqlc.GetUserIdentityParams{Provider: providerName, Subject: claims.Subject})
+	if err == nil {
+		user, err := s.repo.GetUserByID(ctx, identity.UserID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return user, ErrInvalidCredentials
+			}
+			return user, err
+		}
+		if err := s.repo.TouchUserIdentity(ctx, sqlc.TouchUserIdentityParams{ID: identity.ID, Email: claims.Email}); err != nil {
+			return user, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return sqlc.User{}, err
+	}
+
+	if claims.Email == "" {
+		return sqlc.User{}, ErrOAuthEmailRequired
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, claims.Email)
+	switch {
+	case err == nil:
+		if !claims.EmailVerified || !user.EmailVerifiedAt.Valid {
+			return user, ErrAccountLinkRequired
+		}
+		if _, err := s.createIdentity(ctx, s.repo, user.ID, providerName, claims, clientIP); err != nil {
+			return user, err
+		}
+		return user, nil
+	case !errors.Is(err, pgx.ErrNoRows):
+		return user, err
+	}
+
+	return s.createUser(ctx, providerName, claims, clientIP)
+}
+
+// createUser registers a password-less account for a new provider identity.
+func (s *OAuthService) createUser(ctx context.Context, providerName string, claims *oauth.IDTokenClaims, clientIP string) (sqlc.User, error) {
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(strings.TrimSpace(claims.Name), " ")
+	}
+
+	var verifiedAt pgtype.Timestamptz
+	if claims.EmailVerified {
+		verifiedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return sqlc.User{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	user, err := txRepo.CreateExternalUser(ctx, sqlc.CreateExternalUserParams{
+		Email:           validator.NormalizeEmail(claims.Email),
+		FirstName:       firstName,
+		LastName:        lastName,
+		EmailVerifiedAt: verifiedAt,
+	})
+	if err != nil {
+		// Registered with the same email in the meantime
+		if isUniqueViolation(err) {
+			return user, ErrAccountLinkRequired
+		}
+		return user, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	if _, err := s.createIdentity(ctx, txRepo, user.ID, providerName, claims, clientIP); err != nil {
+		return user, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return user, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if !user.EmailVerifiedAt.Valid {
+		s.auth.sendVerification(user)
+	}
+
+	return user, nil
+}
+
+func (s *OAuthService) createIdentity(ctx context.Context, repo *repository.Repository, userID pgtype.UUID, providerName string, claims *oauth.IDTokenClaims, clientIP string) (sqlc.UserIdentity, error) {
+	identity, err := repo.CreateUserIdentity(ctx, sqlc.CreateUserIdentityParams{
+		UserID:   userID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return identity, ErrProviderAlreadyLinked
+		}
+		return identity, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		UserID:   userID,
+		Action:   AuditOAuthLinked,
+		IP:       clientIP,
+		Metadata: map[string]any{"provider": providerName, "subject": claims.Subject},
+	})
+	return identity, nil
+}
+
+func toIdentityResponse(identity sqlc.UserIdentity) *models.IdentityResponse {
+	response := &models.IdentityResponse{
+		ID:        identity.ID.String(),
+		Provider:  identity.Provider,
+		Email:     identity.Email,
+		CreatedAt: identity.CreatedAt.Time.Format(time.RFC3339),
+	}
+	if identity.LastLoginAt.Valid {
+		response.LastLoginAt = identity.LastLoginAt.Time.Format(time.RFC3339)
+	}
+	return response
+}
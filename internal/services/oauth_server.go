@@ -0,0 +1,858 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/config"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/oauth"
+	"github.com/ochko-b/goapp/internal/repository"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+var (
+	ErrOAuthClientNotFound = apperror.NotFound("oauth_client_not_found", "OAuth client not found")
+	ErrInvalidRedirectURI  = apperror.Validation("invalid_redirect_uri", "redirect URIs must be absolute without a fragment, and use https unless they point to this machine or a private-use scheme")
+	ErrRedirectURIRequired = apperror.Validation("redirect_uri_required", "the authorization_code grant needs at least one redirect URI")
+	ErrPublicClientGrant   = apperror.Validation("public_client_grant", "public clients cannot use the client_credentials grant")
+	ErrUnknownScope        = apperror.Validation("unknown_scope", "unknown scope")
+)
+
+// Grant types
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+)
+
+var supportedGrantTypes = []string{GrantAuthorizationCode, GrantRefreshToken, GrantClientCredentials}
+
+// OAuthServerService is an OAuth 2.0 authorization server for registered
+// client apps. Clients get the authorization code grant with mandatory PKCE,
+// refresh tokens and client credentials.
+//
+// Scopes are permission names, like API key scopes. A client may only ask for
+// the scopes it was registered with, and its tokens act as the user with the
+// granted scopes that the user still holds as permissions. Client credentials
+// tokens act as the user who registered the client.
+type OAuthServerService struct {
+	repo        *repository.Repository
+	jwtConfig   config.JWTConfig
+	cfg         config.AuthServerConfig
+	keys        *utils.KeyRing
+	revocations *RevocationService
+	audit       *AuditService
+}
+
+func NewOAuthServerService(repo *repository.Repository, jwtConfig config.JWTConfig, cfg config.AuthServerConfig, keys *utils.KeyRing, revocations *RevocationService, audit *AuditService) *OAuthServerService {
+	return &OAuthServerService{
+		repo:        repo,
+		jwtConfig:   jwtConfig,
+		cfg:         cfg,
+		keys:        keys,
+		revocations: revocations,
+		audit:       audit,
+	}
+}
+
+// CreateClient registers a client owned by the user. The secret of a
+// confidential client is returned only here.
+func (s *OAuthServerService) CreateClient(ctx context.Context, userID string, req *models.CreateOAuthClientRequest) (*models.CreatedOAuthClientResponse, error) {
+	ownerID, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, uri := range req.RedirectURIs {
+		if !validRedirectURI(uri) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidRedirectURI, uri)
+		}
+	}
+
+	grantTypes := []string{}
+	for _, grantType := range req.GrantTypes {
+		if !slices.Contains(grantTypes, grantType) {
+			grantTypes = append(grantTypes, grantType)
+		}
+	}
+	if slices.Contains(grantTypes, GrantAuthorizationCode) && len(req.RedirectURIs) == 0 {
+		return nil, ErrRedirectURIRequired
+	}
+	if req.Public && slices.Contains(grantTypes, GrantClientCredentials) {
+		return nil, ErrPublicClientGrant
+	}
+
+	permissions, err := s.repo.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scopes := []string{}
+	for _, scope := range req.Scopes {
+		known := slices.ContainsFunc(permissions, func(p sqlc.Permission) bool { return p.Name == scope })
+		if !known {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownScope, scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	var secret, secretHash string
+	if !req.Public {
+		if secret, err = utils.GenerateOpaqueToken(); err != nil {
+			return nil, err
+		}
+		secretHash = utils.HashToken(secret)
+	}
+
+	client, err := s.repo.CreateOAuthClient(ctx, sqlc.CreateOAuthClientParams{
+		OwnerID:      ownerID,
+		Name:         req.Name,
+		SecretHash:   secretHash,
+		RedirectUris: req.RedirectURIs,
+		GrantTypes:   grantTypes,
+		Scopes:       scopes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store OAuth client: %w", err)
+	}
+
+	return &models.CreatedOAuthClientResponse{
+		OAuthClientResponse: *toOAuthClientResponse(client),
+		ClientSecret:        secret,
+	}, nil
+}
+
+func (s *OAuthServerService) ListClients(ctx context.Context, userID string) ([]*models.OAuthClientResponse, error) {
+	ownerID, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	clients, err := s.repo.ListUserOAuthClients(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*models.OAuthClientResponse, 0, len(clients))
+	for _, client := range clients {
+		responses = append(responses, toOAuthClientResponse(client))
+	}
+	return responses, nil
+}
+
+func (s *OAuthServerService) GetClient(ctx context.Context, userID, clientID string) (*models.OAuthClientResponse, error) {
+	ownerID, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+	id, err := utils.ParseUUID(clientID)
+	if err != nil {
+		return nil, ErrOAuthClientNotFound
+	}
+
+	client, err := s.repo.GetUserOAuthClient(ctx, sqlc.GetUserOAuthClientParams{ID: id, OwnerID: ownerID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOAuthClientNotFound
+		}
+		return nil, err
+	}
+
+	return toOAuthClientResponse(client), nil
+}
+
+// DeleteClient removes the client along with its codes and refresh tokens.
+// Access tokens already issued stay valid until they expire.
+func (s *OAuthServerService) DeleteClient(ctx context.Context, userID, clientID string) error {
+	ownerID, err := utils.ParseUUID(userID)
+	if err != nil {
+		return err
+	}
+	id, err := utils.ParseUUID(clientID)
+	if err != nil {
+		return ErrOAuthClientNotFound
+	}
+
+	rows, err := s.repo.DeleteOAuthClient(ctx, sqlc.DeleteOAuthClientParams{ID: id, OwnerID: ownerID})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrOAuthClientNotFound
+	}
+	return nil
+}
+
+// authorizeRequest is an authorization request that passed validation.
+type authorizeRequest struct {
+	client sqlc.OauthClient
+	// redirectURI is where the response goes; requestedURI is what the
+	// client sent, which the token request must repeat.
+	redirectURI  string
+	requestedURI string
+	state        string
+	scopes       []string
+	challenge    string
+}
+
+// AuthorizeRedirect is the browser's entry point to the authorization code
+// flow. It returns the consent screen URL for a valid request, and the
+// client's redirect URI with an error for an invalid one. It only fails when
+// the client or redirect URI cannot be trusted with the response.
+func (s *OAuthServerService) AuthorizeRedirect(ctx context.Context, req *models.AuthorizeRequest) (string, error) {
+	ar, err := s.validateAuthorize(ctx, req)
+	if err != nil {
+		if ar == nil {
+			return "", err
+		}
+		return s.errorRedirect(ar, err), nil
+	}
+
+	params := url.Values{
+		"response_type":         {req.ResponseType},
+		"client_id":             {req.ClientID},
+		"code_challenge":        {req.CodeChallenge},
+		"code_challenge_method": {req.CodeChallengeMethod},
+	}
+	for key, value := range map[string]string{"redirect_uri": req.RedirectURI, "scope": req.Scope, "state": req.State} {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+	return withQuery(s.cfg.ConsentURL, params), nil
+}
+
+// Consent describes the request for the consent screen. Only scopes the user
+// holds are listed, since the client could not use the others.
+func (s *OAuthServerService) Consent(ctx context.Context, userID string, req *models.AuthorizeRequest) (*models.OAuthConsentResponse, error) {
+	ar, err := s.validateAuthorize(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	granted, err := s.grantableScopes(ctx, userID, ar.scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.repo.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scopes := []models.OAuthScope{}
+	for _, permission := range permissions {
+		if slices.Contains(granted, permission.Name) {
+			scopes = append(scopes, models.OAuthScope{Name: permission.Name, Description: permission.Description})
+		}
+	}
+
+	return &models.OAuthConsentResponse{
+		ClientID:   ar.client.ID.String(),
+		ClientName: ar.client.Name,
+		Scopes:     scopes,
+	}, nil
+}
+
+// Decide records the user's answer on the consent screen and returns where to
+// send the browser: back to the client with an authorization code, or with
+// access_denied.
+func (s *OAuthServerService) Decide(ctx context.Context, userID string, req *models.AuthorizeDecisionRequest, clientIP string) (*models.OAuthRedirectResponse, error) {
+	ar, err := s.validateAuthorize(ctx, &req.AuthorizeRequest)
+	if err != nil {
+		if ar == nil {
+			return nil, err
+		}
+		return &models.OAuthRedirectResponse{RedirectURI: s.errorRedirect(ar, err)}, nil
+	}
+
+	if !req.Approve {
+		denied := &oauth.Error{Code: oauth.ErrorAccessDenied, Description: "the user denied the request"}
+		return &models.OAuthRedirectResponse{RedirectURI: s.errorRedirect(ar, denied)}, nil
+	}
+
+	uid, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err := s.grantableScopes(ctx, userID, ar.scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.CreateOAuthAuthorizationCode(ctx, sqlc.CreateOAuthAuthorizationCodeParams{
+		CodeHash:      utils.HashToken(code),
+		ClientID:      ar.client.ID,
+		UserID:        uid,
+		RedirectUri:   ar.requestedURI,
+		Scopes:        scopes,
+		CodeChallenge: ar.challenge,
+		ExpiresAt:     pgtype.Timestamptz{Time: time.Now().Add(s.cfg.CodeExpiresIn), Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store authorization code: %w", err)
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		UserID:   uid,
+		Action:   AuditOAuthConsent,
+		IP:       clientIP,
+		Metadata: map[string]any{"client_id": ar.client.ID.String(), "scopes": scopes},
+	})
+
+	params := url.Values{"code": {code}, "iss": {s.cfg.Issuer}}
+	if ar.state != "" {
+		params.Set("state", ar.state)
+	}
+	return &models.OAuthRedirectResponse{RedirectURI: withQuery(ar.redirectURI, params)}, nil
+}
+
+// validateAuthorize checks an authorization request. Errors about the client
+// or the redirect URI come back without a request: they must not be sent to
+// a redirect URI that has not been verified. Other errors come back with the
+// request, so they can be reported to the client by redirect.
+func (s *OAuthServerService) validateAuthorize(ctx context.Context, req *models.AuthorizeRequest) (*authorizeRequest, error) {
+	id, err := utils.ParseUUID(req.ClientID)
+	if err != nil {
+		return nil, ErrOAuthClientNotFound
+	}
+	client, err := s.repo.GetOAuthClient(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOAuthClientNotFound
+		}
+		return nil, err
+	}
+
+	// Without a redirect_uri the response goes to the only registered one
+	redirectURI := req.RedirectURI
+	if redirectURI == "" && len(client.RedirectUris) == 1 {
+		redirectURI = client.RedirectUris[0]
+	}
+	if !slices.Contains(client.RedirectUris, redirectURI) {
+		return nil, &oauth.Error{Code: oauth.ErrorInvalidRequest, Description: "redirect_uri is not registered for this client"}
+	}
+
+	ar := &authorizeRequest{
+		client:       client,
+		redirectURI:  redirectURI,
+		requestedURI: req.RedirectURI,
+		state:        req.State,
+		challenge:    req.CodeChallenge,
+	}
+
+	switch {
+	case req.ResponseType != "code":
+		return ar, &oauth.Error{Code: oauth.ErrorUnsupportedResponseType, Description: "only the code response type is supported"}
+	case !slices.Contains(client.GrantTypes, GrantAuthorizationCode):
+		return ar, &oauth.Error{Code: oauth.ErrorUnauthorizedClient, Description: "client may not use the authorization code grant"}
+	case req.CodeChallenge == "" || req.CodeChallengeMethod != "S256":
+		return ar, &oauth.Error{Code: oauth.ErrorInvalidRequest, Description: "PKCE with the S256 method is required"}
+	}
+
+	if ar.scopes, err = requestedScopes(req.Scope, client.Scopes); err != nil {
+		return ar, err
+	}
+	return ar, nil
+}
+
+// Token handles a token request for any supported grant type.
+func (s *OAuthServerService) Token(ctx context.Context, req *models.TokenRequest, info ClientInfo) (*models.OAuthTokenResponse, error) {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case req.GrantType == "":
+		return nil, &oauth.Error{Code: oauth.ErrorInvalidRequest, Description: "grant_type is required"}
+	case !slices.Contains(supportedGrantTypes, req.GrantType):
+		return nil, &oauth.Error{Code: oauth.ErrorUnsupportedGrantType}
+	case !slices.Contains(client.GrantTypes, req.GrantType):
+		return nil, &oauth.Error{Code: oauth.ErrorUnauthorizedClient, Description: "client may not use this grant type"}
+	}
+
+	switch req.GrantType {
+	case GrantAuthorizationCode:
+		return s.exchangeCode(ctx, client, req, info)
+	case GrantRefreshToken:
+		return s.refresh(ctx, client, req, info)
+	default:
+		return s.clientCredentials(ctx, client, req)
+	}
+}
+
+func (s *OAuthServerService) exchangeCode(ctx context.Context, client sqlc.OauthClient, req *models.TokenRequest, info ClientInfo) (*models.OAuthTokenResponse, error) {
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, &oauth.Error{Code: oauth.ErrorInvalidRequest, Description: "code and code_verifier are required"}
+	}
+
+	// Codes are deleted as they are redeemed, so each works once
+	code, err := s.repo.ConsumeOAuthAuthorizationCode(ctx, utils.HashToken(req.Code))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &oauth.Error{Code: oauth.ErrorInvalidGrant, Description: "authorization code is invalid or expired"}
+		}
+		return nil, err
+	}
+	if code.ClientID != client.ID || code.RedirectUri != req.RedirectURI {
+		return nil, &oauth.Error{Code: oauth.ErrorInvalidGrant, Description: "authorization code was issued for another client or redirect_uri"}
+	}
+	if subtle.ConstantTimeCompare([]byte(oauth.S256Challenge(req.CodeVerifier)), []byte(code.CodeChallenge)) != 1 {
+		return nil, &oauth.Error{Code: oauth.ErrorInvalidGrant, Description: "PKCE verification failed"}
+	}
+
+	user, err := s.repo.GetUserByID(ctx, code.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &oauth.Error{Code: oauth.ErrorInvalidGrant}
+		}
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, s.repo, client, user, code.Scopes, newFamilyID(), info)
+}
+
+// refresh rotates a client's refresh token the same way first-party refresh
+// tokens are rotated, including revoking the family on reuse.
+func (s *OAuthServerService) refresh(ctx context.Context, client sqlc.OauthClient, req *models.TokenRequest, info ClientInfo) (*models.OAuthTokenResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, &oauth.Error{Code: oauth.ErrorInvalidRequest, Description: "refresh_token is required"}
+	}
+	invalidGrant := &oauth.Error{Code: oauth.ErrorInvalidGrant, Description: "refresh token is invalid or expired"}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	stored, err := txRepo.GetRefreshTokenByHash(ctx, utils.HashToken(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, invalidGrant
+		}
+		return nil, err
+	}
+	if stored.ClientID != client.ID {
+		return nil, invalidGrant
+	}
+
+	if stored.UsedAt.Valid || stored.RevokedAt.Valid {
+		return nil, s.revokeFamily(ctx, tx, txRepo, stored.FamilyID)
+	}
+	if time.Now().After(stored.ExpiresAt.Time) {
+		return nil, invalidGrant
+	}
+
+	// A refresh may narrow the scopes but never widen them
+	scopes, err := requestedScopes(req.Scope, stored.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := txRepo.MarkRefreshTokenUsed(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, s.revokeFamily(ctx, tx, txRepo, stored.FamilyID)
+	}
+
+	user, err := txRepo.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, invalidGrant
+		}
+		return nil, err
+	}
+
+	tokens, err := s.issueTokens(ctx, txRepo, client, user, scopes, stored.FamilyID, info)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func (s *OAuthServerService) clientCredentials(ctx context.Context, client sqlc.OauthClient, req *models.TokenRequest) (*models.OAuthTokenResponse, error) {
+	if client.SecretHash == "" {
+		return nil, &oauth.Error{Code: oauth.ErrorUnauthorizedClient, Description: "public clients cannot use client credentials"}
+	}
+
+	scopes, err := requestedScopes(req.Scope, client.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := s.repo.GetUserByID(ctx, client.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// No refresh token: the client can always ask again (RFC 6749 section 4.4.3)
+	return s.issueTokens(ctx, s.repo, client, owner, scopes, pgtype.UUID{}, ClientInfo{})
+}
+
+// Introspect reports whether a token is active (RFC 7662). Any confidential
+// client may introspect access tokens, so resource servers can use it;
+// refresh tokens are only described to the client they were issued to.
+func (s *OAuthServerService) Introspect(ctx context.Context, req *models.TokenActionRequest) (*models.IntrospectionResponse, error) {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if client.SecretHash == "" {
+		return nil, &oauth.Error{Code: oauth.ErrorInvalidClient, Description: "public clients cannot introspect tokens"}
+	}
+
+	inactive := &models.IntrospectionResponse{}
+
+	if claims, err := utils.ValidateToken(req.Token, s.keys); err == nil {
+		if claims.TokenUse != utils.TokenUseAccess && claims.TokenUse != utils.TokenUseOAuth {
+			return inactive, nil
+		}
+		revoked, err := s.revocations.IsRevoked(ctx, claims)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return inactive, nil
+		}
+
+		return &models.IntrospectionResponse{
+			Active:    true,
+			Scope:     claims.Scope,
+			ClientID:  claims.ClientID,
+			Username:  claims.Email,
+			TokenType: "Bearer",
+			ExpiresAt: claims.ExpiresAt.Unix(),
+			IssuedAt:  claims.IssuedAt.Unix(),
+			Subject:   claims.UserID,
+			JTI:       claims.ID,
+		}, nil
+	}
+
+	stored, err := s.repo.GetRefreshTokenByHash(ctx, utils.HashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return inactive, nil
+		}
+		return nil, err
+	}
+	if stored.ClientID != client.ID || stored.UsedAt.Valid || stored.RevokedAt.Valid || time.Now().After(stored.ExpiresAt.Time) {
+		return inactive, nil
+	}
+
+	return &models.IntrospectionResponse{
+		Active:    true,
+		Scope:     strings.Join(stored.Scopes, " "),
+		ClientID:  client.ID.String(),
+		ExpiresAt: stored.ExpiresAt.Time.Unix(),
+		IssuedAt:  stored.CreatedAt.Time.Unix(),
+		Subject:   stored.UserID.String(),
+	}, nil
+}
+
+// Revoke ends the session of a refresh token, revoking the rest of its family
+// and its access tokens, or revokes an access token issued to the calling
+// client (RFC 7009). Unknown tokens and tokens of
+// other clients are ignored, as the RFC asks.
+func (s *OAuthServerService) Revoke(ctx context.Context, req *models.TokenActionRequest) error {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	stored, err := s.repo.GetRefreshTokenByHash(ctx, utils.HashToken(req.Token))
+	switch {
+	case err == nil:
+		if stored.ClientID != client.ID {
+			return nil
+		}
+		return s.revocations.RevokeSession(ctx, stored.FamilyID)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return err
+	}
+
+	claims, err := utils.ValidateToken(req.Token, s.keys)
+	if err != nil || claims.TokenUse != utils.TokenUseOAuth || claims.ClientID != client.ID.String() {
+		return nil
+	}
+	return s.revocations.RevokeToken(ctx, claims)
+}
+
+// Metadata is the authorization server's discovery document (RFC 8414).
+func (s *OAuthServerService) Metadata() *models.AuthorizationServerMetadata {
+	issuer := strings.TrimSuffix(s.cfg.Issuer, "/")
+	return &models.AuthorizationServerMetadata{
+		Issuer:                            s.cfg.Issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		IntrospectionEndpoint:             issuer + "/oauth/introspect",
+		RevocationEndpoint:                issuer + "/oauth/revoke",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               supportedGrantTypes,
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		AuthorizationResponseIssSupported: true,
+	}
+}
+
+// RunSweeper periodically deletes authorization codes that were never redeemed.
+func (s *OAuthServerService) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.repo.DeleteExpiredOAuthAuthorizationCodes(ctx); err != nil {
+				log.Printf("failed to sweep authorization codes: %v", err)
+			}
+		}
+	}
+}
+
+// authenticateClient checks the client's credentials. Public clients only
+// identify themselves; confidential ones must present their secret.
+func (s *OAuthServerService) authenticateClient(ctx context.Context, clientID, secret string) (sqlc.OauthClient, error) {
+	invalidClient := &oauth.Error{Code: oauth.ErrorInvalidClient, Description: "client authentication failed"}
+
+	id, err := utils.ParseUUID(clientID)
+	if err != nil {
+		return sqlc.OauthClient{}, invalidClient
+	}
+	client, err := s.repo.GetOAuthClient(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return client, invalidClient
+		}
+		return client, err
+	}
+
+	if client.SecretHash == "" {
+		if secret != "" {
+			return client, invalidClient
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return client, invalidClient
+	}
+	return client, nil
+}
+
+// issueTokens issues an access token and, when familyID is set and the client
+// may refresh, a refresh token in that family, whose session it records. The
+// refresh token keeps the consented scopes, so permissions the user regains
+// apply on refresh.
+func (s *OAuthServerService) issueTokens(ctx context.Context, repo *repository.Repository, client sqlc.OauthClient, user sqlc.User, scopes []string, familyID pgtype.UUID, info ClientInfo) (*models.OAuthTokenResponse, error) {
+	permissions, err := repo.GetUserPermissionNames(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load permissions: %w", err)
+	}
+	granted := []string{}
+	for _, scope := range scopes {
+		if slices.Contains(permissions, scope) && slices.Contains(client.Scopes, scope) {
+			granted = append(granted, scope)
+		}
+	}
+
+	claims := utils.Claims{
+		UserID:        user.ID.String(),
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		TokenUse:      utils.TokenUseOAuth,
+		Permissions:   granted,
+		ClientID:      client.ID.String(),
+		Scope:         strings.Join(granted, " "),
+	}
+	if familyID.Valid {
+		claims.SessionID = familyID.String()
+	}
+
+	accessToken, err := utils.GenerateToken(claims, s.keys, s.jwtConfig.AccessExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.jwtConfig.AccessExpiresIn.Seconds()),
+		Scope:       claims.Scope,
+	}
+
+	if familyID.Valid && slices.Contains(client.GrantTypes, GrantRefreshToken) {
+		refreshToken, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			return nil, err
+		}
+
+		expiresAt := time.Now().Add(s.jwtConfig.RefreshExpiresIn)
+		_, err = repo.CreateClientRefreshToken(ctx, sqlc.CreateClientRefreshTokenParams{
+			UserID:    user.ID,
+			FamilyID:  familyID,
+			TokenHash: utils.HashToken(refreshToken),
+			ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+			ClientID:  client.ID,
+			Scopes:    scopes,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to store refresh token: %w", err)
+		}
+
+		if err := recordSession(ctx, repo, user.ID, client.ID, familyID, info, expiresAt); err != nil {
+			return nil, err
+		}
+		response.RefreshToken = refreshToken
+	}
+
+	return response, nil
+}
+
+func (s *OAuthServerService) revokeFamily(ctx context.Context, tx pgx.Tx, txRepo *repository.Repository, familyID pgtype.UUID) error {
+	if err := txRepo.RevokeRefreshTokenFamily(ctx, familyID); err != nil {
+		return err
+	}
+	if err := txRepo.RevokeSession(ctx, familyID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &oauth.Error{Code: oauth.ErrorInvalidGrant, Description: "refresh token reuse detected"}
+}
+
+// grantableScopes narrows the scopes to the permissions the user holds.
+func (s *OAuthServerService) grantableScopes(ctx context.Context, userID string, scopes []string) ([]string, error) {
+	uid, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.repo.GetUserPermissionNames(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	granted := []string{}
+	for _, scope := range scopes {
+		if slices.Contains(permissions, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	return granted, nil
+}
+
+func (s *OAuthServerService) errorRedirect(ar *authorizeRequest, err error) string {
+	var oauthErr *oauth.Error
+	if !errors.As(err, &oauthErr) {
+		log.Printf("authorization request failed: %v", err)
+		oauthErr = &oauth.Error{Code: "server_error"}
+	}
+
+	params := url.Values{"error": {oauthErr.Code}, "iss": {s.cfg.Issuer}}
+	if oauthErr.Description != "" {
+		params.Set("error_description", oauthErr.Description)
+	}
+	if ar.state != "" {
+		params.Set("state", ar.state)
+	}
+	return withQuery(ar.redirectURI, params)
+}
+
+// requestedScopes parses a space-delimited scope parameter, which must stay
+// within allowed. An empty parameter asks for everything allowed.
+func requestedScopes(scope string, allowed []string) ([]string, error) {
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		return allowed, nil
+	}
+
+	scopes := []string{}
+	for _, name := range requested {
+		if !slices.Contains(allowed, name) {
+			return nil, &oauth.Error{Code: oauth.ErrorInvalidScope, Description: "scope not allowed: " + name}
+		}
+		if !slices.Contains(scopes, name) {
+			scopes = append(scopes, name)
+		}
+	}
+	return scopes, nil
+}
+
+// validRedirectURI accepts https URIs, http on the loopback interface and the
+// private-use schemes of native apps (RFC 8252 section 7), which must contain
+// a dot, e.g. com.example.app:/callback.
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || strings.Contains(raw, "#") || u.User != nil {
+		return false
+	}
+
+	switch u.Scheme {
+	case "https":
+		return u.Host != ""
+	case "http":
+		host := u.Hostname()
+		return host == "localhost" || net.ParseIP(host).IsLoopback()
+	default:
+		return strings.Contains(u.Scheme, ".")
+	}
+}
+
+// withQuery adds params to the URI, keeping any query it already has.
+func withQuery(uri string, params url.Values) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func toOAuthClientResponse(client sqlc.OauthClient) *models.OAuthClientResponse {
+	return &models.OAuthClientResponse{
+		ClientID:     client.ID.String(),
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		GrantTypes:   client.GrantTypes,
+		Scopes:       client.Scopes,
+		Public:       client.SecretHash == "",
+		CreatedAt:    client.CreatedAt.Time.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/repository"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+var (
+	ErrOrganizationNotFound  = apperror.NotFound("organization_not_found", "organization not found")
+	ErrOrganizationSlugTaken = apperror.Conflict("organization_slug_taken", "organization slug is already taken")
+	ErrInvalidSlug           = apperror.Validation("invalid_slug", "slug may only contain lowercase letters, digits and hyphens")
+	ErrNotOrganizationMember = apperror.Forbidden("not_organization_member", "not a member of this organization")
+	ErrAlreadyMember         = apperror.Conflict("already_member", "user is already a member of this organization")
+	ErrInsufficientOrgRole   = apperror.Forbidden("insufficient_org_role", "insufficient organization role")
+	ErrLastOwnerRemoval      = apperror.Conflict("last_owner_removal", "an organization must keep at least one owner")
+	ErrMemberNotFound        = apperror.NotFound("member_not_found", "member not found")
+)
+
+var (
+	slugPattern     = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	slugInvalidRune = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+type OrganizationService struct {
+	repo *repository.Repository
+}
+
+func NewOrganizationService(repo *repository.Repository) *OrganizationService {
+	return &OrganizationService{
+		repo: repo,
+	}
+}
+
+// Create makes a new organization with the user as its first owner.
+func (s *OrganizationService) Create(ctx context.Context, userID string, req *models.CreateOrganizationRequest) (*models.OrganizationResponse, error) {
+	uid, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	slug := req.Slug
+	if slug == "" {
+		slug = slugify(req.Name)
+	}
+	if !slugPattern.MatchString(slug) {
+		return nil, ErrInvalidSlug
+	}
+
+	orgID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+
+	var org sqlc.Organization
+	err = s.repo.InTenant(ctx, orgID, uid, func(r *repository.TenantRepository) error {
+		var err error
+		org, err = r.CreateOrganization(ctx, req.Name, slug)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrOrganizationSlugTaken
+			}
+			return err
+		}
+
+		_, err = r.AddMember(ctx, uid, models.OrgRoleOwner)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toOrganizationResponse(org, models.OrgRoleOwner), nil
+}
+
+// ListForUser lists every organization the user belongs to, with their role.
+func (s *OrganizationService) ListForUser(ctx context.Context, userID string) ([]*models.OrganizationResponse, error) {
+	uid, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []*models.OrganizationResponse{}
+	err = s.repo.InTenant(ctx, pgtype.UUID{}, uid, func(r *repository.TenantRepository) error {
+		rows, err := r.ListUserOrganizations(ctx)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			responses = append(responses, toOrganizationResponse(sqlc.Organization{
+				ID:        row.ID,
+				Name:      row.Name,
+				Slug:      row.Slug,
+				CreatedAt: row.CreatedAt,
+				UpdatedAt: row.UpdatedAt,
+			}, row.Role))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return responses, nil
+}
+
+// MembershipRole returns the user's role in the organization, or an empty
+// string if they are not a member.
+func (s *OrganizationService) MembershipRole(ctx context.Context, orgID, userID string) (string, error) {
+	var role string
+	err := s.inTenant(ctx, orgID, userID, func(r *repository.TenantRepository) error {
+		member, err := r.GetMember(ctx, r.UserID)
+		if err != nil {
+			return err
+		}
+		role = member.Role
+		return nil
+	})
+	if errors.Is(err, ErrNotOrganizationMember) {
+		return "", nil
+	}
+	return role, err
+}
+
+func (s *OrganizationService) Get(ctx context.Context, orgID, userID string) (*models.OrganizationResponse, error) {
+	var response *models.OrganizationResponse
+	err := s.inTenant(ctx, orgID, userID, func(r *repository.TenantRepository) error {
+		member, err := r.GetMember(ctx, r.UserID)
+		if err != nil {
+			return err
+		}
+		org, err := r.GetOrganization(ctx)
+		if err != nil {
+			return err
+		}
+		response = toOrganizationResponse(org, member.Role)
+		return nil
+	})
+	return response, err
+}
+
+func (s *OrganizationService) Update(ctx context.Context, orgID, userID string, req *models.UpdateOrganizationRequest) (*models.OrganizationResponse, error) {
+	var response *models.OrganizationResponse
+	err := s.inTenant(ctx, orgID, userID, func(r *repository.TenantRepository) error {
+		actor, err := requireOrgRole(ctx, r, models.OrgRoleAdmin)
+		if err != nil {
+			return err
+		}
+		org, err := r.UpdateOrganization(ctx, req.Name)
+		if err != nil {
+			return err
+		}
+		response = toOrganizationResponse(org, actor.Role)
+		return nil
+	})
+	return response, err
+}
+
+func (s *OrganizationService) ListMembers(ctx context.Context, orgID, userID string) ([]*models.MemberResponse, error) {
+	members := []*models.MemberResponse{}
+	err := s.inTenant(ctx, orgID, userID, func(r *repository.TenantRepository) error {
+		if _, err := requireOrgRole(ctx, r, models.OrgRoleMember); err != nil {
+			return err
+		}
+		rows, err := r.ListMembers(ctx)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			members = append(members, &models.MemberResponse{
+				UserID:    row.UserID.String(),
+				Email:     row.Email,
+				FirstName: row.FirstName,
+				LastName:  row.LastName,
+				Role:      row.Role,
+				JoinedAt:  row.CreatedAt.Time.Format(time.RFC3339),
+			})
+		}
+		return nil
+	})
+	return members, err
+}
+
+// AddMember adds an existing user to the organization. Admins may add members
+// and admins; only owners may add owners.
+func (s *OrganizationService) AddMember(ctx context.Context, orgID, userID string, req *models.AddMemberRequest) (*models.MemberResponse, error) {
+	var response *models.MemberResponse
+	err := s.inTenant(ctx, orgID, userID, func(r *repository.TenantRepository) error {
+		actor, err := requireOrgRole(ctx, r, models.OrgRoleAdmin)
+		if err != nil {
+			return err
+		}
+		if !models.OrgRoleAtLeast(actor.Role, req.Role) {
+			return ErrInsufficientOrgRole
+		}
+
+		user, err := r.GetUserByEmail(ctx, req.Email)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+
+		member, err := r.AddMember(ctx, user.ID, req.Role)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrAlreadyMember
+			}
+			return err
+		}
+
+		response = &models.MemberResponse{
+			UserID:    user.ID.String(),
+			Email:     user.Email,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Role:      member.Role,
+			JoinedAt:  member.CreatedAt.Time.Format(time.RFC3339),
+		}
+		return nil
+	})
+	return response, err
+}
+
+// UpdateMemberRole changes a member's role. Admins may only move people
+// between member and admin; owners may change anything, but the last owner
+// cannot step down.
+func (s *OrganizationService) UpdateMemberRole(ctx context.Context, orgID, userID, memberID string, role string) error {
+	mid, err := utils.ParseUUID(memberID)
+	if err != nil {
+		return err
+	}
+
+	return s.inTenant(ctx, orgID, userID, func(r *repository.TenantRepository) error {
+		actor, err := requireOrgRole(ctx, r, models.OrgRoleAdmin)
+		if err != nil {
+			return err
+		}
+
+		target, err := getMember(ctx, r, mid)
+		if err != nil {
+			return err
+		}
+		if !models.OrgRoleAtLeast(actor.Role, target.Role) || !models.OrgRoleAtLeast(actor.Role, role) {
+			return ErrInsufficientOrgRole
+		}
+
+		if target.Role == models.OrgRoleOwner && role != models.OrgRoleOwner {
+			if err := ensureAnotherOwner(ctx, r); err != nil {
+				return err
+			}
+		}
+
+		_, err = r.UpdateMemberRole(ctx, mid, role)
+		return err
+	})
+}
+
+// RemoveMember removes someone from the organization. Anyone may leave;
+// removing others needs a role at least as high as theirs.
+func (s *OrganizationService) RemoveMember(ctx context.Context, orgID, userID, memberID string) error {
+	mid, err := utils.ParseUUID(memberID)
+	if err != nil {
+		return err
+	}
+
+	return s.inTenant(ctx, orgID, userID, func(r *repository.TenantRepository) error {
+		target, err := getMember(ctx, r, mid)
+		if err != nil {
+			return err
+		}
+
+		if mid != r.UserID {
+			actor, err := requireOrgRole(ctx, r, models.OrgRoleAdmin)
+			if err != nil {
+				return err
+			}
+			if !models.OrgRoleAtLeast(actor.Role, target.Role) {
+				return ErrInsufficientOrgRole
+			}
+		}
+
+		if target.Role == models.OrgRoleOwner {
+			if err := ensureAnotherOwner(ctx, r); err != nil {
+				return err
+			}
+		}
+
+		if _, err := r.RemoveMember(ctx, mid); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func (s *OrganizationService) inTenant(ctx context.Context, orgID, userID string, fn func(*repository.TenantRepository) error) error {
+	oid, err := utils.ParseUUID(orgID)
+	if err != nil {
+		return ErrOrganizationNotFound
+	}
+	uid, err := utils.ParseUUID(userID)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.InTenant(ctx, oid, uid, fn)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotOrganizationMember
+	}
+	return err
+}
+
+// requireOrgRole loads the acting user's membership and checks its role.
+func requireOrgRole(ctx context.Context, r *repository.TenantRepository, min string) (sqlc.OrganizationMember, error) {
+	actor, err := r.GetMember(ctx, r.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return actor, ErrNotOrganizationMember
+		}
+		return actor, err
+	}
+	if !models.OrgRoleAtLeast(actor.Role, min) {
+		return actor, ErrInsufficientOrgRole
+	}
+	return actor, nil
+}
+
+func getMember(ctx context.Context, r *repository.TenantRepository, userID pgtype.UUID) (sqlc.OrganizationMember, error) {
+	member, err := r.GetMember(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return member, ErrMemberNotFound
+		}
+		return member, err
+	}
+	return member, nil
+}
+
+func ensureAnotherOwner(ctx context.Context, r *repository.TenantRepository) error {
+	owners, err := r.CountOwners(ctx)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwnerRemoval
+	}
+	return nil
+}
+
+func slugify(name string) string {
+	return strings.Trim(slugInvalidRune.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func toOrganizationResponse(org sqlc.Organization, role string) *models.OrganizationResponse {
+	return &models.OrganizationResponse{
+		ID:        org.ID.String(),
+		Name:      org.Name,
+		Slug:      org.Slug,
+		Role:      role,
+		CreatedAt: org.CreatedAt.Time.Format(time.RFC3339),
+		UpdatedAt: org.UpdatedAt.Time.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,479 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/config"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/repository"
+	"github.com/ochko-b/goapp/internal/utils"
+	"github.com/ochko-b/goapp/internal/webauthn"
+)
+
+var (
+	ErrInvalidPasskey       = apperror.Unauthorized("invalid_passkey", "passkey could not be verified")
+	ErrPasskeyNotFound      = apperror.NotFound("passkey_not_found", "passkey not found")
+	ErrPasskeyAlreadyExists = apperror.Conflict("passkey_already_exists", "this passkey is already registered")
+)
+
+// Ceremonies a WebAuthn challenge is issued for
+const (
+	passkeyPurposeRegistration = "registration"
+	passkeyPurposeLogin        = "login"
+	passkeyPurposeMFA          = "mfa"
+)
+
+// passkeyChallengeGrace keeps a challenge a little longer than the browser
+// waits, so a response sent at the last moment is still accepted.
+const passkeyChallengeGrace = time.Minute
+
+// BeginPasskeyRegistration starts registering a passkey for the user. The
+// options are passed to navigator.credentials.create() as publicKey.
+func (s *AuthService) BeginPasskeyRegistration(ctx context.Context, userID string) (*webauthn.CreationOptions, error) {
+	id, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	existing, err := s.repo.ListUserWebAuthnCredentials(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list passkeys: %w", err)
+	}
+
+	challenge, err := s.createPasskeyChallenge(ctx, id, passkeyPurposeRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	entity := webauthn.UserEntity{
+		ID:          user.ID.Bytes[:],
+		Name:        user.Email,
+		DisplayName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+	}
+	return s.webauthn.CreationOptions(entity, challenge, credentialDescriptors(existing)), nil
+}
+
+// FinishPasskeyRegistration verifies the response to
+// BeginPasskeyRegistration and stores the new passkey. From then on it can
+// be used to sign in, and it is asked for as a second factor after a
+// password or social login.
+func (s *AuthService) FinishPasskeyRegistration(ctx context.Context, userID string, req *models.PasskeyRegisterRequest, clientIP string) (*models.PasskeyResponse, error) {
+	id, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.consumePasskeyChallenge(ctx, &req.Credential, passkeyPurposeRegistration, id)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := s.webauthn.VerifyRegistration(&req.Credential, challenge, false)
+	if err != nil {
+		return nil, passkeyError(err)
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+
+	stored, err := s.repo.CreateWebAuthnCredential(ctx, sqlc.CreateWebAuthnCredentialParams{
+		UserID:       id,
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    int64(credential.SignCount),
+		Transports:   credential.Transports,
+		Aaguid:       credential.AAGUID,
+		Name:         name,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrPasskeyAlreadyExists
+		}
+		return nil, fmt.Errorf("failed to store passkey: %w", err)
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		UserID:   id,
+		Action:   AuditPasskeyRegistered,
+		IP:       clientIP,
+		Metadata: map[string]any{"passkey_id": stored.ID.String()},
+	})
+
+	return toPasskeyResponse(stored), nil
+}
+
+// ListPasskeys returns the passkeys registered to the user.
+func (s *AuthService) ListPasskeys(ctx context.Context, userID string) ([]*models.PasskeyResponse, error) {
+	id, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := s.repo.ListUserWebAuthnCredentials(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list passkeys: %w", err)
+	}
+
+	responses := make([]*models.PasskeyResponse, 0, len(credentials))
+	for _, credential := range credentials {
+		responses = append(responses, toPasskeyResponse(credential))
+	}
+	return responses, nil
+}
+
+// DeletePasskey removes a passkey, unless it is the only way left to sign in.
+func (s *AuthService) DeletePasskey(ctx context.Context, userID, passkeyID, clientIP string) error {
+	uid, err := utils.ParseUUID(userID)
+	if err != nil {
+		return err
+	}
+	pid, err := utils.ParseUUID(passkeyID)
+	if err != nil {
+		return ErrPasskeyNotFound
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	user, err := txRepo.GetUserByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	rows, err := txRepo.DeleteUserWebAuthnCredential(ctx, sqlc.DeleteUserWebAuthnCredentialParams{ID: pid, UserID: uid})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrPasskeyNotFound
+	}
+
+	if user.PasswordHash == "" {
+		hasOther, err := hasPasswordlessSignIn(ctx, txRepo, uid)
+		if err != nil {
+			return err
+		}
+		if !hasOther {
+			return ErrLastSignInMethod
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		UserID:   uid,
+		Action:   AuditPasskeyRemoved,
+		IP:       clientIP,
+		Metadata: map[string]any{"passkey_id": pid.String()},
+	})
+
+	return nil
+}
+
+// BeginPasskeyLogin starts a passwordless sign-in. No credentials are listed,
+// so the authenticator offers whichever passkeys it holds for this site.
+func (s *AuthService) BeginPasskeyLogin(ctx context.Context) (*webauthn.RequestOptions, error) {
+	challenge, err := s.createPasskeyChallenge(ctx, pgtype.UUID{}, passkeyPurposeLogin)
+	if err != nil {
+		return nil, err
+	}
+	return s.webauthn.RequestOptions(challenge, nil, webauthn.VerificationRequired), nil
+}
+
+// FinishPasskeyLogin signs the user in with a passkey alone. User
+// verification is required, so the passkey stands for both a possession and
+// a knowledge or biometric factor, and no second factor is asked for.
+func (s *AuthService) FinishPasskeyLogin(ctx context.Context, req *models.PasskeyLoginRequest, client ClientInfo) (*models.AuthResponse, error) {
+	challenge, err := s.consumePasskeyChallenge(ctx, &req.Credential, passkeyPurposeLogin, pgtype.UUID{})
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.repo.GetWebAuthnCredentialByCredentialID(ctx, req.Credential.RawID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidPasskey
+		}
+		return nil, err
+	}
+
+	// A discoverable credential returns the user handle it was created with
+	handle := req.Credential.Response.UserHandle
+	if len(handle) > 0 && !bytes.Equal(handle, stored.UserID.Bytes[:]) {
+		return nil, ErrInvalidPasskey
+	}
+
+	if err := s.verifyPasskeyAssertion(ctx, &req.Credential, challenge, stored, true); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidPasskey
+		}
+		return nil, err
+	}
+	if !user.IsActive.Bool {
+		return nil, ErrInvalidPasskey
+	}
+	if s.authConfig.EmailVerificationMode == config.EmailVerificationLogin && !user.EmailVerifiedAt.Valid {
+		return nil, ErrEmailNotVerified
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		UserID:   user.ID,
+		Action:   AuditPasskeyLogin,
+		IP:       client.IP,
+		Metadata: map[string]any{"passkey_id": stored.ID.String()},
+	})
+
+	return s.finishLogin(ctx, user, client)
+}
+
+// BeginPasskeyMFA starts the passkey second factor of a login that returned
+// an MFA token. Only the user's own passkeys are allowed.
+func (s *AuthService) BeginPasskeyMFA(ctx context.Context, mfaToken string) (*webauthn.RequestOptions, error) {
+	user, err := s.mfaPendingUser(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := s.repo.ListUserWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list passkeys: %w", err)
+	}
+	if len(credentials) == 0 {
+		return nil, ErrMFANotEnrolled
+	}
+
+	challenge, err := s.createPasskeyChallenge(ctx, user.ID, passkeyPurposeMFA)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.webauthn.RequestOptions(challenge, credentialDescriptors(credentials), webauthn.VerificationPreferred), nil
+}
+
+// FinishPasskeyMFA completes a login with the response to BeginPasskeyMFA.
+// As with VerifyMFA, failures count towards the login lockout.
+func (s *AuthService) FinishPasskeyMFA(ctx context.Context, req *models.PasskeyMFARequest, client ClientInfo) (*models.AuthResponse, error) {
+	user, err := s.mfaPendingUser(ctx, req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.throttle.Check(ctx, user.Email, client.IP); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkPasskeyMFA(ctx, user, &req.Credential); err != nil {
+		if errors.Is(err, ErrInvalidPasskey) {
+			if err := s.throttle.RecordFailure(ctx, user.Email, client.IP, &user); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	return s.finishLogin(ctx, user, client)
+}
+
+func (s *AuthService) checkPasskeyMFA(ctx context.Context, user sqlc.User, resp *webauthn.AssertionResponse) error {
+	challenge, err := s.consumePasskeyChallenge(ctx, resp, passkeyPurposeMFA, user.ID)
+	if err != nil {
+		return err
+	}
+
+	stored, err := s.repo.GetWebAuthnCredentialByCredentialID(ctx, resp.RawID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidPasskey
+		}
+		return err
+	}
+	if stored.UserID != user.ID {
+		return ErrInvalidPasskey
+	}
+
+	// The password already was one factor, so presence is enough here
+	return s.verifyPasskeyAssertion(ctx, resp, challenge, stored, false)
+}
+
+// verifyPasskeyAssertion checks an assertion against a stored credential and
+// records the new signature counter.
+func (s *AuthService) verifyPasskeyAssertion(ctx context.Context, resp *webauthn.AssertionResponse, challenge []byte, stored sqlc.WebauthnCredential, requireUserVerification bool) error {
+	signCount, err := s.webauthn.VerifyAssertion(resp, challenge, webauthn.Credential{
+		ID:         stored.CredentialID,
+		PublicKey:  stored.PublicKey,
+		SignCount:  uint32(stored.SignCount),
+		Transports: stored.Transports,
+		AAGUID:     stored.Aaguid,
+	}, requireUserVerification)
+	if err != nil {
+		if errors.Is(err, webauthn.ErrSignCountRegressed) {
+			log.Printf("passkey %s of user %s: %v", stored.ID.String(), stored.UserID.String(), err)
+		}
+		return passkeyError(err)
+	}
+
+	err = s.repo.UpdateWebAuthnCredentialSignCount(ctx, sqlc.UpdateWebAuthnCredentialSignCountParams{
+		ID:        stored.ID,
+		SignCount: int64(signCount),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update passkey: %w", err)
+	}
+	return nil
+}
+
+// createPasskeyChallenge stores a fresh challenge for a ceremony. Only its
+// hash is kept; the response carries the challenge back in its client data.
+func (s *AuthService) createPasskeyChallenge(ctx context.Context, userID pgtype.UUID, purpose string) (webauthn.Bytes, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.CreateWebAuthnChallenge(ctx, sqlc.CreateWebAuthnChallengeParams{
+		ChallengeHash: hashChallenge(challenge),
+		UserID:        userID,
+		Purpose:       purpose,
+		ExpiresAt: pgtype.Timestamptz{
+			Time:  time.Now().Add(s.webauthn.Timeout() + passkeyChallengeGrace),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store passkey challenge: %w", err)
+	}
+
+	return challenge, nil
+}
+
+// passkeyResponse is a registration or assertion response.
+type passkeyResponse interface {
+	Challenge() ([]byte, error)
+}
+
+// consumePasskeyChallenge deletes the challenge a response was signed over,
+// so it cannot be replayed, and checks it was issued for this ceremony and
+// user. Passwordless logins pass an empty userID. The challenge is returned
+// for the response to be verified against.
+func (s *AuthService) consumePasskeyChallenge(ctx context.Context, resp passkeyResponse, purpose string, userID pgtype.UUID) ([]byte, error) {
+	challenge, err := resp.Challenge()
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+
+	stored, err := s.repo.ConsumeWebAuthnChallenge(ctx, hashChallenge(challenge))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidPasskey
+		}
+		return nil, err
+	}
+	if stored.Purpose != purpose || stored.UserID != userID {
+		return nil, ErrInvalidPasskey
+	}
+
+	return challenge, nil
+}
+
+// RunPasskeySweeper periodically deletes the challenges of passkey
+// ceremonies that were never finished.
+func (s *AuthService) RunPasskeySweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.repo.DeleteExpiredWebAuthnChallenges(ctx); err != nil {
+				log.Printf("failed to sweep passkey challenges: %v", err)
+			}
+		}
+	}
+}
+
+// hasPasswordlessSignIn reports whether a user without a password can still
+// sign in, with a linked identity provider or a passkey.
+func hasPasswordlessSignIn(ctx context.Context, repo *repository.Repository, userID pgtype.UUID) (bool, error) {
+	identities, err := repo.CountUserIdentities(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	passkeys, err := repo.CountUserWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return identities+passkeys > 0, nil
+}
+
+func hashChallenge(challenge []byte) string {
+	return utils.HashToken(base64.RawURLEncoding.EncodeToString(challenge))
+}
+
+// passkeyError keeps the reason verification failed in the message while
+// letting handlers match on ErrInvalidPasskey.
+func passkeyError(err error) error {
+	return fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+}
+
+func credentialDescriptors(credentials []sqlc.WebauthnCredential) []webauthn.CredentialDescriptor {
+	descriptors := make([]webauthn.CredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		descriptors = append(descriptors, webauthn.CredentialDescriptor{
+			Type:       "public-key",
+			ID:         credential.CredentialID,
+			Transports: credential.Transports,
+		})
+	}
+	return descriptors
+}
+
+func toPasskeyResponse(credential sqlc.WebauthnCredential) *models.PasskeyResponse {
+	response := &models.PasskeyResponse{
+		ID:         credential.ID.String(),
+		Name:       credential.Name,
+		Transports: credential.Transports,
+		CreatedAt:  credential.CreatedAt.Time.Format(time.RFC3339),
+	}
+	if response.Transports == nil {
+		response.Transports = []string{}
+	}
+	if credential.LastUsedAt.Valid {
+		response.LastUsedAt = credential.LastUsedAt.Time.Format(time.RFC3339)
+	}
+	return response
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/config"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+// Password policy rules
+const (
+	PasswordRuleMinLength    = "min_length"
+	PasswordRuleMaxLength    = "max_length"
+	PasswordRuleUppercase    = "uppercase"
+	PasswordRuleLowercase    = "lowercase"
+	PasswordRuleDigit        = "digit"
+	PasswordRuleSymbol       = "symbol"
+	PasswordRulePersonalInfo = "personal_info"
+	PasswordRuleBreached     = "breached"
+)
+
+// Parts of the user's identity shorter than this are not looked for in the
+// password; a two letter first name would rule out too much.
+const minPersonalInfoLength = 3
+
+var ErrWeakPassword = apperror.Validation("weak_password", "password does not meet the password policy")
+
+// PasswordPolicyError lists every rule a password failed. It wraps
+// ErrWeakPassword. Field is the request field that held the password, when
+// it is not "password".
+type PasswordPolicyError struct {
+	Field      string
+	Violations []models.PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	rules := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		rules = append(rules, v.Rule)
+	}
+	return fmt.Sprintf("%s: %s", ErrWeakPassword, strings.Join(rules, ", "))
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrWeakPassword
+}
+
+// PasswordIdentity is what a password must not contain.
+type PasswordIdentity struct {
+	Email     string
+	FirstName string
+	LastName  string
+}
+
+// PasswordPolicy checks new passwords on registration, change and reset.
+// Existing passwords are never re-checked at login.
+type PasswordPolicy struct {
+	cfg      config.AuthConfig
+	breached *utils.BreachedPasswords
+}
+
+// NewPasswordPolicy loads the breached password corpus when
+// PasswordBreachedPath is set.
+func NewPasswordPolicy(cfg config.AuthConfig) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{cfg: cfg}
+
+	if cfg.PasswordBreachedPath != "" {
+		breached, err := utils.LoadBreachedPasswords(cfg.PasswordBreachedPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load breached password corpus: %w", err)
+		}
+		policy.breached = breached
+	}
+
+	return policy, nil
+}
+
+// Check returns a *PasswordPolicyError when the password breaks any rule.
+func (p *PasswordPolicy) Check(password string, identity PasswordIdentity) error {
+	violations := p.strength(password)
+
+	if containsPersonalInfo(password, identity) {
+		violations = append(violations, violation(PasswordRulePersonalInfo, "must not contain your email address or name"))
+	}
+
+	if p.breached != nil {
+		found, err := p.breached.Contains(password)
+		if err != nil {
+			return fmt.Errorf("failed to check breached passwords: %w", err)
+		}
+		if found {
+			violations = append(violations, violation(PasswordRuleBreached, "has appeared in a data breach and cannot be used"))
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// strength checks the length and character classes of a password.
+func (p *PasswordPolicy) strength(password string) []models.PasswordViolation {
+	var violations []models.PasswordViolation
+	fail := func(rule, format string, args ...any) {
+		violations = append(violations, violation(rule, format, args...))
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.cfg.PasswordMinLength {
+		fail(PasswordRuleMinLength, "must be at least %d characters long", p.cfg.PasswordMinLength)
+	}
+	if p.cfg.PasswordMaxLength > 0 && length > p.cfg.PasswordMaxLength {
+		fail(PasswordRuleMaxLength, "must be at most %d characters long", p.cfg.PasswordMaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.cfg.PasswordRequireUpper && !upper {
+		fail(PasswordRuleUppercase, "must contain an uppercase letter")
+	}
+	if p.cfg.PasswordRequireLower && !lower {
+		fail(PasswordRuleLowercase, "must contain a lowercase letter")
+	}
+	if p.cfg.PasswordRequireDigit && !digit {
+		fail(PasswordRuleDigit, "must contain a digit")
+	}
+	if p.cfg.PasswordRequireSymbol && !symbol {
+		fail(PasswordRuleSymbol, "must contain a symbol")
+	}
+	return violations
+}
+
+// violation describes a broken rule. The first argument, if any, is the
+// rule's limit.
+func violation(rule, format string, args ...any) models.PasswordViolation {
+	v := models.PasswordViolation{Rule: rule, Message: fmt.Sprintf(format, args...)}
+	if len(args) > 0 {
+		v.Param = fmt.Sprint(args[0])
+	}
+	return v
+}
+
+func containsPersonalInfo(password string, identity PasswordIdentity) bool {
+	password = strings.ToLower(password)
+
+	localPart, _, _ := strings.Cut(identity.Email, "@")
+	for _, part := range []string{identity.Email, localPart, identity.FirstName, identity.LastName} {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if utf8.RuneCountInString(part) >= minPersonalInfoLength && strings.Contains(password, part) {
+			return true
+		}
+	}
+	return false
+}
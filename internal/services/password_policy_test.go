@@ -0,0 +1,120 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ochko-b/goapp/internal/config"
+)
+
+func strictPasswordPolicy() config.AuthConfig {
+	return config.AuthConfig{
+		PasswordMinLength:     8,
+		PasswordMaxLength:     20,
+		PasswordRequireUpper:  true,
+		PasswordRequireLower:  true,
+		PasswordRequireDigit:  true,
+		PasswordRequireSymbol: true,
+	}
+}
+
+// violatedRules returns the rules err lists, nil for a nil error.
+func violatedRules(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("error is not a *PasswordPolicyError: %v", err)
+	}
+	if !errors.Is(err, ErrWeakPassword) {
+		t.Errorf("error does not wrap ErrWeakPassword: %v", err)
+	}
+	rules := make([]string, 0, len(policyErr.Violations))
+	for _, v := range policyErr.Violations {
+		rules = append(rules, v.Rule)
+	}
+	return rules
+}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	policy, err := NewPasswordPolicy(strictPasswordPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := PasswordIdentity{Email: "Jane.Doe@example.com", FirstName: "Jane", LastName: "Li"}
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{"meets every rule", "Tr0ub4dor&3", nil},
+		{"too short", "Ab1!", []string{PasswordRuleMinLength}},
+		{"too long", "Abcdefghij1!klmnopqrs", []string{PasswordRuleMaxLength}},
+		{"length counts runes", "Äbc1!ößü", nil},
+		{"no uppercase", "tr0ub4dor&3", []string{PasswordRuleUppercase}},
+		{"no lowercase", "TR0UB4DOR&3", []string{PasswordRuleLowercase}},
+		{"no digit", "Troubador&!", []string{PasswordRuleDigit}},
+		{"no symbol", "Tr0ub4dor33", []string{PasswordRuleSymbol}},
+		{"space is a symbol", "Tr0ub4dor 3", nil},
+		{"every rule at once", "", []string{
+			PasswordRuleMinLength, PasswordRuleUppercase, PasswordRuleLowercase, PasswordRuleDigit, PasswordRuleSymbol,
+		}},
+		{"first name in any case", "xJANEx-2024!", []string{PasswordRulePersonalInfo}},
+		{"email local part", "My-jane.doe-1X", []string{PasswordRulePersonalInfo}},
+		{"short names are ignored", "Li-Tr0ub4dor&3", nil},
+		{"weak and personal", "jane", []string{
+			PasswordRuleMinLength, PasswordRuleUppercase, PasswordRuleDigit, PasswordRuleSymbol, PasswordRulePersonalInfo,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := violatedRules(t, policy.Check(tt.password, identity))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Check(%q) violations = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyDefaultsAllowAnyClasses(t *testing.T) {
+	policy, err := NewPasswordPolicy(config.AuthConfig{PasswordMinLength: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := policy.Check("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", PasswordIdentity{}); err != nil {
+		t.Errorf("long lowercase password without a maximum length: %v", err)
+	}
+}
+
+func TestPasswordPolicyBreached(t *testing.T) {
+	// SHA-1 of "password", in the single file layout.
+	corpus := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(corpus, []byte("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:10434004\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.AuthConfig{PasswordMinLength: 8, PasswordBreachedPath: corpus}
+	policy, err := NewPasswordPolicy(cfg)
+	if err != nil {
+		t.Fatalf("NewPasswordPolicy: %v", err)
+	}
+
+	if got := violatedRules(t, policy.Check("password", PasswordIdentity{})); !slices.Equal(got, []string{PasswordRuleBreached}) {
+		t.Errorf("breached password: violations = %v", got)
+	}
+	if err := policy.Check("password1", PasswordIdentity{}); err != nil {
+		t.Errorf("password outside the corpus: %v", err)
+	}
+
+	cfg.PasswordBreachedPath = filepath.Join(t.TempDir(), "missing")
+	if _, err := NewPasswordPolicy(cfg); err == nil {
+		t.Error("missing corpus: expected an error")
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+var ErrInvalidResetToken = apperror.Validation("invalid_reset_token", "invalid or expired password reset token")
+
+// ForgotPassword issues a password reset token and mails a link to it. It
+// returns nil for unknown addresses so the endpoint cannot be used to probe
+// which emails have accounts.
+func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	// Store the token and deliver in the background, so known and unknown
+	// addresses do the same work before the response and take as long.
+	sendInBackground("password reset email", func(ctx context.Context) error {
+		return s.sendPasswordReset(ctx, user)
+	})
+
+	return nil
+}
+
+func (s *AuthService) sendPasswordReset(ctx context.Context, user sqlc.User) error {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.repo.CreatePasswordResetToken(ctx, sqlc.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: pgtype.Timestamptz{
+			Time:  time.Now().Add(s.authConfig.PasswordResetExpiresIn),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store password reset token: %w", err)
+	}
+
+	resetURL := s.authConfig.AppURL + "/reset-password?token=" + url.QueryEscape(token)
+	return s.notifier.SendPasswordReset(ctx, user.Email, resetURL, s.authConfig.PasswordResetExpiresIn)
+}
+
+// ResetPassword consumes a reset token, sets the new password and signs the
+// user out everywhere.
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	stored, err := txRepo.GetPasswordResetTokenByHash(ctx, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	if stored.UsedAt.Valid || time.Now().After(stored.ExpiresAt.Time) {
+		return ErrInvalidResetToken
+	}
+
+	rows, err := txRepo.MarkPasswordResetTokenUsed(ctx, stored.ID)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrInvalidResetToken
+	}
+
+	user, err := txRepo.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		return err
+	}
+
+	// A rejected password rolls back, so the token can be used again
+	err = s.policy.Check(newPassword, PasswordIdentity{
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+	})
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.passwords.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	err = txRepo.UpdateUserPassword(ctx, sqlc.UpdateUserPasswordParams{
+		ID:           stored.UserID,
+		PasswordHash: hashedPassword,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if err := txRepo.InvalidateUserPasswordResetTokens(ctx, stored.UserID); err != nil {
+		return err
+	}
+
+	if err := txRepo.RevokeUserRefreshTokens(ctx, stored.UserID); err != nil {
+		return err
+	}
+	if err := txRepo.RevokeUserSessions(ctx, stored.UserID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Proving control of the mailbox is as good as the unlock link
+	if err := s.throttle.Unlock(ctx, user, pgtype.UUID{}, "", "password_reset"); err != nil {
+		return err
+	}
+
+	return s.revocations.RevokeAllForUser(ctx, stored.UserID.String())
+}
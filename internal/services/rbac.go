@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/repository"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+// RoleAdmin is the built-in role seeded with every permission.
+const RoleAdmin = "admin"
+
+var (
+	ErrRoleNotFound     = apperror.NotFound("role_not_found", "role not found")
+	ErrUserNotFound     = apperror.NotFound("user_not_found", "user not found")
+	ErrRoleNotAssigned  = apperror.NotFound("role_not_assigned", "user does not have this role")
+	ErrLastAdminRemoval = apperror.Conflict("last_admin_removal", "cannot remove the last admin")
+)
+
+type RBACService struct {
+	repo *repository.Repository
+}
+
+func NewRBACService(repo *repository.Repository) *RBACService {
+	return &RBACService{
+		repo: repo,
+	}
+}
+
+func (s *RBACService) ListRoles(ctx context.Context) ([]*models.RoleResponse, error) {
+	roles, err := s.repo.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*models.RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		responses = append(responses, &models.RoleResponse{
+			Name:        role.Name,
+			Description: role.Description,
+		})
+	}
+	return responses, nil
+}
+
+// GrantRole gives the user a role. Granting a role the user already has is a
+// no-op. The new permissions show up in the user's next access token.
+func (s *RBACService) GrantRole(ctx context.Context, userID, roleName string) error {
+	id, err := utils.ParseUUID(userID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.repo.GetUserByID(ctx, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	role, err := s.getRole(ctx, roleName)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.AssignUserRole(ctx, sqlc.AssignUserRoleParams{
+		UserID: id,
+		RoleID: role.ID,
+	})
+}
+
+// GrantRoleByEmail is GrantRole for callers that only know the email, such as
+// the admin CLI.
+func (s *RBACService) GrantRoleByEmail(ctx context.Context, email, roleName string) error {
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	return s.GrantRole(ctx, user.ID.String(), roleName)
+}
+
+// RevokeRole takes a role away from the user. The admin role cannot be
+// taken from the last user holding it.
+func (s *RBACService) RevokeRole(ctx context.Context, userID, roleName string) error {
+	id, err := utils.ParseUUID(userID)
+	if err != nil {
+		return err
+	}
+
+	role, err := s.getRole(ctx, roleName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	if role.Name == RoleAdmin {
+		// Locking every admin makes concurrent revokes wait for each other,
+		// so two of them cannot each see the other admin and remove both.
+		admins, err := txRepo.LockRoleHolders(ctx, role.ID)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(admins, id) {
+			return ErrRoleNotAssigned
+		}
+		if len(admins) <= 1 {
+			return ErrLastAdminRemoval
+		}
+	}
+
+	rows, err := txRepo.RemoveUserRole(ctx, sqlc.RemoveUserRoleParams{
+		UserID: id,
+		RoleID: role.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRoleNotAssigned
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// BootstrapAdmin makes the user with the given email an admin, but only while
+// nobody holds the admin role yet. It reports whether the role was granted.
+func (s *RBACService) BootstrapAdmin(ctx context.Context, email string) (bool, error) {
+	admins, err := s.repo.CountUsersWithRole(ctx, RoleAdmin)
+	if err != nil {
+		return false, err
+	}
+	if admins > 0 {
+		return false, nil
+	}
+
+	if err := s.GrantRoleByEmail(ctx, email, RoleAdmin); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *RBACService) getRole(ctx context.Context, name string) (sqlc.Role, error) {
+	role, err := s.repo.GetRoleByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.Role{}, ErrRoleNotFound
+		}
+		return sqlc.Role{}, err
+	}
+	return role, nil
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/repository"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+// revocationCacheTTL bounds how long another replica's revocation can go
+// unnoticed by this process.
+const revocationCacheTTL = 30 * time.Second
+
+type revocationEntry struct {
+	revoked   bool
+	cutoff    time.Time
+	expiresAt time.Time
+}
+
+// RevocationService keeps the access token denylist. Lookups go through a
+// small in-process cache so JWTAuth does not hit Postgres on every request.
+type RevocationService struct {
+	repo      *repository.Repository
+	accessTTL time.Duration
+
+	mu       sync.RWMutex
+	tokens   map[string]revocationEntry
+	users    map[string]revocationEntry
+	sessions map[string]revocationEntry
+}
+
+func NewRevocationService(repo *repository.Repository, accessTTL time.Duration) *RevocationService {
+	return &RevocationService{
+		repo:      repo,
+		accessTTL: accessTTL,
+		tokens:    make(map[string]revocationEntry),
+		users:     make(map[string]revocationEntry),
+		sessions:  make(map[string]revocationEntry),
+	}
+}
+
+// RevokeToken denylists a single access token until it expires.
+func (s *RevocationService) RevokeToken(ctx context.Context, claims *utils.Claims) error {
+	userID, err := utils.ParseUUID(claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(s.accessTTL)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	err = s.repo.RevokeToken(ctx, sqlc.RevokeTokenParams{
+		Jti:       claims.ID,
+		UserID:    userID,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.tokens[claims.ID] = revocationEntry{revoked: true, expiresAt: expiresAt}
+	s.mu.Unlock()
+
+	return nil
+}
+
+// RevokeAllForUser rejects every access token issued to the user so far.
+func (s *RevocationService) RevokeAllForUser(ctx context.Context, userID string) error {
+	id, err := utils.ParseUUID(userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = s.repo.RevokeUserTokens(ctx, sqlc.RevokeUserTokensParams{
+		UserID:        id,
+		RevokedBefore: pgtype.Timestamptz{Time: now, Valid: true},
+		ExpiresAt:     pgtype.Timestamptz{Time: now.Add(s.accessTTL), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.users[userID] = revocationEntry{cutoff: now, expiresAt: now.Add(revocationCacheTTL)}
+	s.mu.Unlock()
+
+	return nil
+}
+
+// RevokeSession signs a session out: the refresh tokens of its family stop
+// working, and access tokens issued with it are rejected from now on.
+func (s *RevocationService) RevokeSession(ctx context.Context, sessionID pgtype.UUID) error {
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := s.repo.WithTx(tx)
+
+	if err := txRepo.RevokeSession(ctx, sessionID); err != nil {
+		return err
+	}
+	if err := txRepo.RevokeRefreshTokenFamily(ctx, sessionID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.mu.Lock()
+	s.sessions[sessionID.String()] = revocationEntry{revoked: true, expiresAt: time.Now().Add(s.accessTTL)}
+	s.mu.Unlock()
+
+	return nil
+}
+
+// IsRevoked reports whether the token was logged out individually, belongs to
+// a revoked session, or was issued before a logout from all devices.
+func (s *RevocationService) IsRevoked(ctx context.Context, claims *utils.Claims) (bool, error) {
+	revoked, err := s.isTokenRevoked(ctx, claims.ID)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	revoked, err = s.isSessionRevoked(ctx, claims.SessionID)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	revoked, err = s.issuedBeforeCutoff(ctx, claims.UserID, claims)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	// Signing the support agent out everywhere ends their impersonation too
+	if claims.Actor != nil {
+		return s.issuedBeforeCutoff(ctx, claims.Actor.UserID, claims)
+	}
+	return false, nil
+}
+
+// issuedBeforeCutoff reports whether the token predates a "revoke all" of the
+// user.
+func (s *RevocationService) issuedBeforeCutoff(ctx context.Context, userID string, claims *utils.Claims) (bool, error) {
+	cutoff, err := s.userCutoff(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if cutoff.IsZero() || claims.IssuedAt == nil {
+		return false, nil
+	}
+
+	return !claims.IssuedAt.Time.After(cutoff), nil
+}
+
+func (s *RevocationService) isTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	now := time.Now()
+
+	s.mu.RLock()
+	entry, ok := s.tokens[jti]
+	s.mu.RUnlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.revoked, nil
+	}
+
+	revoked, err := s.repo.IsTokenRevoked(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+
+	// Revoked tokens stay revoked, so they can be cached until expiry; a
+	// negative answer is only trusted for the cache TTL.
+	expiresAt := now.Add(revocationCacheTTL)
+	if revoked {
+		expiresAt = now.Add(s.accessTTL)
+	}
+
+	s.mu.Lock()
+	s.tokens[jti] = revocationEntry{revoked: revoked, expiresAt: expiresAt}
+	s.mu.Unlock()
+
+	return revoked, nil
+}
+
+// isSessionRevoked looks the session up by its id. Tokens without a session,
+// and sessions already pruned by the sweeper, count as not revoked: the
+// latter's access tokens have expired by then anyway.
+func (s *RevocationService) isSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	if sessionID == "" {
+		return false, nil
+	}
+
+	now := time.Now()
+
+	s.mu.RLock()
+	entry, ok := s.sessions[sessionID]
+	s.mu.RUnlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.revoked, nil
+	}
+
+	id, err := utils.ParseUUID(sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	var revoked bool
+	session, err := s.repo.GetSession(ctx, id)
+	switch {
+	case err == nil:
+		revoked = session.RevokedAt.Valid
+	case !errors.Is(err, pgx.ErrNoRows):
+		return false, err
+	}
+
+	expiresAt := now.Add(revocationCacheTTL)
+	if revoked {
+		expiresAt = now.Add(s.accessTTL)
+	}
+
+	s.mu.Lock()
+	s.sessions[sessionID] = revocationEntry{revoked: revoked, expiresAt: expiresAt}
+	s.mu.Unlock()
+
+	return revoked, nil
+}
+
+func (s *RevocationService) userCutoff(ctx context.Context, userID string) (time.Time, error) {
+	now := time.Now()
+
+	s.mu.RLock()
+	entry, ok := s.users[userID]
+	s.mu.RUnlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.cutoff, nil
+	}
+
+	id, err := utils.ParseUUID(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var cutoff time.Time
+	revokedBefore, err := s.repo.GetUserTokenRevocation(ctx, id)
+	switch {
+	case err == nil:
+		cutoff = revokedBefore.Time
+	case !errors.Is(err, pgx.ErrNoRows):
+		return time.Time{}, err
+	}
+
+	s.mu.Lock()
+	s.users[userID] = revocationEntry{cutoff: cutoff, expiresAt: now.Add(revocationCacheTTL)}
+	s.mu.Unlock()
+
+	return cutoff, nil
+}
+
+// RunSweeper periodically deletes denylist rows whose tokens have expired
+// anyway and sessions that ended, and drops stale cache entries. It returns when ctx is cancelled.
+func (s *RevocationService) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep(ctx)
+		}
+	}
+}
+
+func (s *RevocationService) sweep(ctx context.Context) {
+	if _, err := s.repo.DeleteExpiredRevokedTokens(ctx); err != nil {
+		log.Printf("revocation sweeper: failed to prune revoked tokens: %v", err)
+	}
+	if _, err := s.repo.DeleteExpiredUserTokenRevocations(ctx); err != nil {
+		log.Printf("revocation sweeper: failed to prune user revocations: %v", err)
+	}
+	if _, err := s.repo.DeleteExpiredSessions(ctx); err != nil {
+		log.Printf("revocation sweeper: failed to prune sessions: %v", err)
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	for jti, entry := range s.tokens {
+		if now.After(entry.expiresAt) {
+			delete(s.tokens, jti)
+		}
+	}
+	for userID, entry := range s.users {
+		if now.After(entry.expiresAt) {
+			delete(s.users, userID)
+		}
+	}
+	for sessionID, entry := range s.sessions {
+		if now.After(entry.expiresAt) {
+			delete(s.sessions, sessionID)
+		}
+	}
+	s.mu.Unlock()
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+// testClaims returns the claims of an access token of the user issued at the
+// given time, without a session.
+func testClaims(userID string, issuedAt time.Time) *utils.Claims {
+	return &utils.Claims{
+		UserID:   userID,
+		TokenUse: utils.TokenUseAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(15 * time.Minute)),
+		},
+	}
+}
+
+func TestIsRevokedAnswersFromCache(t *testing.T) {
+	// Without a repository, any lookup the cache does not answer panics.
+	s := NewRevocationService(nil, 15*time.Minute)
+	userID := uuid.NewString()
+	now := time.Now()
+	cutoff := now.Add(-time.Minute)
+	fresh := now.Add(revocationCacheTTL)
+
+	revokedToken := testClaims(userID, now)
+	before := testClaims(userID, cutoff.Add(-time.Second))
+	after := testClaims(userID, cutoff.Add(time.Second))
+
+	s.tokens[revokedToken.ID] = revocationEntry{revoked: true, expiresAt: fresh}
+	s.tokens[before.ID] = revocationEntry{expiresAt: fresh}
+	s.tokens[after.ID] = revocationEntry{expiresAt: fresh}
+	s.users[userID] = revocationEntry{cutoff: cutoff, expiresAt: fresh}
+
+	tests := []struct {
+		name   string
+		claims *utils.Claims
+		want   bool
+	}{
+		{"revoked jti", revokedToken, true},
+		{"issued before the cutoff", before, true},
+		{"issued after the cutoff", after, false},
+	}
+	for _, tt := range tests {
+		revoked, err := s.IsRevoked(context.Background(), tt.claims)
+		if err != nil || revoked != tt.want {
+			t.Errorf("%s: revoked=%v err=%v, want %v", tt.name, revoked, err, tt.want)
+		}
+	}
+}
+
+func TestRevocation(t *testing.T) {
+	repo := testRepository(t)
+	ctx := context.Background()
+	user := createTestUser(t, repo)
+	userID := user.ID.String()
+
+	s := NewRevocationService(repo, 15*time.Minute)
+
+	revokedToken := testClaims(userID, time.Now())
+	if err := s.RevokeToken(ctx, revokedToken); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	before := testClaims(userID, time.Now())
+	if err := s.RevokeAllForUser(ctx, userID); err != nil {
+		t.Fatalf("RevokeAllForUser: %v", err)
+	}
+	after := testClaims(userID, time.Now().Add(time.Millisecond))
+
+	tests := []struct {
+		name   string
+		claims *utils.Claims
+		want   bool
+	}{
+		{"revoked jti", revokedToken, true},
+		{"issued before RevokeAllForUser", before, true},
+		{"issued after RevokeAllForUser", after, false},
+	}
+
+	// The revoking process answers from its cache; another replica only has
+	// the database.
+	for name, service := range map[string]*RevocationService{
+		"same process":  s,
+		"other process": NewRevocationService(repo, 15*time.Minute),
+	} {
+		for _, tt := range tests {
+			revoked, err := service.IsRevoked(ctx, tt.claims)
+			if err != nil || revoked != tt.want {
+				t.Errorf("%s, %s: revoked=%v err=%v, want %v", name, tt.name, revoked, err, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ochko-b/goapp/generated/sqlc"
+	"github.com/ochko-b/goapp/internal/apperror"
+	"github.com/ochko-b/goapp/internal/models"
+	"github.com/ochko-b/goapp/internal/repository"
+	"github.com/ochko-b/goapp/internal/utils"
+)
+
+var ErrSessionNotFound = apperror.NotFound("session_not_found", "session not found")
+
+// AuditSessionRevoked is recorded when a session is signed out from the
+// session list, by its user or by an admin.
+const AuditSessionRevoked = "session.revoked"
+
+// ClientInfo describes the device a request came from. Sign-ins record it on
+// their session.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// SessionService lists and signs out the sessions of a user. A session is a
+// refresh token family: it starts at sign-in and lasts as long as its refresh
+// tokens keep being rotated.
+type SessionService struct {
+	repo        *repository.Repository
+	revocations *RevocationService
+	audit       *AuditService
+}
+
+func NewSessionService(repo *repository.Repository, revocations *RevocationService, audit *AuditService) *SessionService {
+	return &SessionService{
+		repo:        repo,
+		revocations: revocations,
+		audit:       audit,
+	}
+}
+
+// List returns the active sessions of the user, most recently used first.
+// currentSessionID is the session of the caller, if it is the user.
+func (s *SessionService) List(ctx context.Context, userID, currentSessionID string) ([]*models.SessionResponse, error) {
+	uid, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if _, err := s.repo.GetUserByID(ctx, uid); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	sessions, err := s.repo.ListUserSessions(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	responses := make([]*models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response := &models.SessionResponse{
+			ID:         session.ID.String(),
+			Device:     session.Device,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IpAddress,
+			ClientName: session.ClientName,
+			Current:    session.ID.String() == currentSessionID,
+			CreatedAt:  session.CreatedAt.Time.Format(time.RFC3339),
+			LastSeenAt: session.LastSeenAt.Time.Format(time.RFC3339),
+			ExpiresAt:  session.ExpiresAt.Time.Format(time.RFC3339),
+		}
+		if session.ClientID.Valid {
+			response.ClientID = session.ClientID.String()
+		}
+		responses = append(responses, response)
+	}
+
+	return responses, nil
+}
+
+// Revoke signs the session out: its refresh tokens stop working and access
+// tokens issued with it are rejected from now on. actorID is whoever asked
+// for it, the user or an admin.
+func (s *SessionService) Revoke(ctx context.Context, actorID, userID, sessionID, clientIP string) error {
+	uid, err := utils.ParseUUID(userID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+	sid, err := utils.ParseUUID(sessionID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+
+	session, err := s.repo.GetSession(ctx, sid)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	if session.UserID != uid || session.RevokedAt.Valid {
+		return ErrSessionNotFound
+	}
+
+	if err := s.revocations.RevokeSession(ctx, sid); err != nil {
+		return err
+	}
+
+	entry := AuditEntry{
+		UserID:   uid,
+		Action:   AuditSessionRevoked,
+		IP:       clientIP,
+		Metadata: map[string]any{"session_id": sessionID, "device": session.Device},
+	}
+	if actorID != userID {
+		if entry.ActorID, err = utils.ParseUUID(actorID); err != nil {
+			return err
+		}
+	}
+	s.audit.Record(ctx, entry)
+
+	return nil
+}
+
+// recordSession starts the session of a new refresh token family, or marks an
+// existing one as just used when its token is rotated.
+func recordSession(ctx context.Context, repo *repository.Repository, userID, clientID, sessionID pgtype.UUID, client ClientInfo, expiresAt time.Time) error {
+	err := repo.UpsertSession(ctx, sqlc.UpsertSessionParams{
+		ID:        sessionID,
+		UserID:    userID,
+		ClientID:  clientID,
+		UserAgent: client.UserAgent,
+		IpAddress: client.IP,
+		Device:    utils.DeviceLabel(client.UserAgent),
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record session: %w", err)
+	}
+	return nil
+}
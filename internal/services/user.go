@@ -2,10 +2,12 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/ochko-b/goapp/generated/sqlc"
 	"github.com/ochko-b/goapp/internal/models"
@@ -34,15 +36,19 @@ func (s *UserService) UpdateUserWithTransaction(ctx context.Context, userID stri
 	txRepo := s.repo.WithTx(tx)
 	id, err := utils.ParseUUID(userID)
 	if err != nil {
-		return nil, err
+		return nil, ErrUserNotFound
 	}
 
 	user, err := txRepo.UpdateUser(ctx, sqlc.UpdateUserParams{
 		ID:        id,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
+		Locale:    pgtype.Text{String: req.Locale, Valid: req.Locale != ""},
 	})
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
@@ -50,20 +56,13 @@ func (s *UserService) UpdateUserWithTransaction(ctx context.Context, userID stri
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return &models.UserResponse{
-		ID:        user.ID.String(),
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		CreatedAt: user.CreatedAt.Time.Format(time.RFC3339),
-		UpdatedAt: user.UpdatedAt.Time.Format(time.RFC3339),
-	}, nil
+	return toUserResponse(user), nil
 }
 
 func (s *UserService) GetByID(ctx context.Context, userID string) (*models.UserResponse, error) {
 	parsedID, err := uuid.Parse(userID)
 	if err != nil {
-		return nil, err
+		return nil, ErrUserNotFound
 	}
 	pgUUID := pgtype.UUID{
 		Bytes: parsedID,
@@ -71,23 +70,19 @@ func (s *UserService) GetByID(ctx context.Context, userID string) (*models.UserR
 	}
 	user, err := s.repo.GetUserByID(ctx, pgUUID)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
 		return nil, err
 	}
 
-	return &models.UserResponse{
-		ID:        user.ID.String(),
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		CreatedAt: user.CreatedAt.Time.Format(time.RFC3339),
-		UpdatedAt: user.UpdatedAt.Time.Format(time.RFC3339),
-	}, nil
+	return toUserResponse(user), nil
 }
 
 func (s *UserService) UpdateProfile(ctx context.Context, userID string, req *models.UpdateProfileRequest) (*models.UserResponse, error) {
 	parsedID, err := uuid.Parse(userID)
 	if err != nil {
-		return nil, err
+		return nil, ErrUserNotFound
 	}
 	pgUUID := pgtype.UUID{
 		Bytes: parsedID,
@@ -98,19 +93,16 @@ func (s *UserService) UpdateProfile(ctx context.Context, userID string, req *mod
 		ID:        pgUUID,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
+		Locale:    pgtype.Text{String: req.Locale, Valid: req.Locale != ""},
 	})
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
 		return nil, err
 	}
 
-	return &models.UserResponse{
-		ID:        user.ID.String(),
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		CreatedAt: user.CreatedAt.Time.Format(time.RFC3339),
-		UpdatedAt: user.UpdatedAt.Time.Format(time.RFC3339),
-	}, nil
+	return toUserResponse(user), nil
 }
 
 func (s *UserService) List(ctx context.Context, limit, offset int32) ([]*models.UserResponse, error) {
@@ -124,14 +116,20 @@ func (s *UserService) List(ctx context.Context, limit, offset int32) ([]*models.
 
 	var userResponses []*models.UserResponse
 	for _, user := range users {
-		userResponses = append(userResponses, &models.UserResponse{
-			ID:        user.ID.String(),
-			Email:     user.Email,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			CreatedAt: user.CreatedAt.Time.Format(time.RFC3339),
-			UpdatedAt: user.UpdatedAt.Time.Format(time.RFC3339),
-		})
+		userResponses = append(userResponses, toUserResponse(user))
 	}
 	return userResponses, nil
 }
+
+func toUserResponse(user sqlc.User) *models.UserResponse {
+	return &models.UserResponse{
+		ID:            user.ID.String(),
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Locale:        user.Locale.String,
+		CreatedAt:     user.CreatedAt.Time.Format(time.RFC3339),
+		UpdatedAt:     user.UpdatedAt.Time.Format(time.RFC3339),
+	}
+}
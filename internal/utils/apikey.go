@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyPrefix marks API keys so they can share the Bearer scheme with JWTs.
+const APIKeyPrefix = "gk_"
+
+const apiKeyLookupLen = 12
+
+// GenerateAPIKey returns a key of the form gk_<lookup>_<secret> together
+// with its lookup part. Only the lookup part and HashToken(secret) are stored.
+func GenerateAPIKey() (key, lookup, secret string, err error) {
+	b := make([]byte, apiKeyLookupLen/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	lookup = hex.EncodeToString(b)
+
+	secret, err = GenerateOpaqueToken()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return APIKeyPrefix + lookup + "_" + secret, lookup, secret, nil
+}
+
+// IsAPIKey reports whether a credential looks like an API key rather than a JWT.
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, APIKeyPrefix)
+}
+
+// ParseAPIKey splits a key into its lookup and secret parts.
+func ParseAPIKey(key string) (lookup, secret string, ok bool) {
+	rest, ok := strings.CutPrefix(key, APIKeyPrefix)
+	if !ok || len(rest) < apiKeyLookupLen+2 || rest[apiKeyLookupLen] != '_' {
+		return "", "", false
+	}
+	return rest[:apiKeyLookupLen], rest[apiKeyLookupLen+1:], true
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachedPasswords checks passwords against a local copy of a breached
+// password corpus in the Have I Been Pwned k-anonymity layout: SHA-1 hashes
+// split into a 5 character prefix and a 35 character suffix. Nothing is sent
+// over the network.
+//
+// path is either a directory of range files named after their prefix (e.g.
+// "5BAA6" or "5BAA6.txt") holding "SUFFIX:COUNT" lines, which are read on
+// demand, or a single file of "HASH" or "HASH:COUNT" lines, which is loaded
+// into memory.
+type BreachedPasswords struct {
+	dir    string
+	ranges map[string]map[string]struct{}
+}
+
+func LoadBreachedPasswords(path string) (*BreachedPasswords, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &BreachedPasswords{dir: path}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ranges := map[string]map[string]struct{}{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		hash, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if len(hash) != sha1.Size*2 {
+			continue
+		}
+		hash = strings.ToUpper(hash)
+		prefix, suffix := hash[:5], hash[5:]
+		if ranges[prefix] == nil {
+			ranges[prefix] = map[string]struct{}{}
+		}
+		ranges[prefix][suffix] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return &BreachedPasswords{ranges: ranges}, nil
+}
+
+// Contains reports whether the password appears in the corpus.
+func (b *BreachedPasswords) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	if b.dir == "" {
+		_, found := b.ranges[prefix][suffix]
+		return found, nil
+	}
+
+	for _, name := range []string{prefix, prefix + ".txt"} {
+		found, err := rangeContains(filepath.Join(b.dir, name), suffix)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return found, err
+	}
+	return false, nil
+}
+
+func rangeContains(path, suffix string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(line, suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
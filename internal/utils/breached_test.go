@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestBreachedPasswords(t *testing.T) {
+	// Both corpora hold "password" and "password123", one of them in lower
+	// case, and an unrelated hash in the range of "password".
+	layouts := map[string]string{
+		"range directory": "testdata/breached",
+		"single file":     "testdata/breached.txt",
+	}
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{"password", true},
+		{"password123", true},
+		{"Password", false},
+		// No range file for its prefix.
+		{"letmein", false},
+		{"", false},
+	}
+
+	for layout, path := range layouts {
+		t.Run(layout, func(t *testing.T) {
+			breached, err := LoadBreachedPasswords(path)
+			if err != nil {
+				t.Fatalf("LoadBreachedPasswords: %v", err)
+			}
+			for _, tt := range tests {
+				found, err := breached.Contains(tt.password)
+				if err != nil {
+					t.Fatalf("Contains(%q): %v", tt.password, err)
+				}
+				if found != tt.want {
+					t.Errorf("Contains(%q) = %v, want %v", tt.password, found, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadBreachedPasswordsMissing(t *testing.T) {
+	if _, err := LoadBreachedPasswords("testdata/missing"); err == nil {
+		t.Error("expected an error for a missing corpus")
+	}
+}
@@ -1,42 +1,128 @@
 package utils
 
 import (
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// Token uses. JWTAuth only accepts user and OAuth client access tokens; other
+// uses are short-lived tokens for a single step of a flow.
+const (
+	TokenUseAccess     = "access"
+	TokenUseMFAPending = "mfa_pending"
+	// TokenUseAPIKey marks claims built from an API key rather than a JWT.
+	TokenUseAPIKey = "api_key"
+	// TokenUseOAuth is an access token issued to an OAuth client app. JWTAuth
+	// accepts it, but it is not a user token.
+	TokenUseOAuth = "oauth_access"
+)
+
+// Timestamps carry sub-second precision, so a token minted right after a
+// "revoke everything issued before now" cutoff is not caught by it.
+func init() {
+	jwt.TimePrecision = time.Microsecond
+}
+
+// Claims are the application claims of an access token. OrgID is set when the
+// credential is pinned to one organization. SessionID is the refresh token
+// family the token was issued with. ClientID and Scope are set on tokens
+// issued to OAuth clients (RFC 9068), whose Permissions are the granted scopes.
+// Locale is the language the user chose for messages, if any. Actor is set
+// while a support agent impersonates the user (RFC 8693).
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"mail"`
+	UserID        string   `json:"user_id"`
+	Email         string   `json:"mail"`
+	EmailVerified bool     `json:"email_verified"`
+	TokenUse      string   `json:"token_use"`
+	Roles         []string `json:"roles,omitempty"`
+	Permissions   []string `json:"permissions,omitempty"`
+	OrgID         string   `json:"org_id,omitempty"`
+	SessionID     string   `json:"sid,omitempty"`
+	ClientID      string   `json:"client_id,omitempty"`
+	Scope         string   `json:"scope,omitempty"`
+	Locale        string   `json:"locale,omitempty"`
+	Actor         *Actor   `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID, email, secret string, duration time.Duration) (string, error) {
-	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+// Actor is the real user behind an impersonation token. The claims around it
+// describe the effective user, whose identity and permissions apply.
+type Actor struct {
+	UserID string `json:"sub"`
+	Email  string `json:"mail,omitempty"`
+}
+
+// ImpersonatorKey is the request local, and so the context value, holding the
+// actor's user ID while a request is made with an impersonation token.
+const ImpersonatorKey = "impersonator_id"
+
+// HasPermission reports whether the token grants the permission. Roles and
+// permissions are snapshotted at issue time, so changes apply on refresh.
+func (c *Claims) HasPermission(permission string) bool {
+	return slices.Contains(c.Permissions, permission)
+}
+
+// GenerateToken signs the given application claims. The registered claims
+// (jti, iat, exp) are filled in here, and TokenUse defaults to access.
+func GenerateToken(claims Claims, keys *KeyRing, duration time.Duration) (string, error) {
+	if claims.TokenUse == "" {
+		claims.TokenUse = TokenUseAccess
+	}
+
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		IssuedAt:  jwt.NewNumericDate(now),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return SignClaims(claims, keys)
 }
 
-func ValidateToken(tokenString, secret string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (any, error) {
-		return []byte(secret), nil
-	})
-	if err != nil {
+// SignClaims signs arbitrary claims with the active key and stamps its kid.
+func SignClaims(claims jwt.Claims, keys *KeyRing) (string, error) {
+	key := keys.Active()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.sign)
+}
+
+func ValidateToken(tokenString string, keys *KeyRing) (*Claims, error) {
+	claims := &Claims{}
+	if err := ParseClaims(tokenString, claims, keys); err != nil {
 		return nil, err
 	}
+	return claims, nil
+}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+// ParseClaims verifies the token against the key named by its kid header and
+// decodes it into claims. The algorithm must match the key, so a token cannot
+// pick a weaker algorithm than the one the key was issued for.
+func ParseClaims(tokenString string, claims jwt.Claims, keys *KeyRing) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys.Lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.verify, nil
+	})
+	if err != nil {
+		return err
 	}
 
-	return nil, jwt.ErrInvalidKey
+	if !token.Valid {
+		return jwt.ErrInvalidKey
+	}
+	return nil
 }
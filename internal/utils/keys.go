@@ -0,0 +1,350 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a single JWT key. Verify-only keys have no signing half.
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	sign   any
+	verify any
+}
+
+// KeyRing holds the active signing key plus retired keys that are still
+// accepted for verification until the tokens they signed have expired.
+type KeyRing struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewKeyRing(active *SigningKey, verifyOnly ...*SigningKey) (*KeyRing, error) {
+	if active == nil || active.sign == nil {
+		return nil, errors.New("active key must be able to sign")
+	}
+
+	ring := &KeyRing{
+		active: active,
+		keys:   map[string]*SigningKey{active.ID: active},
+	}
+	for _, key := range verifyOnly {
+		if key.ID == "" {
+			return nil, errors.New("verification keys require a key id")
+		}
+		if _, exists := ring.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		ring.keys[key.ID] = key
+	}
+
+	return ring, nil
+}
+
+// LoadKeyRing builds the key ring with the private key at privateKeyPath as
+// the active key, named keyID. Without a private key path it falls back to
+// HS256 with the shared secret. verificationKeys is a comma-separated list of
+// kid=path entries for retired keys that are still accepted.
+func LoadKeyRing(keyID, privateKeyPath, secret, verificationKeys string) (*KeyRing, error) {
+	var active *SigningKey
+	if privateKeyPath == "" {
+		if secret == "" {
+			return nil, errors.New("either JWT_PRIVATE_KEY_PATH or JWT_SECRET must be set")
+		}
+		active = NewHMACKey(keyID, []byte(secret))
+	} else {
+		key, err := LoadSigningKey(keyID, privateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		active = key
+	}
+
+	var verifyOnly []*SigningKey
+	for _, entry := range strings.Split(verificationKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid verification key %q, expected kid=path", entry)
+		}
+		key, err := LoadSigningKey(strings.TrimSpace(kid), strings.TrimSpace(path))
+		if err != nil {
+			return nil, err
+		}
+		verifyOnly = append(verifyOnly, key)
+	}
+
+	return NewKeyRing(active, verifyOnly...)
+}
+
+func NewHMACKey(kid string, secret []byte) *SigningKey {
+	return &SigningKey{
+		ID:     kid,
+		Method: jwt.SigningMethodHS256,
+		sign:   secret,
+		verify: secret,
+	}
+}
+
+// LoadSigningKey reads a PEM encoded RSA, ECDSA or Ed25519 key. A private key
+// can sign and verify; a public key is verify-only.
+func LoadSigningKey(kid, path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %s: %w", path, err)
+	}
+
+	return NewSigningKey(kid, parsed)
+}
+
+// NewSigningKey wraps a parsed private or public key and picks the matching
+// JWT algorithm.
+func NewSigningKey(kid string, key any) (*SigningKey, error) {
+	var public crypto.PublicKey
+	var private any
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		private, public = k, &k.PublicKey
+	case *ecdsa.PrivateKey:
+		private, public = k, &k.PublicKey
+	case ed25519.PrivateKey:
+		private, public = k, k.Public()
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		public = k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+
+	method, err := signingMethodFor(public)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SigningKey{
+		ID:     kid,
+		Method: method,
+		sign:   private,
+		verify: public,
+	}, nil
+}
+
+func signingMethodFor(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, fmt.Errorf("unsupported curve %s", k.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", public)
+}
+
+func (r *KeyRing) Active() *SigningKey {
+	return r.active
+}
+
+// Lookup returns the key a token names in its kid header. Tokens without a
+// kid are checked against the active key.
+func (r *KeyRing) Lookup(kid string) (*SigningKey, bool) {
+	if kid == "" {
+		return r.active, true
+	}
+	key, ok := r.keys[kid]
+	return key, ok
+}
+
+// JWKS returns the public halves of all asymmetric keys. HMAC secrets are
+// never published.
+func (r *KeyRing) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+
+	keys := []*SigningKey{r.active}
+	for kid, key := range r.keys {
+		if kid != r.active.ID {
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range keys {
+		if jwk, ok := key.JWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+func (k *SigningKey) JWK() (JWK, bool) {
+	jwk := JWK{
+		Kid: k.ID,
+		Use: "sig",
+		Alg: k.Method.Alg(),
+	}
+
+	switch pub := k.verify.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		ecdhKey, err := pub.ECDH()
+		if err != nil {
+			return JWK{}, false
+		}
+		// Uncompressed point: 0x04 || X || Y
+		point := ecdhKey.Bytes()[1:]
+		size := len(point) / 2
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(point[:size])
+		jwk.Y = base64.RawURLEncoding.EncodeToString(point[size:])
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return JWK{}, false
+	}
+
+	return jwk, true
+}
+
+// PublicKey decodes a JWK published by someone else, e.g. an OpenID Connect
+// provider.
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch j.Kty {
+	case "RSA":
+		n, err := decode(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := decode(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA exponent: %w", err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		var ecdhCurve ecdh.Curve
+		switch j.Crv {
+		case "P-256":
+			curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, err := decode(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC x coordinate: %w", err)
+		}
+		y, err := decode(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC y coordinate: %w", err)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) > size || len(y) > size {
+			return nil, errors.New("EC coordinates do not fit the curve")
+		}
+		// Let crypto/ecdh check that the point is on the curve
+		point := make([]byte, 1+2*size)
+		point[0] = 4
+		copy(point[1+size-len(x):1+size], x)
+		copy(point[1+2*size-len(y):], y)
+		if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, err := decode(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", j.Kty)
+}
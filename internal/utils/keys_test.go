@@ -0,0 +1,300 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writePEM writes der as a PEM block of the given type and returns its path.
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func mustPKCS8(t *testing.T, key any) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func mustPKIX(t *testing.T, key any) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		blockType string
+		der       []byte
+		alg       string
+		canSign   bool
+	}{
+		{"rsa pkcs8", "PRIVATE KEY", mustPKCS8(t, rsaKey), "RS256", true},
+		{"rsa pkcs1", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), "RS256", true},
+		{"rsa public", "PUBLIC KEY", mustPKIX(t, &rsaKey.PublicKey), "RS256", false},
+		{"rsa pkcs1 public", "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey), "RS256", false},
+		{"ec sec1", "EC PRIVATE KEY", ecDER, "ES384", true},
+		{"ec pkcs8", "PRIVATE KEY", mustPKCS8(t, ecKey), "ES384", true},
+		{"ec public", "PUBLIC KEY", mustPKIX(t, &ecKey.PublicKey), "ES384", false},
+		{"ed25519 pkcs8", "PRIVATE KEY", mustPKCS8(t, edKey), "EdDSA", true},
+		{"ed25519 public", "PUBLIC KEY", mustPKIX(t, edPublic), "EdDSA", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := LoadSigningKey("kid", writePEM(t, "key.pem", tt.blockType, tt.der))
+			if err != nil {
+				t.Fatalf("LoadSigningKey: %v", err)
+			}
+			if key.Method.Alg() != tt.alg {
+				t.Errorf("alg = %s, want %s", key.Method.Alg(), tt.alg)
+			}
+			if (key.sign != nil) != tt.canSign {
+				t.Errorf("can sign = %v, want %v", key.sign != nil, tt.canSign)
+			}
+		})
+	}
+}
+
+func TestLoadSigningKeyErrors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "key.txt")
+	if err := os.WriteFile(notPEM, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"missing file":      filepath.Join(dir, "missing.pem"),
+		"no PEM block":      notPEM,
+		"unsupported block": writePEM(t, "cert.pem", "CERTIFICATE", []byte{1, 2, 3}),
+		"corrupt key":       writePEM(t, "bad.pem", "PRIVATE KEY", []byte{1, 2, 3}),
+	}
+	for name, path := range tests {
+		if _, err := LoadSigningKey("kid", path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadKeyRing(t *testing.T) {
+	_, active, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retired, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	activePath := writePEM(t, "active.pem", "PRIVATE KEY", mustPKCS8(t, active))
+	retiredPath := writePEM(t, "retired.pem", "PUBLIC KEY", mustPKIX(t, &retired.PublicKey))
+
+	ring, err := LoadKeyRing("new", activePath, "", " old = "+retiredPath+" ,")
+	if err != nil {
+		t.Fatalf("LoadKeyRing: %v", err)
+	}
+	if ring.Active().ID != "new" || ring.Active().Method.Alg() != "EdDSA" {
+		t.Errorf("active key = %s %s", ring.Active().ID, ring.Active().Method.Alg())
+	}
+	if key, ok := ring.Lookup("old"); !ok || key.Method.Alg() != "ES256" {
+		t.Errorf("retired key not found")
+	}
+	if key, ok := ring.Lookup(""); !ok || key != ring.Active() {
+		t.Errorf("a token without kid is not checked against the active key")
+	}
+
+	hmac, err := LoadKeyRing("", "", "secret", "")
+	if err != nil {
+		t.Fatalf("LoadKeyRing with a secret: %v", err)
+	}
+	if hmac.Active().Method.Alg() != "HS256" {
+		t.Errorf("alg = %s, want HS256", hmac.Active().Method.Alg())
+	}
+
+	if _, err := LoadKeyRing("", "", "", ""); err == nil {
+		t.Error("expected an error without a key or secret")
+	}
+	if _, err := LoadKeyRing("new", activePath, "", retiredPath); err == nil {
+		t.Error("expected an error for a verification key without kid")
+	}
+	if _, err := LoadKeyRing("new", activePath, "", "new="+retiredPath); err == nil {
+		t.Error("expected an error for a duplicate kid")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active, err := NewSigningKey("rsa", rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec, err := NewSigningKey("ec", &ecKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed, err := NewSigningKey("ed", edPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := NewKeyRing(active, ec, ed, NewHMACKey("hmac", []byte("secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]struct {
+		kty, crv, alg string
+		public        crypto.PublicKey
+	}{
+		"rsa": {"RSA", "", "RS256", &rsaKey.PublicKey},
+		"ec":  {"EC", "P-521", "ES512", &ecKey.PublicKey},
+		"ed":  {"OKP", "Ed25519", "EdDSA", edPublic},
+	}
+
+	set := ring.JWKS()
+	if len(set.Keys) != len(want) {
+		t.Fatalf("JWKS has %d keys, want %d without the HMAC secret", len(set.Keys), len(want))
+	}
+	if set.Keys[0].Kid != "rsa" {
+		t.Errorf("first key = %s, want the active key", set.Keys[0].Kid)
+	}
+	for _, jwk := range set.Keys {
+		w, ok := want[jwk.Kid]
+		if !ok {
+			t.Errorf("unexpected key %s", jwk.Kid)
+			continue
+		}
+		if jwk.Kty != w.kty || jwk.Crv != w.crv || jwk.Alg != w.alg || jwk.Use != "sig" {
+			t.Errorf("%s: kty=%s crv=%s alg=%s use=%s", jwk.Kid, jwk.Kty, jwk.Crv, jwk.Alg, jwk.Use)
+		}
+
+		// Decoding the JWK must give back the same public key.
+		public, err := jwk.PublicKey()
+		if err != nil {
+			t.Errorf("%s: PublicKey: %v", jwk.Kid, err)
+			continue
+		}
+		if !public.(interface{ Equal(crypto.PublicKey) bool }).Equal(w.public) {
+			t.Errorf("%s: decoded key differs", jwk.Kid)
+		}
+	}
+}
+
+func TestJWKRejectsPointOffCurve(t *testing.T) {
+	jwk := JWK{Kty: "EC", Crv: "P-256", X: "AQ", Y: "AQ"}
+	if _, err := jwk.PublicKey(); err == nil {
+		t.Error("expected an error for a point off the curve")
+	}
+}
+
+func TestTokenRoundTrip(t *testing.T) {
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := NewSigningKey("old", oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldRing, err := NewKeyRing(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := GenerateToken(Claims{UserID: "user"}, oldRing, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// After rotation the old key only verifies.
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	active, err := NewSigningKey("new", rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyOnly, err := NewSigningKey("old", oldKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := NewKeyRing(active, verifyOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := ValidateToken(token, ring)
+	if err != nil {
+		t.Fatalf("token signed by a retired key: %v", err)
+	}
+	if claims.UserID != "user" || claims.TokenUse != TokenUseAccess {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	if _, err := ValidateToken(token, mustRing(t, active)); err == nil {
+		t.Error("expected a token of an unknown kid to be rejected")
+	}
+}
+
+func mustRing(t *testing.T, active *SigningKey) *KeyRing {
+	t.Helper()
+
+	ring, err := NewKeyRing(active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ring
+}
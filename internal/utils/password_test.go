@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cheap parameters keep the tests fast; production uses DefaultArgon2Params.
+var testArgon2Params = Argon2Params{
+	Memory:      64,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestArgon2RoundTrip(t *testing.T) {
+	h := NewPasswordHasher(testArgon2Params)
+
+	encoded, err := h.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("hash does not record its parameters: %s", encoded)
+	}
+
+	other, _ := h.Hash("correct horse battery staple")
+	if other == encoded {
+		t.Error("two hashes of the same password share a salt")
+	}
+
+	ok, needsRehash, err := h.Verify("correct horse battery staple", encoded)
+	if err != nil || !ok || needsRehash {
+		t.Errorf("right password: ok=%v needsRehash=%v err=%v", ok, needsRehash, err)
+	}
+
+	ok, needsRehash, err = h.Verify("Correct horse battery staple", encoded)
+	if err != nil || ok || needsRehash {
+		t.Errorf("wrong password: ok=%v needsRehash=%v err=%v", ok, needsRehash, err)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	old := NewPasswordHasher(testArgon2Params)
+	encoded, err := old.Hash("password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed := map[string]func(*Argon2Params){
+		"memory":      func(p *Argon2Params) { p.Memory = 128 },
+		"iterations":  func(p *Argon2Params) { p.Iterations = 2 },
+		"parallelism": func(p *Argon2Params) { p.Parallelism = 2 },
+		"salt length": func(p *Argon2Params) { p.SaltLength = 32 },
+		"key length":  func(p *Argon2Params) { p.KeyLength = 64 },
+	}
+	for name, change := range changed {
+		params := testArgon2Params
+		change(&params)
+		h := NewPasswordHasher(params)
+
+		ok, needsRehash, err := h.Verify("password123", encoded)
+		if err != nil || !ok || !needsRehash {
+			t.Errorf("%s changed: ok=%v needsRehash=%v err=%v", name, ok, needsRehash, err)
+		}
+
+		// A wrong password never asks for a rehash.
+		if _, needsRehash, _ := h.Verify("password124", encoded); needsRehash {
+			t.Errorf("%s changed: wrong password asks for a rehash", name)
+		}
+	}
+}
+
+func TestVerifyLegacyBcrypt(t *testing.T) {
+	h := NewPasswordHasher(testArgon2Params)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := string(hash)
+
+	// $2y$ is what PHP writes for the same algorithm.
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		stored := prefix + encoded[4:]
+
+		ok, needsRehash, err := h.Verify("password123", stored)
+		if err != nil || !ok || !needsRehash {
+			t.Errorf("%s right password: ok=%v needsRehash=%v err=%v", prefix, ok, needsRehash, err)
+		}
+
+		ok, needsRehash, err = h.Verify("password124", stored)
+		if err != nil || ok || needsRehash {
+			t.Errorf("%s wrong password: ok=%v needsRehash=%v err=%v", prefix, ok, needsRehash, err)
+		}
+	}
+
+	// bcrypt only looks at 72 bytes and refuses longer passwords.
+	long := strings.Repeat("a", 100)
+	if ok, _, err := h.Verify(long, encoded); err != nil || ok {
+		t.Errorf("overlong password: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestVerifyWithoutPassword(t *testing.T) {
+	h := NewPasswordHasher(testArgon2Params)
+
+	ok, needsRehash, err := h.Verify("", "")
+	if err != nil || ok || needsRehash {
+		t.Errorf("account without password: ok=%v needsRehash=%v err=%v", ok, needsRehash, err)
+	}
+}
+
+func TestVerifyRejectsMalformedHashes(t *testing.T) {
+	h := NewPasswordHasher(testArgon2Params)
+
+	const salt, key = "c29tZXNhbHRzb21lc2FsdA", "YWJjZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXphYmNkZWY"
+	tests := map[string]string{
+		"unknown algorithm":    "$argon2i$v=19$m=64,t=1,p=1$" + salt + "$" + key,
+		"plain text":           "password123",
+		"missing part":         "$argon2id$v=19$m=64,t=1,p=1$" + salt,
+		"bad version field":    "$argon2id$19$m=64,t=1,p=1$" + salt + "$" + key,
+		"bad parameters":       "$argon2id$v=19$m=64;t=1;p=1$" + salt + "$" + key,
+		"zero memory":          "$argon2id$v=19$m=0,t=1,p=1$" + salt + "$" + key,
+		"zero iterations":      "$argon2id$v=19$m=64,t=0,p=1$" + salt + "$" + key,
+		"zero parallelism":     "$argon2id$v=19$m=64,t=1,p=0$" + salt + "$" + key,
+		"parallelism overflow": "$argon2id$v=19$m=64,t=1,p=256$" + salt + "$" + key,
+		"bad salt":             "$argon2id$v=19$m=64,t=1,p=1$!!!$" + key,
+		"empty salt":           "$argon2id$v=19$m=64,t=1,p=1$$" + key,
+		"empty key":            "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$",
+	}
+
+	for name, encoded := range tests {
+		ok, _, err := h.Verify("password123", encoded)
+		if ok || !errors.Is(err, ErrUnknownPasswordHash) {
+			t.Errorf("%s: ok=%v err=%v, want ErrUnknownPasswordHash", name, ok, err)
+		}
+	}
+
+	if _, _, err := h.Verify("password123", "$argon2id$v=16$m=64,t=1,p=1$"+salt+"$"+key); err == nil {
+		t.Error("unsupported version: expected an error")
+	}
+}
@@ -1,12 +1,57 @@
 package utils
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ochko-b/goapp/internal/i18n"
+	"github.com/ochko-b/goapp/pkg/validator"
+)
 
 type APIResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message,omitempty"`
 	Data    any    `json:"data,omitempty"`
-	Error   string `json:"error,omitempty"`
+}
+
+// MIMEApplicationProblemJSON is the content type of error responses.
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// Problem types. Errors without a more specific type use about:blank, where
+// the title is the status text.
+const (
+	ProblemTypeBlank      = "about:blank"
+	ProblemTypeValidation = "/problems/validation-error"
+)
+
+// RequestIDKey is the local the request ID middleware stores the ID under.
+const RequestIDKey = "requestid"
+
+// LocaleKey is the local the locale middleware stores the language of
+// messages under.
+const LocaleKey = "locale"
+
+// Locale returns the language of messages for the request.
+func Locale(c *fiber.Ctx) string {
+	if locale, ok := c.Locals(LocaleKey).(string); ok && locale != "" {
+		return locale
+	}
+	return validator.DefaultLocale
+}
+
+// Problem is an RFC 9457 problem details object. Code, RequestID and Errors
+// are extension members. Code identifies the error for clients, as the title
+// and detail are in the language of the request.
+type Problem struct {
+	Type      string                 `json:"type"`
+	Title     string                 `json:"title"`
+	Status    int                    `json:"status"`
+	Detail    string                 `json:"detail,omitempty"`
+	Instance  string                 `json:"instance,omitempty"`
+	Code      string                 `json:"code,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Errors    []validator.FieldError `json:"errors,omitempty"`
 }
 
 func SuccessResponse(c *fiber.Ctx, data any, message ...string) error {
@@ -21,10 +66,40 @@ func SuccessResponse(c *fiber.Ctx, data any, message ...string) error {
 	return c.JSON(response)
 }
 
+// ErrorResponse writes an about:blank problem with err as the detail.
 func ErrorResponse(c *fiber.Ctx, status int, err string) error {
-	response := APIResponse{
-		Success: false,
-		Message: err,
+	return ProblemResponse(c, Problem{
+		Type:   ProblemTypeBlank,
+		Status: status,
+		Detail: err,
+	})
+}
+
+// ValidationErrorResponse writes a 400 problem that lists each invalid field,
+// so clients can show the messages next to the inputs.
+func ValidationErrorResponse(c *fiber.Ctx, detail string, fields []validator.FieldError) error {
+	return ProblemResponse(c, Problem{
+		Type:   ProblemTypeValidation,
+		Title:  i18n.T(Locale(c), "titles.validation", "Your request has invalid fields"),
+		Status: fiber.StatusBadRequest,
+		Detail: detail,
+		Errors: fields,
+	})
+}
+
+// ProblemResponse writes p, filling in the instance and request ID. A
+// missing title is the status text in the language of the request.
+func ProblemResponse(c *fiber.Ctx, p Problem) error {
+	locale := Locale(c)
+	if p.Title == "" {
+		p.Title = i18n.T(locale, "titles."+strconv.Itoa(p.Status), http.StatusText(p.Status))
+	}
+	if p.Instance == "" {
+		p.Instance = c.Path()
+	}
+	if id, ok := c.Locals(RequestIDKey).(string); ok {
+		p.RequestID = id
 	}
-	return c.Status(status).JSON(response)
+	c.Set(fiber.HeaderContentLanguage, locale)
+	return c.Status(p.Status).JSON(p, MIMEApplicationProblemJSON)
 }
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The ASCII secret "12345678901234567890" of the RFC test vectors.
+var rfcSecret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+// RFC 4226, Appendix D.
+func TestHOTPVectors(t *testing.T) {
+	want := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+
+	for counter, code := range want {
+		if got := hotp([]byte("12345678901234567890"), int64(counter)); got != code {
+			t.Errorf("counter %d: got %s, want %s", counter, got, code)
+		}
+	}
+}
+
+// RFC 6238, Appendix B, SHA-1. The RFC lists eight digits; six-digit codes
+// are the last six of them.
+func TestTOTPVectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		step int64
+		code string
+	}{
+		{59, 0x1, "287082"},
+		{1111111109, 0x23523EC, "081804"},
+		{1111111111, 0x23523ED, "050471"},
+		{1234567890, 0x273EF07, "005924"},
+		{2000000000, 0x3F940AA, "279037"},
+		{20000000000, 0x27BC86AA, "353130"},
+	}
+
+	for _, tt := range tests {
+		step, ok := ValidateTOTP(rfcSecret, tt.code, time.Unix(tt.unix, 0))
+		if !ok {
+			t.Errorf("t=%d: code %s rejected", tt.unix, tt.code)
+			continue
+		}
+		if step != tt.step {
+			t.Errorf("t=%d: step = %#x, want %#x", tt.unix, step, tt.step)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code := hotp([]byte("12345678901234567890"), now.Unix()/totpPeriod)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		at     time.Time
+		ok     bool
+	}{
+		{"current step", rfcSecret, code, now, true},
+		{"lower-case secret", strings.ToLower(rfcSecret), code, now, true},
+		{"one step of drift early", rfcSecret, code, now.Add(-totpPeriod * time.Second), true},
+		{"one step of drift late", rfcSecret, code, now.Add(totpPeriod * time.Second), true},
+		{"two steps late", rfcSecret, code, now.Add(2 * totpPeriod * time.Second), false},
+		{"wrong code", rfcSecret, "000000", now, false},
+		{"too short", rfcSecret, code[:5], now, false},
+		{"too long", rfcSecret, code + "0", now, false},
+		{"invalid secret", "not base32!", code, now, false},
+	}
+
+	for _, tt := range tests {
+		if _, ok := ValidateTOTP(tt.secret, tt.code, tt.at); ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+		}
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("secret %q does not decode to 160 bits", secret)
+	}
+
+	other, _ := GenerateTOTPSecret()
+	if other == secret {
+		t.Error("two secrets are equal")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Go App", "jane@example.com", "JBSWY3DPEHPK3PXP")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Go%20App:jane@example.com?") {
+		t.Errorf("label not escaped as authenticator apps expect: %s", uri)
+	}
+	if strings.Contains(uri, "+") {
+		t.Errorf("spaces encoded as +: %s", uri)
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	want := map[string]string{
+		"secret":    "JBSWY3DPEHPK3PXP",
+		"issuer":    "Go App",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+	for key, value := range want {
+		if q.Get(key) != value {
+			t.Errorf("%s = %q, want %q", key, q.Get(key), value)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// softAuthenticator is a software authenticator for tests. It creates one
+// credential and signs registration and authentication responses the way a
+// browser would pass them on.
+type softAuthenticator struct {
+	t      *testing.T
+	rpID   string
+	origin string
+
+	credentialID []byte
+	alg          int64
+	ecKey        *ecdsa.PrivateKey
+	edKey        ed25519.PrivateKey
+	signCount    uint32
+	userHandle   []byte
+
+	// flags, when set, replaces the authenticator data flags
+	flags *byte
+}
+
+func newSoftAuthenticator(t *testing.T, rpID, origin string, alg int64) *softAuthenticator {
+	t.Helper()
+
+	a := &softAuthenticator{
+		t:            t,
+		rpID:         rpID,
+		origin:       origin,
+		credentialID: randomBytes(t, 16),
+		alg:          alg,
+	}
+
+	var err error
+	switch alg {
+	case AlgES256:
+		a.ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, a.edKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		t.Fatalf("unsupported algorithm %d", alg)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func (a *softAuthenticator) coseKey() []byte {
+	if a.alg == AlgEdDSA {
+		return encodeCBOR(cborMap{
+			{int64(coseKeyType), int64(coseKeyTypeOKP)},
+			{int64(coseKeyAlg), AlgEdDSA},
+			{int64(coseKeyCrv), int64(coseCurveEd25519)},
+			{int64(coseKeyX), []byte(a.edKey.Public().(ed25519.PublicKey))},
+		})
+	}
+
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.ecKey.X.FillBytes(x)
+	a.ecKey.Y.FillBytes(y)
+	return encodeCBOR(cborMap{
+		{int64(coseKeyType), int64(coseKeyTypeEC2)},
+		{int64(coseKeyAlg), AlgES256},
+		{int64(coseKeyCrv), int64(coseCurveP256)},
+		{int64(coseKeyX), x},
+		{int64(coseKeyY), y},
+	})
+}
+
+func (a *softAuthenticator) sign(message []byte) []byte {
+	if a.alg == AlgEdDSA {
+		return ed25519.Sign(a.edKey, message)
+	}
+	digest := sha256.Sum256(message)
+	signature, err := ecdsa.SignASN1(rand.Reader, a.ecKey, digest[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return signature
+}
+
+func (a *softAuthenticator) authenticatorData(attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+
+	flags := byte(flagUserPresent | flagUserVerified)
+	if attested {
+		flags |= flagAttestedCredData
+	}
+	if a.flags != nil {
+		flags = *a.flags
+	}
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...) // AAGUID of a software authenticator
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.coseKey()...)
+	}
+	return data
+}
+
+func (a *softAuthenticator) clientData(ceremony string, challenge []byte) []byte {
+	data, err := json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      a.origin,
+		"crossOrigin": false,
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return data
+}
+
+// register answers navigator.credentials.create() with the given
+// attestation format: "none", "packed" (self attestation) or "packed-x5c".
+func (a *softAuthenticator) register(challenge []byte, format string) *RegistrationResponse {
+	clientDataJSON := a.clientData(ceremonyCreate, challenge)
+	authData := a.authenticatorData(true)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+
+	var statement cborMap
+	switch format {
+	case "none":
+		statement = cborMap{}
+	case "packed":
+		statement = cborMap{{"alg", a.alg}, {"sig", a.sign(signed)}}
+	case "packed-x5c":
+		format = "packed"
+		certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			a.t.Fatal(err)
+		}
+		digest := sha256.Sum256(signed)
+		signature, err := ecdsa.SignASN1(rand.Reader, certKey, digest[:])
+		if err != nil {
+			a.t.Fatal(err)
+		}
+		statement = cborMap{{"alg", AlgES256}, {"sig", signature}, {"x5c", []any{selfSignedCertificate(a.t, certKey)}}}
+	default:
+		statement = cborMap{}
+	}
+
+	resp := &RegistrationResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		RawID: a.credentialID,
+		Type:  credentialType,
+	}
+	resp.Response.ClientDataJSON = clientDataJSON
+	resp.Response.AttestationObject = encodeCBOR(cborMap{
+		{"fmt", format},
+		{"attStmt", statement},
+		{"authData", authData},
+	})
+	resp.Response.Transports = []string{"internal"}
+	return resp
+}
+
+// assert answers navigator.credentials.get(), bumping the signature counter.
+func (a *softAuthenticator) assert(challenge []byte) *AssertionResponse {
+	a.signCount++
+
+	clientDataJSON := a.clientData(ceremonyGet, challenge)
+	authData := a.authenticatorData(false)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+
+	resp := &AssertionResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		RawID: a.credentialID,
+		Type:  credentialType,
+	}
+	resp.Response.ClientDataJSON = clientDataJSON
+	resp.Response.AuthenticatorData = authData
+	resp.Response.Signature = a.sign(append(append([]byte{}, authData...), clientDataHash[:]...))
+	resp.Response.UserHandle = a.userHandle
+	return resp
+}
+
+func selfSignedCertificate(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Test Authenticator Attestation", OrganizationalUnit: []string{"Authenticator Attestation"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// cborMap is a map with its keys in encoding order.
+type cborMap []struct {
+	key   any
+	value any
+}
+
+// encodeCBOR encodes the values the tests need: integers, byte and text
+// strings, arrays and maps.
+func encodeCBOR(v any) []byte {
+	switch v := v.(type) {
+	case int64:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case []any:
+		out := cborHead(4, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	case cborMap:
+		out := cborHead(5, uint64(len(v)))
+		for _, pair := range v {
+			out = append(out, encodeCBOR(pair.key)...)
+			out = append(out, encodeCBOR(pair.value)...)
+		}
+		return out
+	}
+	panic("encodeCBOR: unsupported type")
+}
+
+func cborHead(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+	case arg <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, arg)
+}
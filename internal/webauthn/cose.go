@@ -0,0 +1,148 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers (RFC 9053) of the supported credential keys
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// SupportedAlgorithms are offered to authenticators in order of preference.
+var SupportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE key parameters (RFC 9052 section 7 and RFC 9053 section 7)
+const (
+	coseKeyType = 1
+	coseKeyAlg  = 3
+	coseKeyCrv  = -1
+	coseKeyX    = -2
+	coseKeyY    = -3
+	coseKeyN    = -1
+	coseKeyE    = -2
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+const minRSAKeyBits = 2048
+
+// publicKey is a credential public key decoded from its COSE form.
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// parseCOSEKey decodes a COSE_Key and returns the bytes that follow it.
+func parseCOSEKey(data []byte) (*publicKey, []byte, error) {
+	item, rest, err := decodeCBOR(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	params, ok := item.(map[any]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: public key is not a COSE key", ErrUnsupportedKey)
+	}
+
+	kty, _ := params[int64(coseKeyType)].(int64)
+	alg, _ := params[int64(coseKeyAlg)].(int64)
+	crv, _ := params[int64(coseKeyCrv)].(int64)
+
+	switch {
+	case kty == coseKeyTypeEC2 && alg == AlgES256 && crv == coseCurveP256:
+		x, _ := params[int64(coseKeyX)].([]byte)
+		y, _ := params[int64(coseKeyY)].([]byte)
+		if len(x) != 32 || len(y) != 32 {
+			return nil, nil, fmt.Errorf("%w: bad P-256 coordinates", ErrUnsupportedKey)
+		}
+		// crypto/ecdh rejects points that are not on the curve
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrUnsupportedKey, err)
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		return &publicKey{alg: alg, key: key}, rest, nil
+
+	case kty == coseKeyTypeOKP && alg == AlgEdDSA && crv == coseCurveEd25519:
+		x, _ := params[int64(coseKeyX)].([]byte)
+		if len(x) != ed25519.PublicKeySize {
+			return nil, nil, fmt.Errorf("%w: bad Ed25519 key", ErrUnsupportedKey)
+		}
+		return &publicKey{alg: alg, key: ed25519.PublicKey(x)}, rest, nil
+
+	case kty == coseKeyTypeRSA && alg == AlgRS256:
+		n, _ := params[int64(coseKeyN)].([]byte)
+		e, _ := params[int64(coseKeyE)].([]byte)
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, nil, fmt.Errorf("%w: bad RSA exponent", ErrUnsupportedKey)
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+		if key.N.BitLen() < minRSAKeyBits {
+			return nil, nil, fmt.Errorf("%w: RSA key too short", ErrUnsupportedKey)
+		}
+		return &publicKey{alg: alg, key: key}, rest, nil
+	}
+
+	return nil, nil, fmt.Errorf("%w: key type %d with algorithm %d", ErrUnsupportedKey, kty, alg)
+}
+
+// verify checks a signature made with the key over message.
+func (k *publicKey) verify(message, signature []byte) error {
+	if !verifySignature(k.alg, k.key, message, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// verifySignature checks a signature by a COSE algorithm. Attestation
+// certificates go through here too, so the key type is checked rather than
+// assumed from alg.
+func verifySignature(alg int64, key crypto.PublicKey, message, signature []byte) bool {
+	switch alg {
+	case AlgES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return false
+		}
+		digest := sha256.Sum256(message)
+		return ecdsa.VerifyASN1(pub, digest[:], signature)
+	case AlgEdDSA:
+		pub, ok := key.(ed25519.PublicKey)
+		return ok && ed25519.Verify(pub, message, signature)
+	case AlgRS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		digest := sha256.Sum256(message)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	}
+	return false
+}
+
+// certificateKey returns the public key of a DER encoded attestation
+// certificate.
+func certificateKey(der []byte) (crypto.PublicKey, error) {
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return cert.PublicKey, nil
+}
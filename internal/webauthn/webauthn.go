@@ -0,0 +1,508 @@
+// Package webauthn implements the relying party side of WebAuthn Level 2:
+// the registration and authentication ceremonies of passkeys and security
+// keys. It builds the options passed to navigator.credentials.create() and
+// get(), and verifies what the browser sends back.
+//
+// Attestation is requested as "none". The "packed" format is still checked
+// for a valid signature, but attestation certificates are not chained to
+// any trust anchor, so no claims about the authenticator model are made.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ochko-b/goapp/internal/config"
+)
+
+var (
+	ErrInvalidResponse        = errors.New("webauthn: invalid credential response")
+	ErrUserNotPresent         = errors.New("webauthn: user presence was not confirmed")
+	ErrUserNotVerified        = errors.New("webauthn: user was not verified")
+	ErrInvalidSignature       = errors.New("webauthn: invalid signature")
+	ErrSignCountRegressed     = errors.New("webauthn: signature counter did not increase; the authenticator may be cloned")
+	ErrUnsupportedAttestation = errors.New("webauthn: unsupported attestation format")
+	ErrUnsupportedKey         = errors.New("webauthn: unsupported public key")
+)
+
+// Client data types
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+)
+
+// User verification requirements
+const (
+	VerificationRequired    = "required"
+	VerificationPreferred   = "preferred"
+	VerificationDiscouraged = "discouraged"
+)
+
+// Authenticator data flags (WebAuthn section 6.1)
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagAttestedCredData = 0x40
+	flagExtensionData    = 0x80
+)
+
+const (
+	challengeLength       = 32
+	maxCredentialIDLength = 1023
+	credentialType        = "public-key"
+)
+
+// Bytes is binary data in the JSON serialization WebAuthn clients use:
+// base64url without padding. Padded input is accepted as well.
+type Bytes []byte
+
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// NewChallenge returns a random challenge for one ceremony.
+func NewChallenge() (Bytes, error) {
+	challenge := make([]byte, challengeLength)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity identifies the account a credential is created for. ID is the
+// user handle: opaque, and stored by discoverable credentials so a login can
+// find the account without a username.
+type UserEntity struct {
+	ID          Bytes  `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// CredentialDescriptor names an existing credential, to exclude it from
+// registration or to allow it for authentication.
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         Bytes    `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey,omitempty"`
+	UserVerification string `json:"userVerification,omitempty"`
+}
+
+// CreationOptions are the PublicKeyCredentialCreationOptions of a
+// registration ceremony.
+type CreationOptions struct {
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              Bytes                  `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are the PublicKeyCredentialRequestOptions of an
+// authentication ceremony. Without AllowCredentials the authenticator offers
+// its discoverable credentials for the relying party.
+type RequestOptions struct {
+	Challenge        Bytes                  `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials,omitempty"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationResponse is the PublicKeyCredential returned by
+// navigator.credentials.create(), in its JSON serialization.
+type RegistrationResponse struct {
+	ID       string `json:"id"`
+	RawID    Bytes  `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    Bytes    `json:"clientDataJSON"`
+		AttestationObject Bytes    `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the PublicKeyCredential returned by
+// navigator.credentials.get(), in its JSON serialization.
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	RawID    Bytes  `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    Bytes `json:"clientDataJSON"`
+		AuthenticatorData Bytes `json:"authenticatorData"`
+		Signature         Bytes `json:"signature"`
+		UserHandle        Bytes `json:"userHandle"`
+	} `json:"response"`
+}
+
+// Challenge returns the challenge the client signed, so the caller can find
+// the ceremony the response belongs to. It is not verified yet.
+func (r *RegistrationResponse) Challenge() ([]byte, error) {
+	return clientDataChallenge(r.Response.ClientDataJSON)
+}
+
+// Challenge returns the challenge the client signed, so the caller can find
+// the ceremony the response belongs to. It is not verified yet.
+func (r *AssertionResponse) Challenge() ([]byte, error) {
+	return clientDataChallenge(r.Response.ClientDataJSON)
+}
+
+// Credential is what a relying party stores about a registered credential.
+// PublicKey stays in its COSE encoding.
+type Credential struct {
+	ID         []byte
+	PublicKey  []byte
+	SignCount  uint32
+	Transports []string
+	AAGUID     []byte
+}
+
+// RelyingParty runs the ceremonies for one relying party ID.
+type RelyingParty struct {
+	id       string
+	name     string
+	origins  []string
+	timeout  int64
+	rpIDHash [32]byte
+}
+
+func New(cfg config.WebAuthnConfig) *RelyingParty {
+	var origins []string
+	for _, origin := range cfg.Origins {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	return &RelyingParty{
+		id:       cfg.RPID,
+		name:     cfg.RPName,
+		origins:  origins,
+		timeout:  cfg.Timeout.Milliseconds(),
+		rpIDHash: sha256.Sum256([]byte(cfg.RPID)),
+	}
+}
+
+// Timeout is how long the browser is asked to wait for the user.
+func (rp *RelyingParty) Timeout() time.Duration {
+	return time.Duration(rp.timeout) * time.Millisecond
+}
+
+// CreationOptions builds the options of a registration ceremony. Credentials
+// the user already has are excluded, so one authenticator is not registered
+// twice. Discoverable credentials are preferred, so they can be used without
+// typing an email address.
+func (rp *RelyingParty) CreationOptions(user UserEntity, challenge Bytes, exclude []CredentialDescriptor) *CreationOptions {
+	params := make([]CredentialParameter, 0, len(SupportedAlgorithms))
+	for _, alg := range SupportedAlgorithms {
+		params = append(params, CredentialParameter{Type: credentialType, Alg: alg})
+	}
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+
+	return &CreationOptions{
+		RP:                 RelyingPartyEntity{ID: rp.id, Name: rp.name},
+		User:               user,
+		Challenge:          challenge,
+		PubKeyCredParams:   params,
+		Timeout:            rp.timeout,
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: VerificationPreferred,
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions builds the options of an authentication ceremony.
+func (rp *RelyingParty) RequestOptions(challenge Bytes, allow []CredentialDescriptor, userVerification string) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.timeout,
+		RPID:             rp.id,
+		AllowCredentials: allow,
+		UserVerification: userVerification,
+	}
+}
+
+// VerifyRegistration checks a registration response against the challenge
+// of its ceremony (WebAuthn section 7.1) and returns the new credential.
+func (rp *RelyingParty) VerifyRegistration(resp *RegistrationResponse, challenge []byte, requireUserVerification bool) (*Credential, error) {
+	if resp.Type != credentialType {
+		return nil, fmt.Errorf("%w: unexpected credential type %q", ErrInvalidResponse, resp.Type)
+	}
+	if err := rp.verifyClientData(resp.Response.ClientDataJSON, ceremonyCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	item, rest, err := decodeCBOR(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+	attestation, ok := item.(map[any]any)
+	if !ok || len(rest) != 0 {
+		return nil, fmt.Errorf("%w: malformed attestation object", ErrInvalidResponse)
+	}
+	format, _ := attestation["fmt"].(string)
+	statement, _ := attestation["attStmt"].(map[any]any)
+	rawAuthData, _ := attestation["authData"].([]byte)
+	if statement == nil {
+		return nil, fmt.Errorf("%w: missing attestation statement", ErrInvalidResponse)
+	}
+
+	authData, err := rp.parseAuthenticatorData(rawAuthData, requireUserVerification)
+	if err != nil {
+		return nil, err
+	}
+	if authData.flags&flagAttestedCredData == 0 {
+		return nil, fmt.Errorf("%w: no attested credential data", ErrInvalidResponse)
+	}
+	if !bytes.Equal(authData.credentialID, resp.RawID) {
+		return nil, fmt.Errorf("%w: credential ID mismatch", ErrInvalidResponse)
+	}
+
+	key, _, err := parseCOSEKey(authData.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signed := append(slices.Clip(rawAuthData), clientDataHash[:]...)
+	if err := verifyAttestation(format, statement, key, signed); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:         authData.credentialID,
+		PublicKey:  authData.publicKey,
+		SignCount:  authData.signCount,
+		Transports: resp.Response.Transports,
+		AAGUID:     authData.aaguid,
+	}, nil
+}
+
+// VerifyAssertion checks an authentication response against the challenge
+// of its ceremony and the stored credential it claims to come from (WebAuthn
+// section 7.2). It returns the new signature counter to store.
+func (rp *RelyingParty) VerifyAssertion(resp *AssertionResponse, challenge []byte, credential Credential, requireUserVerification bool) (uint32, error) {
+	if resp.Type != credentialType {
+		return 0, fmt.Errorf("%w: unexpected credential type %q", ErrInvalidResponse, resp.Type)
+	}
+	if !bytes.Equal(resp.RawID, credential.ID) {
+		return 0, fmt.Errorf("%w: credential ID mismatch", ErrInvalidResponse)
+	}
+	if err := rp.verifyClientData(resp.Response.ClientDataJSON, ceremonyGet, challenge); err != nil {
+		return 0, err
+	}
+
+	authData, err := rp.parseAuthenticatorData(resp.Response.AuthenticatorData, requireUserVerification)
+	if err != nil {
+		return 0, err
+	}
+
+	key, _, err := parseCOSEKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signed := append(slices.Clip([]byte(resp.Response.AuthenticatorData)), clientDataHash[:]...)
+	if err := key.verify(signed, resp.Response.Signature); err != nil {
+		return 0, err
+	}
+
+	// Authenticators without a counter always report zero
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return 0, ErrSignCountRegressed
+	}
+
+	return authData.signCount, nil
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+func parseClientData(raw []byte) (*clientData, []byte, error) {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, nil, fmt.Errorf("%w: malformed client data", ErrInvalidResponse)
+	}
+	challenge, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(data.Challenge, "="))
+	if err != nil || len(challenge) == 0 {
+		return nil, nil, fmt.Errorf("%w: malformed challenge", ErrInvalidResponse)
+	}
+	return &data, challenge, nil
+}
+
+func clientDataChallenge(raw []byte) ([]byte, error) {
+	_, challenge, err := parseClientData(raw)
+	return challenge, err
+}
+
+func (rp *RelyingParty) verifyClientData(raw []byte, ceremony string, challenge []byte) error {
+	data, signed, err := parseClientData(raw)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case data.Type != ceremony:
+		return fmt.Errorf("%w: unexpected client data type %q", ErrInvalidResponse, data.Type)
+	case subtle.ConstantTimeCompare(signed, challenge) != 1:
+		return fmt.Errorf("%w: challenge mismatch", ErrInvalidResponse)
+	case !slices.Contains(rp.origins, data.Origin):
+		return fmt.Errorf("%w: unexpected origin %q", ErrInvalidResponse, data.Origin)
+	}
+	return nil
+}
+
+type authenticatorData struct {
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	// publicKey is the credential public key, still COSE encoded
+	publicKey []byte
+}
+
+// parseAuthenticatorData decodes the authenticator data (WebAuthn section
+// 6.1) and checks the parts that do not depend on the ceremony.
+func (rp *RelyingParty) parseAuthenticatorData(data []byte, requireUserVerification bool) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, fmt.Errorf("%w: authenticator data too short", ErrInvalidResponse)
+	}
+	if subtle.ConstantTimeCompare(data[:32], rp.rpIDHash[:]) != 1 {
+		return nil, fmt.Errorf("%w: credential is scoped to another relying party", ErrInvalidResponse)
+	}
+
+	authData := &authenticatorData{
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return nil, ErrUserNotPresent
+	}
+	if requireUserVerification && authData.flags&flagUserVerified == 0 {
+		return nil, ErrUserNotVerified
+	}
+
+	rest := data[37:]
+	if authData.flags&flagAttestedCredData != 0 {
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("%w: attested credential data too short", ErrInvalidResponse)
+		}
+		authData.aaguid = rest[:16:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength == 0 || idLength > maxCredentialIDLength || len(rest) < idLength {
+			return nil, fmt.Errorf("%w: bad credential ID length", ErrInvalidResponse)
+		}
+		authData.credentialID = rest[:idLength:idLength]
+		rest = rest[idLength:]
+
+		_, after, err := parseCOSEKey(rest)
+		if err != nil {
+			return nil, err
+		}
+		authData.publicKey = rest[: len(rest)-len(after) : len(rest)-len(after)]
+		rest = after
+	}
+
+	if authData.flags&flagExtensionData != 0 {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+		rest = after
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing bytes in authenticator data", ErrInvalidResponse)
+	}
+
+	return authData, nil
+}
+
+// verifyAttestation checks the attestation statement signature. signed is
+// the authenticator data followed by the client data hash.
+func verifyAttestation(format string, statement map[any]any, key *publicKey, signed []byte) error {
+	switch format {
+	case "none":
+		if len(statement) != 0 {
+			return fmt.Errorf("%w: none attestation with a statement", ErrInvalidResponse)
+		}
+		return nil
+
+	case "packed":
+		alg, _ := statement["alg"].(int64)
+		signature, _ := statement["sig"].([]byte)
+		chain, hasChain := statement["x5c"].([]any)
+
+		// Self attestation is signed with the credential key itself
+		if !hasChain {
+			if alg != key.alg {
+				return fmt.Errorf("%w: self attestation algorithm mismatch", ErrInvalidResponse)
+			}
+			return key.verify(signed, signature)
+		}
+
+		if len(chain) == 0 {
+			return fmt.Errorf("%w: empty attestation certificate chain", ErrInvalidResponse)
+		}
+		der, _ := chain[0].([]byte)
+		certKey, err := certificateKey(der)
+		if err != nil {
+			return fmt.Errorf("%w: bad attestation certificate: %v", ErrInvalidResponse, err)
+		}
+		if !verifySignature(alg, certKey, signed, signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%w: %q", ErrUnsupportedAttestation, format)
+}
@@ -0,0 +1,255 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ochko-b/goapp/internal/config"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://app.example.com"
+)
+
+func newTestRelyingParty() *RelyingParty {
+	return New(config.WebAuthnConfig{
+		RPID:    testRPID,
+		RPName:  "Example",
+		Origins: []string{testOrigin + "/"},
+		Timeout: time.Minute,
+	})
+}
+
+func newTestChallenge(t *testing.T) Bytes {
+	t.Helper()
+
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return challenge
+}
+
+// registerCredential runs a registration ceremony and returns the stored
+// credential.
+func registerCredential(t *testing.T, rp *RelyingParty, a *softAuthenticator) Credential {
+	t.Helper()
+
+	challenge := newTestChallenge(t)
+	credential, err := rp.VerifyRegistration(a.register(challenge, "none"), challenge, true)
+	if err != nil {
+		t.Fatalf("registration failed: %v", err)
+	}
+	return *credential
+}
+
+func TestRegistrationAndLogin(t *testing.T) {
+	for _, alg := range []int64{AlgES256, AlgEdDSA} {
+		rp := newTestRelyingParty()
+		a := newSoftAuthenticator(t, testRPID, testOrigin, alg)
+
+		credential := registerCredential(t, rp, a)
+		if string(credential.ID) != string(a.credentialID) {
+			t.Fatalf("alg %d: credential ID = %x, want %x", alg, credential.ID, a.credentialID)
+		}
+		if len(credential.Transports) != 1 || credential.Transports[0] != "internal" {
+			t.Fatalf("alg %d: transports = %v", alg, credential.Transports)
+		}
+
+		for i := 1; i <= 2; i++ {
+			challenge := newTestChallenge(t)
+			signCount, err := rp.VerifyAssertion(a.assert(challenge), challenge, credential, true)
+			if err != nil {
+				t.Fatalf("alg %d: assertion %d failed: %v", alg, i, err)
+			}
+			if signCount != uint32(i) {
+				t.Fatalf("alg %d: sign count = %d, want %d", alg, signCount, i)
+			}
+			credential.SignCount = signCount
+		}
+	}
+}
+
+func TestRegistrationWithPackedAttestation(t *testing.T) {
+	rp := newTestRelyingParty()
+
+	for _, format := range []string{"packed", "packed-x5c"} {
+		a := newSoftAuthenticator(t, testRPID, testOrigin, AlgES256)
+		challenge := newTestChallenge(t)
+		if _, err := rp.VerifyRegistration(a.register(challenge, format), challenge, true); err != nil {
+			t.Fatalf("%s: registration failed: %v", format, err)
+		}
+	}
+}
+
+func TestRegistrationRejectsTamperedAttestation(t *testing.T) {
+	rp := newTestRelyingParty()
+	a := newSoftAuthenticator(t, testRPID, testOrigin, AlgES256)
+	challenge := newTestChallenge(t)
+
+	resp := a.register(challenge, "packed")
+	// The client data hash is part of the signed message
+	resp.Response.ClientDataJSON = []byte(strings.Replace(string(resp.Response.ClientDataJSON), `"crossOrigin":false`, `"crossOrigin":false,"extra":1`, 1))
+
+	if _, err := rp.VerifyRegistration(resp, challenge, true); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("err = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestRegistrationRejectsUnsupportedAttestation(t *testing.T) {
+	rp := newTestRelyingParty()
+	a := newSoftAuthenticator(t, testRPID, testOrigin, AlgES256)
+	challenge := newTestChallenge(t)
+
+	if _, err := rp.VerifyRegistration(a.register(challenge, "fido-u2f"), challenge, true); !errors.Is(err, ErrUnsupportedAttestation) {
+		t.Fatalf("err = %v, want ErrUnsupportedAttestation", err)
+	}
+}
+
+func TestRegistrationRejectsBadClientData(t *testing.T) {
+	rp := newTestRelyingParty()
+	challenge := newTestChallenge(t)
+
+	tests := map[string]func(a *softAuthenticator) *RegistrationResponse{
+		"other challenge": func(a *softAuthenticator) *RegistrationResponse {
+			return a.register(newTestChallenge(t), "none")
+		},
+		"other origin": func(a *softAuthenticator) *RegistrationResponse {
+			a.origin = "https://evil.example.net"
+			return a.register(challenge, "none")
+		},
+		"other relying party": func(a *softAuthenticator) *RegistrationResponse {
+			a.rpID = "evil.example.net"
+			return a.register(challenge, "none")
+		},
+		"assertion client data": func(a *softAuthenticator) *RegistrationResponse {
+			resp := a.register(challenge, "none")
+			resp.Response.ClientDataJSON = a.clientData(ceremonyGet, challenge)
+			return resp
+		},
+		"credential ID mismatch": func(a *softAuthenticator) *RegistrationResponse {
+			resp := a.register(challenge, "none")
+			resp.RawID = randomBytes(t, 16)
+			return resp
+		},
+	}
+
+	for name, build := range tests {
+		a := newSoftAuthenticator(t, testRPID, testOrigin, AlgES256)
+		if _, err := rp.VerifyRegistration(build(a), challenge, false); !errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("%s: err = %v, want ErrInvalidResponse", name, err)
+		}
+	}
+}
+
+func TestUserVerificationRequired(t *testing.T) {
+	rp := newTestRelyingParty()
+	a := newSoftAuthenticator(t, testRPID, testOrigin, AlgES256)
+	credential := registerCredential(t, rp, a)
+
+	presentOnly := byte(flagUserPresent)
+	a.flags = &presentOnly
+
+	challenge := newTestChallenge(t)
+	if _, err := rp.VerifyAssertion(a.assert(challenge), challenge, credential, true); !errors.Is(err, ErrUserNotVerified) {
+		t.Fatalf("err = %v, want ErrUserNotVerified", err)
+	}
+
+	// As a second factor, presence is enough
+	challenge = newTestChallenge(t)
+	if _, err := rp.VerifyAssertion(a.assert(challenge), challenge, credential, false); err != nil {
+		t.Fatalf("assertion without user verification failed: %v", err)
+	}
+
+	none := byte(0)
+	a.flags = &none
+	challenge = newTestChallenge(t)
+	if _, err := rp.VerifyAssertion(a.assert(challenge), challenge, credential, false); !errors.Is(err, ErrUserNotPresent) {
+		t.Fatalf("err = %v, want ErrUserNotPresent", err)
+	}
+}
+
+func TestAssertionRejectsBadSignature(t *testing.T) {
+	rp := newTestRelyingParty()
+	a := newSoftAuthenticator(t, testRPID, testOrigin, AlgES256)
+	credential := registerCredential(t, rp, a)
+
+	// A key that was never registered signs the response
+	impostor := newSoftAuthenticator(t, testRPID, testOrigin, AlgES256)
+	impostor.credentialID = a.credentialID
+
+	challenge := newTestChallenge(t)
+	if _, err := rp.VerifyAssertion(impostor.assert(challenge), challenge, credential, true); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("err = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestAssertionRejectsSignCountRegression(t *testing.T) {
+	rp := newTestRelyingParty()
+	a := newSoftAuthenticator(t, testRPID, testOrigin, AlgES256)
+	credential := registerCredential(t, rp, a)
+	credential.SignCount = 5
+
+	challenge := newTestChallenge(t)
+	if _, err := rp.VerifyAssertion(a.assert(challenge), challenge, credential, true); !errors.Is(err, ErrSignCountRegressed) {
+		t.Fatalf("err = %v, want ErrSignCountRegressed", err)
+	}
+}
+
+func TestAssertionAcceptsAuthenticatorWithoutCounter(t *testing.T) {
+	rp := newTestRelyingParty()
+	a := newSoftAuthenticator(t, testRPID, testOrigin, AlgEdDSA)
+	credential := registerCredential(t, rp, a)
+
+	for range 2 {
+		// assert bumps the counter before signing, so this signs with zero
+		a.signCount = ^uint32(0)
+		challenge := newTestChallenge(t)
+		if _, err := rp.VerifyAssertion(a.assert(challenge), challenge, credential, true); err != nil {
+			t.Fatalf("assertion with a zero counter failed: %v", err)
+		}
+	}
+}
+
+func TestBytesJSON(t *testing.T) {
+	var b Bytes
+	if err := json.Unmarshal([]byte(`"AQID"`), &b); err != nil || string(b) != "\x01\x02\x03" {
+		t.Fatalf("unpadded: %x, %v", b, err)
+	}
+	if err := json.Unmarshal([]byte(`"AQI="`), &b); err != nil || string(b) != "\x01\x02" {
+		t.Fatalf("padded: %x, %v", b, err)
+	}
+
+	encoded, err := json.Marshal(Bytes{0xfb, 0xff})
+	if err != nil || string(encoded) != `"-_8"` {
+		t.Fatalf("marshal: %s, %v", encoded, err)
+	}
+}
+
+func TestDecodeCBORRejectsMalformedInput(t *testing.T) {
+	inputs := map[string][]byte{
+		"truncated string":  {0x45, 0x01},
+		"huge array":        {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"indefinite length": {0x5f, 0x41, 0x01, 0xff},
+		"duplicate key":     {0xa2, 0x01, 0x01, 0x01, 0x02},
+		"deep nesting":      repeat(0x81, maxCBORDepth+2),
+	}
+
+	for name, input := range inputs {
+		if _, _, err := decodeCBOR(input); !errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("%s: err = %v, want ErrInvalidResponse", name, err)
+		}
+	}
+}
+
+func repeat(b byte, n int) []byte {
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = b
+	}
+	return out
+}
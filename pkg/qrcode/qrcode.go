@@ -0,0 +1,553 @@
+// Package qrcode renders short strings as QR codes (ISO/IEC 18004). It only
+// implements what the project needs: byte mode at error correction level M,
+// versions 1 to 20 (up to 666 bytes), which comfortably fits otpauth URIs.
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+var ErrTooLong = errors.New("qrcode: content too long")
+
+// blockLayout describes the error correction blocks of a version at level M.
+type blockLayout struct {
+	ecPerBlock int
+	group1     int // number of blocks in group 1
+	data1      int // data codewords per block in group 1
+	group2     int
+	data2      int
+}
+
+// Level M block structure for versions 1-20 (ISO/IEC 18004, table 9).
+var layouts = [...]blockLayout{
+	1:  {10, 1, 16, 0, 0},
+	2:  {16, 1, 28, 0, 0},
+	3:  {26, 1, 44, 0, 0},
+	4:  {18, 2, 32, 0, 0},
+	5:  {24, 2, 43, 0, 0},
+	6:  {16, 4, 27, 0, 0},
+	7:  {18, 4, 31, 0, 0},
+	8:  {22, 2, 38, 2, 39},
+	9:  {22, 3, 36, 2, 37},
+	10: {26, 4, 43, 1, 44},
+	11: {30, 1, 50, 4, 51},
+	12: {22, 6, 36, 2, 37},
+	13: {22, 8, 37, 1, 38},
+	14: {24, 4, 40, 5, 41},
+	15: {24, 5, 41, 5, 42},
+	16: {28, 7, 45, 3, 46},
+	17: {28, 10, 46, 1, 47},
+	18: {26, 9, 43, 4, 44},
+	19: {26, 3, 44, 11, 45},
+	20: {26, 3, 41, 13, 42},
+}
+
+var alignmentPositions = [...][]int{
+	1:  {},
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+	11: {6, 30, 54},
+	12: {6, 32, 58},
+	13: {6, 34, 62},
+	14: {6, 26, 46, 66},
+	15: {6, 26, 48, 70},
+	16: {6, 26, 50, 74},
+	17: {6, 30, 54, 78},
+	18: {6, 30, 56, 82},
+	19: {6, 30, 58, 86},
+	20: {6, 34, 62, 90},
+}
+
+const maxVersion = 20
+
+func (l blockLayout) dataCodewords() int {
+	return l.group1*l.data1 + l.group2*l.data2
+}
+
+// Code is an encoded QR symbol. Modules are indexed [row][column].
+type Code struct {
+	Version  int
+	Size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+// Encode picks the smallest version that fits content and returns the symbol.
+func Encode(content string) (*Code, error) {
+	c, err := newCode(content)
+	if err != nil {
+		return nil, err
+	}
+
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			bestMask, bestPenalty = mask, p
+		}
+		c.applyMask(mask) // masking is an XOR, so this undoes it
+	}
+	c.applyMask(bestMask)
+	c.drawFormatBits(bestMask)
+
+	return c, nil
+}
+
+// newCode lays out content in the smallest version that fits it, without a
+// mask applied.
+func newCode(content string) (*Code, error) {
+	data := []byte(content)
+
+	version := 0
+	for v := 1; v <= maxVersion; v++ {
+		if headerBits(v)+len(data)*8 <= layouts[v].dataCodewords()*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	size := version*4 + 17
+	c := &Code{
+		Version:  version,
+		Size:     size,
+		modules:  newGrid(size),
+		function: newGrid(size),
+	}
+
+	c.drawFunctionPatterns()
+	c.drawCodewords(addErrorCorrection(version, encodeData(version, data)))
+
+	return c, nil
+}
+
+// Dark reports whether the module at row y, column x is dark.
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// Image renders the symbol with scale pixels per module and the mandatory
+// four-module quiet zone.
+func (c *Code) Image(scale int) image.Image {
+	const quiet = 4
+	dim := (c.Size + 2*quiet) * scale
+	img := image.NewPaletted(image.Rect(0, 0, dim, dim), color.Palette{color.White, color.Black})
+
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex((x+quiet)*scale+dx, (y+quiet)*scale+dy, 1)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// PNG encodes content and renders it as a PNG image.
+func PNG(content string, scale int) ([]byte, error) {
+	code, err := Encode(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, code.Image(scale)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func newGrid(size int) [][]bool {
+	grid := make([][]bool, size)
+	for i := range grid {
+		grid[i] = make([]bool, size)
+	}
+	return grid
+}
+
+func headerBits(version int) int {
+	// 4-bit mode indicator plus the character count
+	if version <= 9 {
+		return 4 + 8
+	}
+	return 4 + 16
+}
+
+type bitBuffer struct {
+	bytes []byte
+	n     int
+}
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		if b.n%8 == 0 {
+			b.bytes = append(b.bytes, 0)
+		}
+		if (value>>i)&1 == 1 {
+			b.bytes[b.n/8] |= 0x80 >> (b.n % 8)
+		}
+		b.n++
+	}
+}
+
+func encodeData(version int, data []byte) []byte {
+	capacity := layouts[version].dataCodewords() * 8
+
+	var bb bitBuffer
+	bb.append(0b0100, 4) // byte mode
+	bb.append(len(data), headerBits(version)-4)
+	for _, d := range data {
+		bb.append(int(d), 8)
+	}
+
+	terminator := min(4, capacity-bb.n)
+	bb.append(0, terminator)
+	if r := bb.n % 8; r != 0 {
+		bb.append(0, 8-r)
+	}
+	for pad := 0xEC; bb.n < capacity; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+	return bb.bytes
+}
+
+// addErrorCorrection splits data into blocks, appends Reed-Solomon codewords
+// to each and interleaves the result.
+func addErrorCorrection(version int, data []byte) []byte {
+	layout := layouts[version]
+	divisor := rsGenerator(layout.ecPerBlock)
+
+	var dataBlocks, ecBlocks [][]byte
+	offset := 0
+	for i := 0; i < layout.group1+layout.group2; i++ {
+		n := layout.data1
+		if i >= layout.group1 {
+			n = layout.data2
+		}
+		block := data[offset : offset+n]
+		offset += n
+		dataBlocks = append(dataBlocks, block)
+		ecBlocks = append(ecBlocks, rsRemainder(block, divisor))
+	}
+
+	var result []byte
+	for i := 0; i < max(layout.data1, layout.data2); i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < layout.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+// rsGenerator returns the coefficients of prod(x - a^i) for i < degree,
+// highest power first, leading 1 omitted.
+func rsGenerator(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= gfMultiply(coef, factor)
+		}
+	}
+	return result
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.Size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	positions := alignmentPositions[c.Version]
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// Skip the three corners occupied by finder patterns
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignment(x, y)
+		}
+	}
+
+	// Reserve the format areas; the real bits are drawn once the mask is known.
+	c.drawFormatBits(0)
+	c.drawVersion()
+}
+
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= c.Size || y < 0 || y >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+func (c *Code) drawFormatBits(mask int) {
+	// Level M is encoded as 00, so the data is just the mask pattern.
+	data := mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	// Around the top-left finder
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	// Split between the other two finders
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(i))
+	}
+	c.setFunction(8, c.Size-8, true) // always dark
+}
+
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+
+	rem := c.Version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := c.Version<<12 | rem
+
+	for i := 0; i < 18; i++ {
+		dark := (bits>>i)&1 == 1
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places the bits in the zigzag order of the standard, going
+// up and down two-module columns from the right edge.
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the vertical timing pattern
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if !c.function[y][x] && i < len(data)*8 {
+					c.modules[y][x] = (data[i>>3]>>(7-i&7))&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.function[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			c.modules[y][x] = c.modules[y][x] != invert
+		}
+	}
+}
+
+// penalty scores the symbol with the four rules of the standard; lower is
+// easier to scan.
+func (c *Code) penalty() int {
+	score := 0
+	dark := 0
+
+	line := func(get func(i int) bool) {
+		run := 1
+		for i := 1; i < c.Size; i++ {
+			if get(i) == get(i-1) {
+				run++
+				continue
+			}
+			if run >= 5 {
+				score += run - 2
+			}
+			run = 1
+		}
+		if run >= 5 {
+			score += run - 2
+		}
+
+		// Finder-like 1:1:3:1:1 pattern with four light modules on one side
+		pattern := []bool{true, false, true, true, true, false, true}
+		for i := 0; i+7 <= c.Size; i++ {
+			match := true
+			for k, p := range pattern {
+				if get(i+k) != p {
+					match = false
+					break
+				}
+			}
+			if !match {
+				continue
+			}
+			if c.lightRun(get, i-4, i) || c.lightRun(get, i+7, i+11) {
+				score += 40
+			}
+		}
+	}
+
+	for y := 0; y < c.Size; y++ {
+		line(func(i int) bool { return c.modules[y][i] })
+	}
+	for x := 0; x < c.Size; x++ {
+		line(func(i int) bool { return c.modules[i][x] })
+	}
+
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				v := c.modules[y][x]
+				if v == c.modules[y][x+1] && v == c.modules[y+1][x] && v == c.modules[y+1][x+1] {
+					score += 3
+				}
+			}
+		}
+	}
+
+	// Deviation of the dark ratio from 50%, in 5% steps
+	percent := dark * 100 / (c.Size * c.Size)
+	score += abs(percent-50) / 5 * 10
+
+	return score
+}
+
+// lightRun reports whether every module in [from, to) is light; positions
+// outside the symbol count as light quiet zone.
+func (c *Code) lightRun(get func(i int) bool, from, to int) bool {
+	for i := from; i < to; i++ {
+		if i >= 0 && i < c.Size && get(i) {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
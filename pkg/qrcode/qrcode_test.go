@@ -0,0 +1,149 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/png"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testContent returns n bytes of an otpauth-like URI. It is lower case, so a
+// reference encoder also has to use byte mode.
+func testContent(n int) string {
+	return strings.Repeat("otpauth://totp/goapp:jane?secret=jbswy3dpehpk3pxp&issuer=goapp&", 20)[:n]
+}
+
+// render draws the symbol as one line per row, # for dark modules.
+func render(c *Code) string {
+	var b strings.Builder
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.Dark(x, y) {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// The files in testdata were produced by rsc.io/qr, an independent encoder,
+// for the same content at level M with a fixed mask. Together the cases
+// cover every mask, a version with version information and one with several
+// block groups.
+func TestMatchesReferenceEncoder(t *testing.T) {
+	tests := []struct {
+		version, mask, length int
+	}{
+		{1, 0, 14},
+		{2, 1, 20},
+		{3, 2, 42},
+		{5, 3, 80},
+		{7, 4, 122},
+		{10, 5, 200},
+		{14, 6, 362},
+		{20, 7, 666},
+	}
+
+	for _, tt := range tests {
+		name := fmt.Sprintf("v%d-mask%d", tt.version, tt.mask)
+		t.Run(name, func(t *testing.T) {
+			want, err := os.ReadFile("testdata/" + name + ".txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := newCode(testContent(tt.length))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Version != tt.version {
+				t.Fatalf("version = %d, want %d", c.Version, tt.version)
+			}
+			c.applyMask(tt.mask)
+			c.drawFormatBits(tt.mask)
+
+			if got := render(c); got != string(want) {
+				t.Errorf("symbol differs from the reference:\ngot:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestEncodePicksLowestPenaltyMask(t *testing.T) {
+	content := testContent(60)
+
+	got, err := Encode(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	best := -1
+	for mask := 0; mask < 8; mask++ {
+		c, err := newCode(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if best < 0 || c.penalty() < best {
+			best = c.penalty()
+		}
+	}
+	if got.penalty() != best {
+		t.Errorf("penalty = %d, want the lowest, %d", got.penalty(), best)
+	}
+}
+
+func TestVersionSelection(t *testing.T) {
+	// The largest content each version holds at level M in byte mode.
+	capacities := map[int]int{1: 14, 2: 26, 7: 122, 9: 180, 10: 213, 20: 666}
+
+	for version, n := range capacities {
+		c, err := Encode(testContent(n))
+		if err != nil {
+			t.Fatalf("%d bytes: %v", n, err)
+		}
+		if c.Version != version || c.Size != version*4+17 {
+			t.Errorf("%d bytes: version %d, size %d, want version %d", n, c.Version, c.Size, version)
+		}
+		if version < 20 {
+			if c, _ := Encode(testContent(n + 1)); c.Version != version+1 {
+				t.Errorf("%d bytes: version %d, want %d", n+1, c.Version, version+1)
+			}
+		}
+	}
+
+	if _, err := Encode(testContent(667)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("667 bytes: err = %v, want ErrTooLong", err)
+	}
+}
+
+func TestPNG(t *testing.T) {
+	data, err := PNG("otpauth://totp/goapp:jane?secret=JBSWY3DPEHPK3PXP", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("not a PNG: %v", err)
+	}
+	c, _ := Encode("otpauth://totp/goapp:jane?secret=JBSWY3DPEHPK3PXP")
+	if dim := (c.Size + 8) * 4; img.Bounds().Dx() != dim || img.Bounds().Dy() != dim {
+		t.Errorf("image is %v, want %dx%d with the quiet zone", img.Bounds(), dim, dim)
+	}
+
+	// The quiet zone is light and the top-left finder corner dark.
+	if r, _, _, _ := img.At(0, 0).RGBA(); r == 0 {
+		t.Error("quiet zone is dark")
+	}
+	if r, _, _, _ := img.At(16, 16).RGBA(); r != 0 {
+		t.Error("finder pattern is light")
+	}
+}
@@ -0,0 +1,67 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Errors of requests that could not be parsed at all. Body and Query wrap
+// them with the parser's error; the app's error handler tells clients only
+// which part of the request was malformed.
+var (
+	ErrInvalidBody  = errors.New("invalid request body")
+	ErrInvalidQuery = errors.New("invalid query parameters")
+)
+
+// Body parses the request body into out, a pointer to a struct, normalizes
+// it and validates it. It returns an error wrapping ErrInvalidBody if the
+// body cannot be parsed, and an *Error listing the invalid fields otherwise.
+// An empty body leaves out as it is, so optional bodies need no special case
+// and a missing body is reported as its missing required fields.
+func Body(c *fiber.Ctx, out any) error {
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(out); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBody, err)
+		}
+	}
+	Normalize(out)
+	return ValidateStruct(out)
+}
+
+// Query is Body for the query string, which fiber reads by query tags.
+func Query(c *fiber.Ctx, out any) error {
+	if err := c.QueryParser(out); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	Normalize(out)
+	return ValidateStruct(out)
+}
+
+// Param returns the route parameter name after validating it against tag,
+// e.g. "required,uuid_param". An invalid parameter is reported as a field
+// named after it.
+func Param(c *fiber.Ctx, name, tag string) (string, error) {
+	value := c.Params(name)
+
+	// A one-field struct gives the error a field name, which validating the
+	// bare value would not.
+	param := reflect.New(reflect.StructOf([]reflect.StructField{{
+		Name: "Value",
+		Type: reflect.TypeFor[string](),
+		Tag:  reflect.StructTag(fmt.Sprintf(`json:%q validate:%q`, name, tag)),
+	}}))
+	param.Elem().Field(0).SetString(value)
+
+	if err := ValidateStruct(param.Interface()); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// UUIDParam returns the route parameter name, which must be a resource ID.
+func UUIDParam(c *fiber.Ctx, name string) (string, error) {
+	return Param(c, name, "required,uuid_param")
+}
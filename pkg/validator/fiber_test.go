@@ -0,0 +1,132 @@
+package validator
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type signupRequest struct {
+	Email  string   `json:"email" normalize:"email" validate:"required,normalized_email"`
+	Name   string   `json:"name" normalize:"name" validate:"required,human_name"`
+	Phone  *string  `json:"phone" normalize:"phone" validate:"omitempty,phone"`
+	Tags   []string `json:"tags" normalize:"trim,lower" validate:"dive,required"`
+	Nested struct {
+		Slug string `json:"slug" normalize:"trim,lower"`
+	} `json:"nested"`
+}
+
+func TestNormalize(t *testing.T) {
+	phone := "0049 (30) 123-456"
+	req := signupRequest{
+		Email: "  Jane@Example.COM ",
+		Name:  " Mary   Jane\tDoe ",
+		Phone: &phone,
+		Tags:  []string{" Admin ", "OPS"},
+	}
+	req.Nested.Slug = " My-Org "
+	Normalize(&req)
+
+	if req.Email != "jane@example.com" {
+		t.Errorf("Email = %q", req.Email)
+	}
+	if req.Name != "Mary Jane Doe" {
+		t.Errorf("Name = %q", req.Name)
+	}
+	if *req.Phone != "+4930123456" {
+		t.Errorf("Phone = %q", *req.Phone)
+	}
+	if strings.Join(req.Tags, ",") != "admin,ops" {
+		t.Errorf("Tags = %q", req.Tags)
+	}
+	if req.Nested.Slug != "my-org" {
+		t.Errorf("Nested.Slug = %q", req.Nested.Slug)
+	}
+}
+
+func TestBody(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			var invalid *Error
+			switch {
+			case errors.As(err, &invalid):
+				return c.Status(fiber.StatusBadRequest).JSON(invalid.Fields)
+			case errors.Is(err, ErrInvalidBody):
+				return c.Status(fiber.StatusBadRequest).SendString("invalid body")
+			}
+			return fiber.DefaultErrorHandler(c, err)
+		},
+	})
+	app.Post("/", func(c *fiber.Ctx) error {
+		var req signupRequest
+		if err := Body(c, &req); err != nil {
+			return err
+		}
+		return c.SendString(req.Email + "|" + req.Name)
+	})
+
+	tests := []struct {
+		body   string
+		status int
+		want   string
+	}{
+		{`{"email":" Jane@Example.com","name":"Jane  Doe"}`, fiber.StatusOK, "jane@example.com|Jane Doe"},
+		{`{"email":"jane","name":"J4ne"}`, fiber.StatusBadRequest, `[{"field":"email","rule":"normalized_email","message":"email must be a valid email address"},{"field":"name","rule":"human_name","message":"name may only contain letters, spaces, hyphens, apostrophes and periods"}]`},
+		{``, fiber.StatusBadRequest, `[{"field":"email","rule":"required","message":"email is a required field"},{"field":"name","rule":"required","message":"name is a required field"}]`},
+		{`{"email":`, fiber.StatusBadRequest, "invalid body"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(fiber.MethodPost, "/", strings.NewReader(tt.body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != tt.status || string(body) != tt.want {
+			t.Errorf("body %q: got %d %s, want %d %s", tt.body, resp.StatusCode, body, tt.status, tt.want)
+		}
+	}
+}
+
+func TestUUIDParam(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			var invalid *Error
+			if errors.As(err, &invalid) {
+				return c.Status(fiber.StatusBadRequest).JSON(invalid.Fields)
+			}
+			return fiber.DefaultErrorHandler(c, err)
+		},
+	})
+	app.Get("/users/:id", func(c *fiber.Ctx) error {
+		id, err := UUIDParam(c, "id")
+		if err != nil {
+			return err
+		}
+		return c.SendString(id)
+	})
+
+	tests := []struct {
+		id     string
+		status int
+		want   string
+	}{
+		{"0b6c3a3e-6f1c-4a7e-9a0c-6c1f1f0e2b11", fiber.StatusOK, "0b6c3a3e-6f1c-4a7e-9a0c-6c1f1f0e2b11"},
+		{"me", fiber.StatusBadRequest, `[{"field":"id","rule":"uuid_param","message":"id must be a valid ID"}]`},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/users/"+tt.id, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != tt.status || string(body) != tt.want {
+			t.Errorf("id %q: got %d %s, want %d %s", tt.id, resp.StatusCode, body, tt.status, tt.want)
+		}
+	}
+}
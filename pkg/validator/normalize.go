@@ -0,0 +1,103 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// normalizers are the operations of the normalize struct tag. A tag lists
+// one or more, e.g. `normalize:"trim,lower"`, applied in order.
+var normalizers = map[string]func(string) string{
+	"trim":  strings.TrimSpace,
+	"lower": strings.ToLower,
+	"email": NormalizeEmail,
+	"name":  NormalizeName,
+	"phone": NormalizePhone,
+}
+
+// NormalizeEmail trims and lower-cases an email address.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizeName trims a name and collapses runs of whitespace inside it to
+// one space.
+func NormalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// NormalizePhone removes the spaces, dashes, periods and parentheses people
+// write phone numbers with, and turns a leading 00 into +.
+func NormalizePhone(phone string) string {
+	phone = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')', '\t':
+			return -1
+		}
+		return r
+	}, phone)
+	if rest, ok := strings.CutPrefix(phone, "00"); ok {
+		phone = "+" + rest
+	}
+	return phone
+}
+
+// Normalize rewrites the string fields of s, a pointer to a struct, as their
+// normalize tags say. Nested structs and slices of strings are normalized
+// too. An unknown operation in a tag panics, as it is a programming error.
+func Normalize(s any) {
+	normalize(reflect.ValueOf(s))
+}
+
+func normalize(v reflect.Value) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := range t.NumField() {
+		field, value := t.Field(i), v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("normalize")
+		if tag == "" {
+			normalize(value)
+			continue
+		}
+		ops := strings.Split(tag, ",")
+		switch {
+		case value.Kind() == reflect.String:
+			value.SetString(apply(ops, value.String()))
+		case value.Kind() == reflect.Pointer && value.Type().Elem().Kind() == reflect.String:
+			if !value.IsNil() {
+				value.Elem().SetString(apply(ops, value.Elem().String()))
+			}
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+			for j := range value.Len() {
+				value.Index(j).SetString(apply(ops, value.Index(j).String()))
+			}
+		default:
+			panic(fmt.Sprintf("validator: normalize tag on %s.%s, which is not a string", t.Name(), field.Name))
+		}
+	}
+}
+
+func apply(ops []string, s string) string {
+	for _, op := range ops {
+		fn, ok := normalizers[op]
+		if !ok {
+			panic(fmt.Sprintf("validator: unknown normalize operation %q", op))
+		}
+		s = fn(s)
+	}
+	return s
+}
@@ -0,0 +1,170 @@
+package validator
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldLevel and StructLevel are what rules get to inspect the value and,
+// for struct rules, report invalid fields, so packages adding rules need not
+// import go-playground/validator.
+type (
+	FieldLevel  = validator.FieldLevel
+	StructLevel = validator.StructLevel
+)
+
+// Rule is a custom validation tag with its message in every locale. {0} in a
+// message is the field name, {1} the tag's parameter. Func may be nil for
+// tags that only struct rules report. Translate, when set, builds the message
+// itself, e.g. to pick a more specific one.
+type Rule struct {
+	Tag       string
+	Func      func(fl FieldLevel) bool
+	Messages  map[string]string
+	Translate validator.TranslationFunc
+}
+
+var (
+	uuidPattern  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+)
+
+var rules = []Rule{
+	{
+		Tag: "locale",
+		Func: func(fl FieldLevel) bool {
+			return slices.Contains(Locales(), fl.Field().String())
+		},
+		Messages: map[string]string{
+			"en": "{0} must be a supported language",
+			"de": "{0} muss eine unterstützte Sprache sein",
+		},
+	},
+	{
+		// An email address as NormalizeEmail leaves it, so it can be
+		// compared and stored as is.
+		Tag: "normalized_email",
+		Func: func(fl FieldLevel) bool {
+			email := fl.Field().String()
+			if email != NormalizeEmail(email) {
+				return false
+			}
+			addr, err := mail.ParseAddress(email)
+			return err == nil && addr.Address == email && strings.Contains(email[strings.LastIndexByte(email, '@'):], ".")
+		},
+		Messages: map[string]string{
+			"en": "{0} must be a valid email address",
+			"de": "{0} muss eine gültige E-Mail-Adresse sein",
+		},
+	},
+	{
+		// Letters of any script with the marks, spaces, hyphens, apostrophes
+		// and periods names are written with, e.g. "Mary-Jane O'Neil Jr.".
+		Tag: "human_name",
+		Func: func(fl FieldLevel) bool {
+			name := fl.Field().String()
+			if name != strings.TrimSpace(name) {
+				return false
+			}
+			letters := 0
+			for _, r := range name {
+				switch {
+				case unicode.IsLetter(r):
+					letters++
+				case unicode.Is(unicode.M, r), r == ' ', r == '-', r == '\'', r == '’', r == '.':
+				default:
+					return false
+				}
+			}
+			return letters > 0
+		},
+		Messages: map[string]string{
+			"en": "{0} may only contain letters, spaces, hyphens, apostrophes and periods",
+			"de": "{0} darf nur Buchstaben, Leerzeichen, Bindestriche, Apostrophe und Punkte enthalten",
+		},
+	},
+	{
+		// A resource ID in a route. Unlike uuid it accepts upper case, as
+		// the IDs are parsed case-insensitively.
+		Tag: "uuid_param",
+		Func: func(fl FieldLevel) bool {
+			return uuidPattern.MatchString(fl.Field().String())
+		},
+		Messages: map[string]string{
+			"en": "{0} must be a valid ID",
+			"de": "{0} muss eine gültige ID sein",
+		},
+	},
+	{
+		// E.164: a plus, the country code and at most 15 digits. Spaces,
+		// dashes and parentheses are removed by NormalizePhone.
+		Tag: "phone",
+		Func: func(fl FieldLevel) bool {
+			return phonePattern.MatchString(fl.Field().String())
+		},
+		Messages: map[string]string{
+			"en": "{0} must be a phone number in international format, e.g. +4930123456",
+			"de": "{0} muss eine Telefonnummer im internationalen Format sein, z. B. +4930123456",
+		},
+	},
+}
+
+// RegisterRule adds a validation tag with its messages. It must be called
+// before the first validation, e.g. from an init function, and fails if a
+// locale has no message.
+func RegisterRule(r Rule) error {
+	if r.Func != nil {
+		if err := validate.RegisterValidation(r.Tag, r.Func); err != nil {
+			return fmt.Errorf("failed to register rule %s: %w", r.Tag, err)
+		}
+	}
+
+	translate := r.Translate
+	if translate == nil {
+		translate = func(trans ut.Translator, fe validator.FieldError) string {
+			text, _ := trans.T(fe.Tag(), fe.Field(), fe.Param())
+			return text
+		}
+	}
+
+	for _, locale := range Locales() {
+		message, ok := r.Messages[locale]
+		if !ok {
+			return fmt.Errorf("rule %s has no %s message", r.Tag, locale)
+		}
+		err := validate.RegisterTranslation(r.Tag, Translator(locale),
+			func(trans ut.Translator) error {
+				return trans.Add(r.Tag, message, true)
+			},
+			translate,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to register %s message of rule %s: %w", locale, r.Tag, err)
+		}
+	}
+	return nil
+}
+
+// RegisterStructRule adds a rule over several fields of each of types, for
+// checks a tag on one field cannot express. fn reports invalid fields with
+// sl.ReportError, naming them by their JSON name and using a tag that has
+// messages, such as a built-in one or one added with RegisterRule.
+func RegisterStructRule(fn func(sl StructLevel), types ...any) {
+	validate.RegisterStructValidation(fn, types...)
+}
+
+func registerRules() error {
+	for _, r := range rules {
+		if err := RegisterRule(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package validator
+
+import "testing"
+
+// TestRuleMessages checks that every custom rule has a message in every
+// locale, and that the messages are registered with the translators.
+func TestRuleMessages(t *testing.T) {
+	for _, r := range rules {
+		for _, locale := range Locales() {
+			if r.Messages[locale] == "" {
+				t.Errorf("rule %s: no %s message", r.Tag, locale)
+				continue
+			}
+			if _, err := Translator(locale).T(r.Tag, "field", "param"); err != nil {
+				t.Errorf("rule %s: %s message not registered: %v", r.Tag, locale, err)
+			}
+		}
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	type request struct {
+		Email  string `json:"email" validate:"required,email"`
+		Locale string `json:"locale" validate:"omitempty,locale"`
+	}
+
+	err := ValidateStruct(&request{Locale: "xx"})
+	invalid, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("ValidateStruct = %v, want *Error", err)
+	}
+
+	want := map[string][2]string{
+		"en": {"email is a required field", "locale must be a supported language"},
+		"de": {"email ist ein Pflichtfeld", "locale muss eine unterstützte Sprache sein"},
+	}
+	for locale, messages := range want {
+		fields := invalid.Localize(locale)
+		if len(fields) != 2 {
+			t.Fatalf("%s: got %d fields, want 2", locale, len(fields))
+		}
+		for i, f := range fields {
+			if f.Message != messages[i] {
+				t.Errorf("%s: %s message = %q, want %q", locale, f.Field, f.Message, messages[i])
+			}
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		tag   string
+		value string
+		valid bool
+	}{
+		{"normalized_email", "jane@example.com", true},
+		{"normalized_email", "Jane@example.com", false},
+		{"normalized_email", " jane@example.com", false},
+		{"normalized_email", "Jane <jane@example.com>", false},
+		{"normalized_email", "jane@localhost", false},
+		{"normalized_email", "jane", false},
+		{"human_name", "Mary-Jane O'Neil Jr.", true},
+		{"human_name", "Zoë", true},
+		{"human_name", "José María", true},
+		{"human_name", "李小龍", true},
+		{"human_name", "Robert'); DROP TABLE", false},
+		{"human_name", "R2D2", false},
+		{"human_name", "--", false},
+		{"human_name", " Jane", false},
+		{"uuid_param", "0b6c3a3e-6f1c-4a7e-9a0c-6c1f1f0e2b11", true},
+		{"uuid_param", "0B6C3A3E-6F1C-4A7E-9A0C-6C1F1F0E2B11", true},
+		{"uuid_param", "0b6c3a3e6f1c4a7e9a0c6c1f1f0e2b11", false},
+		{"uuid_param", "me", false},
+		{"phone", "+4930123456", true},
+		{"phone", "+14155552671", true},
+		{"phone", "030123456", false},
+		{"phone", "+0123456789", false},
+		{"phone", "+1234567890123456", false},
+	}
+	for _, tt := range tests {
+		err := validate.Var(tt.value, tt.tag)
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("%s(%q) valid = %v, want %v", tt.tag, tt.value, valid, tt.valid)
+		}
+	}
+}
+
+func TestStructRule(t *testing.T) {
+	type request struct {
+		Current string `json:"current"`
+		Next    string `json:"next"`
+	}
+	RegisterStructRule(func(sl StructLevel) {
+		req := sl.Current().Interface().(request)
+		if req.Next == req.Current {
+			sl.ReportError(req.Next, "next", "Next", "nefield", "current")
+		}
+	}, request{})
+
+	if err := ValidateStruct(&request{Current: "a", Next: "b"}); err != nil {
+		t.Errorf("ValidateStruct(valid) = %v", err)
+	}
+
+	err := ValidateStruct(&request{Current: "a", Next: "a"})
+	invalid, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("ValidateStruct = %v, want *Error", err)
+	}
+	want := FieldError{Field: "next", Rule: "nefield", Message: "next cannot be equal to current"}
+	if len(invalid.Fields) != 1 || invalid.Fields[0] != want {
+		t.Errorf("fields = %+v, want %+v", invalid.Fields, want)
+	}
+}
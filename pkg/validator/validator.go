@@ -1,19 +1,171 @@
 package validator
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
+	de_translations "github.com/go-playground/validator/v10/translations/de"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
 )
 
-var validate *validator.Validate
+// DefaultLocale is the locale of messages when no other is asked for.
+const DefaultLocale = "en"
+
+var (
+	validate *validator.Validate
+	uni      *ut.UniversalTranslator
+)
 
 func init() {
 	validate = validator.New()
+	validate.RegisterTagNameFunc(jsonName)
+
+	english := en.New()
+	uni = ut.New(english, english, de.New())
+
+	defaults := map[string]func(*validator.Validate, ut.Translator) error{
+		"en": en_translations.RegisterDefaultTranslations,
+		"de": de_translations.RegisterDefaultTranslations,
+	}
+	for _, locale := range Locales() {
+		trans, _ := uni.GetTranslator(locale)
+		if err := defaults[locale](validate, trans); err != nil {
+			panic(fmt.Sprintf("validator: failed to register %s translations: %v", locale, err))
+		}
+	}
+
+	if err := registerRules(); err != nil {
+		panic(fmt.Sprintf("validator: %v", err))
+	}
+}
+
+// Locales lists the locales messages are available in, DefaultLocale first.
+func Locales() []string {
+	return []string{"en", "de"}
+}
+
+// Translator returns the translator of locale, or of DefaultLocale when the
+// locale is not supported. Callers may add their own messages to it.
+func Translator(locale string) ut.Translator {
+	trans, _ := uni.FindTranslator(locale, DefaultLocale)
+	return trans
+}
+
+// FieldError is one invalid field, named by its path in the JSON body, e.g.
+// "email" or "redirect_uris[0]". Rule is the validation tag that failed.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Error lists every invalid field of a validated struct, with messages in
+// DefaultLocale. Localize gives them in another locale.
+type Error struct {
+	Fields []FieldError
+
+	root    reflect.Type
+	invalid validator.ValidationErrors
 }
 
+func (e *Error) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+": "+f.Message)
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// ValidateStruct validates s and returns an *Error when any field is invalid.
 func ValidateStruct(s any) error {
-	return validate.Struct(s)
+	err := validate.Struct(s)
+
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return err
+	}
+
+	e := &Error{root: reflect.TypeOf(s), invalid: invalid}
+	e.Fields = e.Localize(DefaultLocale)
+	return e
+}
+
+// Localize returns the invalid fields with messages in locale.
+func (e *Error) Localize(locale string) []FieldError {
+	trans := Translator(locale)
+
+	fields := make([]FieldError, 0, len(e.invalid))
+	for _, fe := range e.invalid {
+		fields = append(fields, FieldError{
+			Field:   fieldPath(e.root, fe.StructNamespace()),
+			Rule:    fe.Tag(),
+			Message: fe.Translate(trans),
+		})
+	}
+	return fields
 }
 
 func GetValidator() *validator.Validate {
 	return validate
 }
+
+// jsonName names fields as the client sends them, so fe.Field() is the JSON
+// name in messages.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// fieldPath turns a Go namespace such as "Request.Embedded.RedirectURIs[0]"
+// into the JSON path "redirect_uris[0]". The root struct and embedded
+// structs, whose fields JSON flattens, are left out. Namespaces of unnamed
+// structs start at the field.
+func fieldPath(t reflect.Type, namespace string) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	segments := strings.Split(namespace, ".")
+	if t.Name() != "" {
+		segments = segments[1:]
+	}
+
+	path := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		name, index, indexed := strings.Cut(segment, "[")
+
+		field, ok := t.FieldByName(name)
+		if !ok {
+			path = append(path, segment)
+			continue
+		}
+		t = field.Type
+		if indexed {
+			t = t.Elem()
+		}
+		if field.Anonymous {
+			continue
+		}
+
+		key := jsonName(field)
+		if key == "" {
+			key = field.Name
+		}
+		if indexed {
+			key += "[" + index
+		}
+		path = append(path, key)
+	}
+	return strings.Join(path, ".")
+}
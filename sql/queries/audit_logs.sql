@@ -0,0 +1,9 @@
+-- name: CreateAuditLog :exec
+INSERT INTO audit_logs (user_id, actor_id, action, ip_address, metadata)
+VALUES ($1,$2,$3,$4,$5);
+
+-- name: ListUserAuditLogs :many
+SELECT * FROM audit_logs
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
@@ -0,0 +1,18 @@
+-- name: CreateEmailChangeToken :one
+INSERT INTO email_change_tokens (user_id, new_email, token_hash, expires_at)
+VALUES ($1,$2,$3,$4)
+RETURNING *;
+
+-- name: GetEmailChangeTokenByHash :one
+SELECT * FROM email_change_tokens
+WHERE token_hash = $1;
+
+-- name: MarkEmailChangeTokenUsed :execrows
+UPDATE email_change_tokens
+SET used_at = NOW()
+WHERE id = $1 AND used_at IS NULL;
+
+-- name: InvalidateUserEmailChangeTokens :exec
+UPDATE email_change_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
@@ -0,0 +1,48 @@
+-- name: CreateInvitation :one
+INSERT INTO invitations (email, role_id, token_hash, invited_by, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetInvitationByID :one
+SELECT * FROM invitations
+WHERE id = $1;
+
+-- name: GetInvitationByTokenHash :one
+SELECT * FROM invitations
+WHERE token_hash = $1;
+
+-- name: LockInvitationByTokenHash :one
+SELECT * FROM invitations
+WHERE token_hash = $1
+FOR UPDATE;
+
+-- name: GetPendingInvitationByEmail :one
+SELECT * FROM invitations
+WHERE lower(email) = lower($1) AND status = 'pending';
+
+-- name: ListInvitations :many
+SELECT i.*, COALESCE(r.name, '')::text AS role_name
+FROM invitations i
+LEFT JOIN roles r ON r.id = i.role_id
+WHERE sqlc.arg('status')::text = ''
+    OR (sqlc.arg('status') = 'pending' AND i.status = 'pending' AND i.expires_at > NOW())
+    OR (sqlc.arg('status') = 'expired' AND i.status = 'pending' AND i.expires_at <= NOW())
+    OR (sqlc.arg('status') IN ('accepted', 'revoked') AND i.status = sqlc.arg('status'))
+ORDER BY i.created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: RenewInvitation :one
+UPDATE invitations
+SET token_hash = $2, expires_at = $3, sent_count = sent_count + 1, last_sent_at = NOW()
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
+
+-- name: RevokeInvitation :execrows
+UPDATE invitations
+SET status = 'revoked', revoked_at = NOW()
+WHERE id = $1 AND status = 'pending';
+
+-- name: AcceptInvitation :exec
+UPDATE invitations
+SET status = 'accepted', accepted_at = NOW(), accepted_user_id = $2
+WHERE id = $1;
@@ -0,0 +1,39 @@
+-- name: GetLoginThrottle :one
+SELECT * FROM login_throttles
+WHERE scope = $1 AND subject = $2;
+
+-- name: RecordLoginFailure :one
+-- Failures older than window_start no longer count.
+INSERT INTO login_throttles (scope, subject, failures, last_failed_at)
+VALUES ($1, $2, 1, NOW())
+ON CONFLICT (scope, subject) DO UPDATE
+SET failures = CASE
+        WHEN login_throttles.last_failed_at < sqlc.arg(window_start) THEN 1
+        ELSE login_throttles.failures + 1
+    END,
+    last_failed_at = NOW()
+RETURNING *;
+
+-- name: LockLogin :exec
+UPDATE login_throttles
+SET locked_until = $3
+WHERE scope = $1 AND subject = $2;
+
+-- name: DeleteLoginThrottle :execrows
+DELETE FROM login_throttles
+WHERE scope = $1 AND subject = $2;
+
+-- name: DeleteStaleLoginThrottles :exec
+DELETE FROM login_throttles
+WHERE last_failed_at < $1 AND (locked_until IS NULL OR locked_until < NOW());
+
+-- name: CreateAccountUnlockToken :one
+INSERT INTO account_unlock_tokens (user_id, token_hash, expires_at)
+VALUES ($1,$2,$3)
+RETURNING *;
+
+-- name: ConsumeAccountUnlockToken :one
+UPDATE account_unlock_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id;
@@ -0,0 +1,19 @@
+-- name: CreateMagicLinkToken :one
+INSERT INTO magic_link_tokens (user_id, token_hash, expires_at)
+VALUES ($1,$2,$3)
+RETURNING *;
+
+-- name: CountRecentMagicLinkTokens :one
+SELECT COUNT(*) FROM magic_link_tokens
+WHERE user_id = $1 AND created_at > $2;
+
+-- name: ConsumeMagicLinkToken :one
+UPDATE magic_link_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING *;
+
+-- name: InvalidateUserMagicLinkTokens :exec
+UPDATE magic_link_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
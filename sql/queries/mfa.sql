@@ -0,0 +1,39 @@
+-- name: UpsertPendingTOTP :one
+-- Starts (or restarts) enrollment. Does nothing once MFA is enabled.
+INSERT INTO user_mfa (user_id, totp_secret)
+VALUES ($1,$2)
+ON CONFLICT (user_id) DO UPDATE
+SET totp_secret = EXCLUDED.totp_secret, last_used_step = 0
+WHERE user_mfa.enabled_at IS NULL
+RETURNING *;
+
+-- name: GetUserMFA :one
+SELECT * FROM user_mfa
+WHERE user_id = $1;
+
+-- name: EnableUserMFA :exec
+UPDATE user_mfa
+SET enabled_at = NOW()
+WHERE user_id = $1;
+
+-- name: UseTOTPStep :execrows
+UPDATE user_mfa
+SET last_used_step = $2
+WHERE user_id = $1 AND last_used_step < $2;
+
+-- name: DeleteUserMFA :exec
+DELETE FROM user_mfa
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO mfa_recovery_codes (user_id, code_hash)
+VALUES ($1,$2);
+
+-- name: DeleteUserRecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE mfa_recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
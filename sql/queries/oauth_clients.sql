@@ -0,0 +1,34 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (owner_id, name, secret_hash, redirect_uris, grant_types, scopes)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1;
+
+-- name: GetUserOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1 AND owner_id = $2;
+
+-- name: ListUserOAuthClients :many
+SELECT * FROM oauth_clients
+WHERE owner_id = $1
+ORDER BY created_at DESC;
+
+-- name: DeleteOAuthClient :execrows
+DELETE FROM oauth_clients
+WHERE id = $1 AND owner_id = $2;
+
+-- name: CreateOAuthAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ConsumeOAuthAuthorizationCode :one
+DELETE FROM oauth_authorization_codes
+WHERE code_hash = $1 AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteExpiredOAuthAuthorizationCodes :exec
+DELETE FROM oauth_authorization_codes
+WHERE expires_at <= NOW();
@@ -0,0 +1,47 @@
+-- name: GetRoleByName :one
+SELECT * FROM roles
+WHERE name = $1;
+
+-- name: GetRoleByID :one
+SELECT * FROM roles
+WHERE id = $1;
+
+-- name: ListRoles :many
+SELECT * FROM roles
+ORDER BY name;
+
+-- name: AssignUserRole :exec
+INSERT INTO user_roles (user_id, role_id)
+VALUES ($1,$2)
+ON CONFLICT DO NOTHING;
+
+-- name: LockRoleHolders :many
+SELECT ur.user_id FROM user_roles ur
+WHERE ur.role_id = $1
+FOR UPDATE;
+
+-- name: RemoveUserRole :execrows
+DELETE FROM user_roles
+WHERE user_id = $1 AND role_id = $2;
+
+-- name: GetUserRoleNames :many
+SELECT r.name FROM roles r
+JOIN user_roles ur ON ur.role_id = r.id
+WHERE ur.user_id = $1
+ORDER BY r.name;
+
+-- name: GetUserPermissionNames :many
+SELECT DISTINCT p.name FROM permissions p
+JOIN role_permissions rp ON rp.permission_id = p.id
+JOIN user_roles ur ON ur.role_id = rp.role_id
+WHERE ur.user_id = $1
+ORDER BY p.name;
+
+-- name: ListPermissions :many
+SELECT * FROM permissions
+ORDER BY name;
+
+-- name: CountUsersWithRole :one
+SELECT COUNT(*) FROM user_roles ur
+JOIN roles r ON r.id = ur.role_id
+WHERE r.name = $1;
@@ -0,0 +1,27 @@
+-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (jti, user_id, expires_at)
+VALUES ($1,$2,$3)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: IsTokenRevoked :one
+SELECT EXISTS (
+    SELECT 1 FROM revoked_tokens WHERE jti = $1
+);
+
+-- name: DeleteExpiredRevokedTokens :execrows
+DELETE FROM revoked_tokens
+WHERE expires_at < NOW();
+
+-- name: RevokeUserTokens :exec
+INSERT INTO user_token_revocations (user_id, revoked_before, expires_at)
+VALUES ($1,$2,$3)
+ON CONFLICT (user_id) DO UPDATE
+SET revoked_before = EXCLUDED.revoked_before, expires_at = EXCLUDED.expires_at;
+
+-- name: GetUserTokenRevocation :one
+SELECT revoked_before FROM user_token_revocations
+WHERE user_id = $1;
+
+-- name: DeleteExpiredUserTokenRevocations :execrows
+DELETE FROM user_token_revocations
+WHERE expires_at < NOW();
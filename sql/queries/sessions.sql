@@ -0,0 +1,41 @@
+-- name: UpsertSession :exec
+INSERT INTO sessions (id, user_id, client_id, user_agent, ip_address, device, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (id) DO UPDATE
+SET user_agent = EXCLUDED.user_agent,
+    ip_address = EXCLUDED.ip_address,
+    device = EXCLUDED.device,
+    expires_at = EXCLUDED.expires_at,
+    last_seen_at = NOW();
+
+-- name: GetSession :one
+SELECT * FROM sessions
+WHERE id = $1;
+
+-- name: ListUserSessions :many
+SELECT s.id, s.user_id, s.client_id, s.user_agent, s.ip_address, s.device,
+       s.created_at, s.last_seen_at, s.expires_at,
+       COALESCE(c.name, '')::text AS client_name
+FROM sessions s
+LEFT JOIN oauth_clients c ON c.id = s.client_id
+WHERE s.user_id = $1 AND s.revoked_at IS NULL AND s.expires_at > NOW()
+ORDER BY s.last_seen_at DESC;
+
+-- name: RevokeSession :exec
+UPDATE sessions
+SET revoked_at = NOW()
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserSessions :exec
+UPDATE sessions
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeOtherUserSessions :exec
+UPDATE sessions
+SET revoked_at = NOW()
+WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL;
+
+-- name: DeleteExpiredSessions :execrows
+DELETE FROM sessions
+WHERE expires_at <= NOW();
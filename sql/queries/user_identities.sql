@@ -0,0 +1,39 @@
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (user_id, provider, subject, email, last_login_at)
+VALUES ($1,$2,$3,$4,NOW())
+RETURNING *;
+
+-- name: GetUserIdentity :one
+SELECT * FROM user_identities
+WHERE provider = $1 AND subject = $2;
+
+-- name: ListUserIdentities :many
+SELECT * FROM user_identities
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: TouchUserIdentity :exec
+UPDATE user_identities
+SET email = $2, last_login_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteUserIdentity :execrows
+DELETE FROM user_identities
+WHERE id = $1 AND user_id = $2;
+
+-- name: CountUserIdentities :one
+SELECT COUNT(*) FROM user_identities
+WHERE user_id = $1;
+
+-- name: CreateOAuthState :exec
+INSERT INTO oauth_states (state_hash, provider, nonce, code_verifier, user_id, expires_at)
+VALUES ($1,$2,$3,$4,$5,$6);
+
+-- name: ConsumeOAuthState :one
+DELETE FROM oauth_states
+WHERE state_hash = $1 AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteExpiredOAuthStates :exec
+DELETE FROM oauth_states
+WHERE expires_at <= NOW();
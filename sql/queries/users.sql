@@ -9,11 +9,11 @@ WHERE id = $1 AND is_active = true;
 
 -- name: GetUserByEmail :one
 SELECT * FROM users
-WHERE email = $1 AND is_active = true;
+WHERE lower(email) = lower($1) AND is_active = true;
 
 -- name: UpdateUser :one
 UPDATE users
-SET first_name = $2, last_name = $3, updated_at = NOW()
+SET first_name = $2, last_name = $3, locale = $4, updated_at = NOW()
 WHERE id = $1 AND is_active = true
 RETURNING *;
 
@@ -27,3 +27,24 @@ SELECT * FROM users
 WHERE is_active = true
 ORDER BY created_at DESC
 LIMIT $1 OFFSET $2;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkUserEmailVerified :exec
+UPDATE users
+SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email_verified_at IS NULL;
+
+-- name: UpdateUserEmail :one
+UPDATE users
+SET email = $2, email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND is_active = true
+RETURNING *;
+
+-- name: CreateExternalUser :one
+INSERT INTO users (email, password_hash, first_name, last_name, email_verified_at)
+VALUES ($1,'',$2,$3,$4)
+RETURNING *;